@@ -0,0 +1,118 @@
+package pool
+
+import (
+	"fmt"
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// 调参建议使用的阈值
+const (
+	// adviseHeadroom 建议的MaxCap相对峰值需求的余量
+	adviseHeadroom = 1.25
+	// adviseMissRate 新建连接占比超过该值时认为空闲连接不足
+	adviseMissRate = 0.1
+	// adviseMinIdleTimeout 建议的最短IdleTimeout
+	adviseMinIdleTimeout = time.Minute
+)
+
+// SizingReport 根据统计区间内的观测值给出的连接池调参建议
+type SizingReport struct {
+	// 统计区间的时长，自创建连接池或上次ResetStats起
+	Window time.Duration
+
+	// 区间内同时借出及等待的连接数峰值，即MaxBusy+MaxWaiters
+	PeakDemand int
+	// 区间内阻塞等待的次数及平均等待时长
+	WaitCount uint64
+	AvgWait   time.Duration
+	// 因ctx结束被丢弃的等待者数
+	ShedWaiters uint64
+	// Get新建连接的次数占比
+	MissRate float64
+	// 整个区间内从未被取出的空闲连接数，即IdleLow
+	IdleWaste int
+	// 因空闲超时关闭的连接数
+	IdleTimeoutCloses uint64
+
+	// 当前配置
+	MaxCap      int
+	IdleTimeout time.Duration
+
+	// 建议值，InitialCap为创建连接池时应预建的空闲连接数，不超过MaxIdle
+	RecommendedMaxCap      int
+	RecommendedInitialCap  int
+	RecommendedIdleTimeout time.Duration
+
+	// 各建议的依据
+	Reasons []string
+}
+
+// Advise 分析自创建连接池或上次ResetStats以来的统计信息，给出MaxCap、InitialCap及IdleTimeout的建议值
+// 统计区间越接近真实负载的一个完整周期，建议越可靠
+func (c *ChannelPool) Advise() SizingReport {
+	stats := c.Stats()
+	r := SizingReport{
+		Window:            c.now().Sub(time.Unix(0, atomic.LoadInt64(&c.statsSince))),
+		PeakDemand:        int(stats.MaxBusy + stats.MaxWaiters),
+		WaitCount:         stats.WaitCount,
+		ShedWaiters:       stats.ShedWaiters,
+		IdleWaste:         int(stats.IdleLow),
+		IdleTimeoutCloses: stats.Closes[CloseIdleTimeout],
+		MaxCap:            c.maxCap,
+		IdleTimeout:       c.loadIdleTimeout(),
+	}
+	if r.WaitCount > 0 {
+		r.AvgWait = stats.WaitDuration / time.Duration(r.WaitCount)
+	}
+	if gets := stats.Hits + stats.Misses; gets > 0 {
+		r.MissRate = float64(stats.Misses) / float64(gets)
+	}
+
+	// MaxCap：按峰值需求加余量，有等待时不低于当前值
+	r.RecommendedMaxCap = int(math.Ceil(float64(r.PeakDemand) * adviseHeadroom))
+	if r.RecommendedMaxCap < 1 {
+		r.RecommendedMaxCap = 1
+	}
+	contended := r.WaitCount > 0 || r.ShedWaiters > 0
+	if contended {
+		if r.RecommendedMaxCap <= r.MaxCap {
+			r.RecommendedMaxCap = r.MaxCap + 1
+		}
+		r.Reasons = append(r.Reasons, fmt.Sprintf("%d Get calls waited %v on average, peak demand %d", r.WaitCount, r.AvgWait, r.PeakDemand))
+	} else if r.RecommendedMaxCap < r.MaxCap {
+		r.Reasons = append(r.Reasons, fmt.Sprintf("peak demand %d leaves MaxCap %d underused", r.PeakDemand, r.MaxCap))
+	}
+
+	// InitialCap：新建连接占比高时预建峰值所需的连接，否则预建区间内实际取用过的空闲连接
+	if r.MissRate > adviseMissRate {
+		r.RecommendedInitialCap = int(stats.MaxBusy)
+		r.Reasons = append(r.Reasons, fmt.Sprintf("%.0f%% of Get calls dialed a new connection", r.MissRate*100))
+	} else {
+		r.RecommendedInitialCap = int(stats.IdleHigh) - r.IdleWaste
+	}
+	if r.RecommendedInitialCap > r.RecommendedMaxCap {
+		r.RecommendedInitialCap = r.RecommendedMaxCap
+	}
+	if maxIdle := c.loadMaxIdle(); r.RecommendedInitialCap > maxIdle {
+		r.RecommendedInitialCap = maxIdle
+	}
+	if r.IdleWaste > 0 {
+		r.Reasons = append(r.Reasons, fmt.Sprintf("%d idle connections were never used during the window", r.IdleWaste))
+	}
+
+	// IdleTimeout：空闲超时关闭后又新建连接说明过短，长期未用的空闲连接说明过长或未配置
+	r.RecommendedIdleTimeout = r.IdleTimeout
+	switch {
+	case r.IdleTimeoutCloses > 0 && r.MissRate > adviseMissRate:
+		r.RecommendedIdleTimeout = 2 * r.IdleTimeout
+		r.Reasons = append(r.Reasons, fmt.Sprintf("%d connections closed by IdleTimeout were redialed", r.IdleTimeoutCloses))
+	case r.IdleWaste > 0 && (r.IdleTimeout == 0 || r.IdleTimeout > r.Window):
+		r.RecommendedIdleTimeout = r.Window.Round(time.Second)
+		if r.RecommendedIdleTimeout < adviseMinIdleTimeout {
+			r.RecommendedIdleTimeout = adviseMinIdleTimeout
+		}
+	}
+	return r
+}
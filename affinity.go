@@ -0,0 +1,152 @@
+package pool
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// GetAffinity 优先返回此前以key取出且当前空闲的连接，否则与GetContext相同
+// 适用于依赖服务端会话缓存或预编译语句的场景，连接需可作为map的key
+func (c *ChannelPool) GetAffinity(ctx context.Context, key string) (interface{}, error) {
+	c.affinityMu.Lock()
+	prev, ok := c.affinity[key]
+	c.affinityMu.Unlock()
+
+	var filter *idleFilter
+	if ok {
+		filter = &idleFilter{match: func(wrapConn *idleConn) bool {
+			return wrapConn.shard != nil && trackKey(wrapConn.conn) == prev
+		}}
+	}
+
+	conn, err := c.get(ctx, c.qosLimit(""), filter)
+	if err == nil && trackable(conn) {
+		c.bindAffinity(key, trackKey(conn))
+	}
+	return conn, err
+}
+
+// bindAffinity 记录key最近取出的连接，ck为连接的trackKey，不持有连接本身
+func (c *ChannelPool) bindAffinity(key string, ck interface{}) {
+	c.affinityMu.Lock()
+	defer c.affinityMu.Unlock()
+	if prev, ok := c.affinity[key]; ok {
+		if prev == ck {
+			return
+		}
+		if keys := c.affinityKeys[prev]; keys != nil {
+			delete(keys, key)
+			if len(keys) == 0 {
+				delete(c.affinityKeys, prev)
+			}
+		}
+	}
+	c.affinity[key] = ck
+	keys := c.affinityKeys[ck]
+	if keys == nil {
+		keys = make(map[string]struct{})
+		c.affinityKeys[ck] = keys
+	}
+	keys[key] = struct{}{}
+}
+
+// forgetAffinity 连接关闭时删除所有指向它的key
+func (c *ChannelPool) forgetAffinity(conn interface{}) {
+	c.affinityMu.Lock()
+	ck := trackKey(conn)
+	for key := range c.affinityKeys[ck] {
+		delete(c.affinity, key)
+	}
+	delete(c.affinityKeys, ck)
+	c.affinityMu.Unlock()
+}
+
+// idleFilter 限定getConn借出的空闲连接
+type idleFilter struct {
+	match  func(*idleConn) bool // 优先借出满足match的空闲连接
+	strict bool                 // 只借出满足match的空闲连接，没有时新建或等待，否则取任一空闲连接
+	evict  bool                 // strict时名额已满，关闭一条不满足match的空闲连接腾出名额新建
+}
+
+// takeIdle 按filter取出一条可复用的空闲连接，filter为nil时与popIdle相同
+func (c *ChannelPool) takeIdle(conns idleQueue, filter *idleFilter) (*idleConn, int) {
+	if filter == nil {
+		return c.popIdle(conns)
+	}
+	wrapConn, skipped := c.takeIdleWhere(filter.match)
+	if wrapConn != nil || filter.strict {
+		return wrapConn, skipped
+	}
+	wrapConn, n := c.popIdle(conns)
+	return wrapConn, skipped + n
+}
+
+// evictIdle 不经校验取出一条空闲连接并以CloseEvicted关闭，为新建腾出名额，没有空闲连接时返回false
+func (c *ChannelPool) evictIdle(conns idleQueue) bool {
+	wrapConn := conns.pop()
+	if wrapConn == nil {
+		return false
+	}
+	updateMin(&c.idleLow, int32(conns.len()))
+	c.discard(wrapConn, CloseEvicted)
+	return true
+}
+
+// takeIdleWhere 从空闲连接中取出第一条满足match且可复用的连接，
+// skipped为取到前因不可复用丢弃的满足match的连接数
+func (c *ChannelPool) takeIdleWhere(match func(*idleConn) bool) (*idleConn, int) {
+	skipped := 0
+	for {
+		found := c.scanIdle(match)
+		if found == nil {
+			return nil, skipped
+		}
+		reason, ok := c.reusable(found)
+		if ok {
+			return found, skipped
+		}
+		c.discard(found, reason)
+		if instrumented {
+			atomic.AddUint64(&c.counters.shard().staleSkips, 1)
+		}
+		skipped++
+	}
+}
+
+// scanIdle 从空闲连接中取出第一条满足match的连接
+// 逐条取出检查，不满足的立即放回队尾，查找期间其余空闲连接仍可被借出；
+// 查找互相串行，保证等待者重新查找时不会漏掉其他查找者暂时取出的连接
+func (c *ChannelPool) scanIdle(match func(*idleConn) bool) *idleConn {
+	conns := c.getConns()
+	if conns == nil {
+		return nil
+	}
+	if c.stash != nil {
+		c.flushStash()
+	}
+
+	c.scanMu.Lock()
+	var found *idleConn
+	rotated := false
+	for n := conns.len(); n > 0; n-- {
+		wrapConn := conns.pop()
+		if wrapConn == nil {
+			break
+		}
+		if match(wrapConn) {
+			found = wrapConn
+			break
+		}
+		rotated = true
+		if !conns.push(wrapConn) {
+			c.discard(wrapConn, ClosePoolFull)
+		}
+	}
+	c.scanMu.Unlock()
+	c.drainIfClosed()
+	// 放回的连接不是新的空闲连接，只唤醒一个可能错过它们的等待者，不广播
+	if rotated && atomic.LoadInt32(&c.waiters) > 0 {
+		c.signalWaiter()
+	}
+	return found
+}
@@ -0,0 +1,282 @@
+package pool
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// balanceDecay 自适应权重中错误率及新建连接耗时的指数滑动平均系数
+const balanceDecay = 0.2
+
+// minWeightRatio 自适应权重的下限占静态权重的比例，保证表现差的后端仍有少量新连接用于探测恢复
+const minWeightRatio = 0.05
+
+// Backend BalancedPool的一个后端
+type Backend struct {
+	//后端名称，如地址，作为KeyedPool的key
+	Name string
+	//新建该后端连接的方法
+	Factory Factory
+	//静态权重，默认1
+	Weight float64
+}
+
+// BalancedConfig BalancedPool的配置
+type BalancedConfig struct {
+	//后端列表
+	Backends []Backend
+	//每个后端连接池的配置模板，Factory被Backend.Factory替换
+	Pool PoolConfig
+	//为true时按观测到的新建连接错误率及耗时调整权重，错误率越高、耗时越长的后端权重越低
+	Adaptive bool
+	//异常后端摘除配置
+	Outlier OutlierConfig
+}
+
+// BalancedPool 多后端连接池，每个后端使用独立的连接池，Get按权重平滑轮询选择后端
+// 连接需可作为map的key，以便放回时找到所属的连接池
+type BalancedPool struct {
+	keyed    *KeyedPool
+	adaptive bool
+	outlier  OutlierConfig
+	clock    Clock
+
+	mu       sync.Mutex
+	backends []*backend
+}
+
+// backend 后端的权重及观测到的新建连接情况，由BalancedPool.mu保护
+type backend struct {
+	Backend
+	//平滑加权轮询的当前值
+	current float64
+	//新建连接错误率及耗时（纳秒）的滑动平均，dialed为false时尚无观测
+	errRate float64
+	latency float64
+	dialed  bool
+	//连续失败次数及摘除状态，参见OutlierConfig
+	failures     int
+	ejected      bool
+	probing      bool
+	ejectedUntil time.Time
+	//通过DrainKey排空，UndrainKey之前不参与选择
+	drained bool
+}
+
+// NewBalancedPool 按cfg初始化多后端连接池
+func NewBalancedPool(cfg BalancedConfig) *BalancedPool {
+	p := &BalancedPool{
+		adaptive: cfg.Adaptive,
+		outlier:  cfg.Outlier,
+		clock:    cfg.Pool.Clock,
+	}
+	if p.outlier.Consecutive > 0 {
+		p.outlier.setDefaults()
+	}
+	if p.clock == nil {
+		p.clock = systemClock{}
+	}
+	byName := make(map[string]*backend, len(cfg.Backends))
+	for _, b := range cfg.Backends {
+		if b.Weight <= 0 {
+			b.Weight = 1
+		}
+		be := &backend{Backend: b}
+		p.backends = append(p.backends, be)
+		byName[b.Name] = be
+	}
+	template := cfg.Pool
+	p.keyed = NewKeyedPool(func(key string) *PoolConfig {
+		b := byName[key]
+		poolConfig := template
+		poolConfig.Factory = p.observe(b)
+		if p.outlier.Consecutive > 0 {
+			onClose := template.OnClose
+			poolConfig.OnClose = func(conn interface{}, reason CloseReason) {
+				if outlierReason(reason) {
+					p.record(b, false)
+				}
+				if onClose != nil {
+					onClose(conn, reason)
+				}
+			}
+		}
+		return &poolConfig
+	})
+	return p
+}
+
+// observe 包装后端的工厂方法，记录新建连接的结果及耗时
+func (p *BalancedPool) observe(b *backend) Factory {
+	return func() (interface{}, error) {
+		start := p.clock.Now()
+		conn, err := b.Factory()
+		took := p.clock.Now().Sub(start)
+
+		p.mu.Lock()
+		failed := 0.0
+		if err != nil {
+			failed = 1
+		}
+		if !b.dialed {
+			b.errRate, b.latency, b.dialed = failed, float64(took), true
+		} else {
+			b.errRate += balanceDecay * (failed - b.errRate)
+			b.latency += balanceDecay * (float64(took) - b.latency)
+		}
+		if err != nil {
+			p.recordLocked(b, false)
+		}
+		p.mu.Unlock()
+		return conn, err
+	}
+}
+
+// weightsLocked 返回各后端当前的有效权重，需持有mu
+func (p *BalancedPool) weightsLocked() []float64 {
+	weights := make([]float64, len(p.backends))
+	minLatency := 0.0
+	if p.adaptive {
+		for _, b := range p.backends {
+			if !b.ejected && b.dialed && b.errRate < 1 && b.latency > 0 && (minLatency == 0 || b.latency < minLatency) {
+				minLatency = b.latency
+			}
+		}
+	}
+	for i, b := range p.backends {
+		if b.ejected || b.drained {
+			continue
+		}
+		w := b.Weight
+		if p.adaptive && b.dialed {
+			w *= 1 - b.errRate
+			if minLatency > 0 && b.latency > minLatency {
+				w *= minLatency / b.latency
+			}
+			if w < b.Weight*minWeightRatio {
+				w = b.Weight * minWeightRatio
+			}
+		}
+		weights[i] = w
+	}
+	return weights
+}
+
+// pick 按平滑加权轮询选择一个未被摘除或排空的后端，没有时返回空字符串
+func (p *BalancedPool) pick() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var (
+		best  *backend
+		total float64
+	)
+	for i, w := range p.weightsLocked() {
+		if w == 0 {
+			continue
+		}
+		b := p.backends[i]
+		b.current += w
+		total += w
+		if best == nil || b.current > best.current {
+			best = b
+		}
+	}
+	if best == nil {
+		return ""
+	}
+	best.current -= total
+	return best.Name
+}
+
+// Get 按权重选择一个后端并从其连接池取一个连接
+func (p *BalancedPool) Get(ctx context.Context) (interface{}, error) {
+	p.readmit()
+	name := p.pick()
+	if name == "" {
+		return nil, getErr(ErrPoolExhausted)
+	}
+	return p.keyed.Get(ctx, name)
+}
+
+// Put 将连接放回其所属后端的连接池，可用的连接放回成功视为该后端的一次成功
+func (p *BalancedPool) Put(conn interface{}) error {
+	key, kp := p.keyed.ownerKey(conn)
+	if kp == nil {
+		return ErrUnknownConn
+	}
+	healthy := !isUnusable(conn)
+	if err := kp.Put(conn); err != nil {
+		return err
+	}
+	if healthy && p.outlier.Consecutive > 0 {
+		p.record(p.backend(key), true)
+	}
+	return nil
+}
+
+// backend 返回名称为name的后端
+func (p *BalancedPool) backend(name string) *backend {
+	for _, b := range p.backends {
+		if b.Name == name {
+			return b
+		}
+	}
+	return nil
+}
+
+// Close 关闭连接并从其所属后端的连接池中移除
+func (p *BalancedPool) Close(conn interface{}) error {
+	return p.keyed.Close(conn)
+}
+
+// Weights 返回各后端当前的有效权重，未开启Adaptive时即静态权重，被摘除或排空的后端为0
+func (p *BalancedPool) Weights() map[string]float64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	weights := make(map[string]float64, len(p.backends))
+	for i, w := range p.weightsLocked() {
+		weights[p.backends[i].Name] = w
+	}
+	return weights
+}
+
+// DrainKey 停止选择名为key的后端，并按KeyedPool.DrainKey排空其连接池，
+// 之后该后端不再参与选择，直到调用UndrainKey
+func (p *BalancedPool) DrainKey(ctx context.Context, key string) error {
+	b := p.backend(key)
+	if b == nil {
+		return nil
+	}
+	p.mu.Lock()
+	b.drained = true
+	p.mu.Unlock()
+	return p.keyed.DrainKey(ctx, key)
+}
+
+// UndrainKey 恢复选择已排空的后端
+func (p *BalancedPool) UndrainKey(key string) {
+	b := p.backend(key)
+	if b == nil {
+		return
+	}
+	p.mu.Lock()
+	b.drained = false
+	b.current = 0
+	p.mu.Unlock()
+}
+
+// Keyed 返回按后端划分的连接池，可按后端查看统计信息
+func (p *BalancedPool) Keyed() *KeyedPool {
+	return p.keyed
+}
+
+// Stats 返回所有后端汇总的统计信息
+func (p *BalancedPool) Stats() *Stats {
+	return p.keyed.Stats()
+}
+
+// Release 释放所有后端的连接池
+func (p *BalancedPool) Release() {
+	p.keyed.Release()
+}
@@ -0,0 +1,174 @@
+package pool_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hms58/pool"
+	"github.com/hms58/pool/poolsim"
+)
+
+// nameConn 记录所属后端的测试连接
+type nameConn struct {
+	backend string
+}
+
+func backendFactory(name string) pool.Factory {
+	return func() (interface{}, error) { return &nameConn{backend: name}, nil }
+}
+
+func TestBalancedPoolAdaptive(t *testing.T) {
+	sim := poolsim.New(time.Now())
+	slow := func() (interface{}, error) {
+		sim.Clock.Sleep(40 * time.Millisecond)
+		return &nameConn{backend: "slow"}, nil
+	}
+	fast := func() (interface{}, error) {
+		sim.Clock.Sleep(10 * time.Millisecond)
+		return &nameConn{backend: "fast"}, nil
+	}
+	failing := func() (interface{}, error) { return nil, errors.New("refused") }
+
+	p := pool.NewBalancedPool(pool.BalancedConfig{
+		Backends: []pool.Backend{
+			{Name: "fast", Factory: fast},
+			{Name: "slow", Factory: slow},
+			{Name: "failing", Factory: failing},
+		},
+		Pool:     pool.PoolConfig{MaxCap: 100, Clock: sim.Clock},
+		Adaptive: true,
+	})
+	defer p.Release()
+
+	for i := 0; i < 30; i++ {
+		p.Get(context.Background())
+	}
+	w := p.Weights()
+	if w["fast"] != 1 || w["slow"] != 0.25 || w["failing"] != 0.05 {
+		t.Errorf("Weights() = %v, want fast:1 slow:0.25 failing:0.05", w)
+	}
+
+	counts := map[string]int{}
+	for i := 0; i < 130; i++ {
+		if c, err := p.Get(context.Background()); err == nil {
+			counts[c.(*nameConn).backend]++
+		}
+	}
+	if counts["fast"] < 3*counts["slow"] {
+		t.Errorf("counts = %v, want fast to get about 4x slow", counts)
+	}
+}
+
+func TestBalancedPoolOutlierEjection(t *testing.T) {
+	sim := poolsim.New(time.Now())
+	bDown := true
+	p := pool.NewBalancedPool(pool.BalancedConfig{
+		Backends: []pool.Backend{
+			{Name: "a", Factory: backendFactory("a")},
+			{Name: "b", Factory: func() (interface{}, error) {
+				if bDown {
+					return nil, errors.New("refused")
+				}
+				return &nameConn{backend: "b"}, nil
+			}},
+		},
+		Pool:    pool.PoolConfig{MaxCap: 10, Clock: sim.Clock},
+		Outlier: pool.OutlierConfig{Consecutive: 2, EjectFor: time.Minute},
+	})
+	defer p.Release()
+	ctx := context.Background()
+
+	for i := 0; i < 4; i++ {
+		if c, err := p.Get(ctx); err == nil {
+			p.Put(c)
+		}
+	}
+	if ejected := p.Ejected(); len(ejected) != 1 || ejected[0] != "b" {
+		t.Fatalf("Ejected() = %v, want [b]", ejected)
+	}
+	for i := 0; i < 4; i++ {
+		c, err := p.Get(ctx)
+		if err != nil || c.(*nameConn).backend != "a" {
+			t.Fatalf("Get() with b ejected = %v, %v", c, err)
+		}
+		p.Put(c)
+	}
+
+	sim.Clock.Advance(time.Minute)
+	p.Get(ctx)
+	if len(p.Ejected()) != 1 {
+		t.Fatalf("b readmitted although canary dial failed")
+	}
+
+	bDown = false
+	sim.Clock.Advance(time.Minute)
+	p.Get(ctx)
+	if len(p.Ejected()) != 0 || p.Keyed().Pool("b").Len() != 1 {
+		t.Fatalf("Ejected() = %v after successful canary, want none", p.Ejected())
+	}
+	if w := p.Weights(); w["b"] != 1 {
+		t.Errorf("readmitted weight = %v", w["b"])
+	}
+}
+
+func TestBalancedPoolOutlierBrokenConns(t *testing.T) {
+	p := pool.NewBalancedPool(pool.BalancedConfig{
+		Backends: []pool.Backend{
+			{Name: "a", Factory: backendFactory("a")},
+			{Name: "b", Factory: backendFactory("b")},
+		},
+		Pool:    pool.PoolConfig{MaxCap: 10},
+		Outlier: pool.OutlierConfig{Consecutive: 3},
+	})
+	defer p.Release()
+
+	for i := 0; i < 10; i++ {
+		c, _ := p.Get(context.Background())
+		if c.(*nameConn).backend == "b" {
+			p.Close(c)
+		} else {
+			p.Put(c)
+		}
+	}
+	if ejected := p.Ejected(); len(ejected) != 1 || ejected[0] != "b" {
+		t.Fatalf("Ejected() = %v, want [b]", ejected)
+	}
+}
+
+func TestBalancedPoolDrainKey(t *testing.T) {
+	p := pool.NewBalancedPool(pool.BalancedConfig{
+		Backends: []pool.Backend{
+			{Name: "a", Factory: backendFactory("a")},
+			{Name: "b", Factory: backendFactory("b")},
+		},
+		Pool: pool.PoolConfig{MaxCap: 10},
+	})
+	defer p.Release()
+	ctx := context.Background()
+
+	if err := p.DrainKey(ctx, "b"); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 4; i++ {
+		c, _ := p.Get(ctx)
+		if c.(*nameConn).backend != "a" {
+			t.Fatalf("Get() chose drained backend")
+		}
+		p.Put(c)
+	}
+	if w := p.Weights(); w["b"] != 0 {
+		t.Errorf("drained weight = %v, want 0", w["b"])
+	}
+
+	p.UndrainKey("b")
+	counts := map[string]int{}
+	for i := 0; i < 4; i++ {
+		c, _ := p.Get(ctx)
+		counts[c.(*nameConn).backend]++
+	}
+	if counts["b"] != 2 {
+		t.Errorf("counts after UndrainKey = %v, want a:2 b:2", counts)
+	}
+}
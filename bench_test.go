@@ -3,6 +3,7 @@ package pool_test
 
 import (
 	"net"
+	"runtime"
 	"testing"
 	"time"
 
@@ -13,9 +14,9 @@ func dummyDialer() (interface{}, error) {
 	return &net.TCPConn{}, nil
 }
 
-func benchmarkPoolGetPut(b *testing.B, poolSize int) {
+func benchmarkPoolGetPut(b *testing.B, newPool func(*pool.PoolConfig) pool.Pooler, poolSize int) {
 	close := func(v interface{}) error { return v.(net.Conn).Close() }
-	connPool := pool.NewChannelPool(&pool.PoolConfig{
+	connPool := newPool(&pool.PoolConfig{
 		// InitialCap: 1,
 		MaxCap:  poolSize,
 		Factory: dummyDialer,
@@ -41,15 +42,27 @@ func benchmarkPoolGetPut(b *testing.B, poolSize int) {
 }
 
 func BenchmarkPoolGetPut10Conns(b *testing.B) {
-	benchmarkPoolGetPut(b, 10)
+	benchmarkPoolGetPut(b, pool.NewChannelPool, 10)
 }
 
 func BenchmarkPoolGetPut100Conns(b *testing.B) {
-	benchmarkPoolGetPut(b, 100)
+	benchmarkPoolGetPut(b, pool.NewChannelPool, 100)
 }
 
 func BenchmarkPoolGetPut1000Conns(b *testing.B) {
-	benchmarkPoolGetPut(b, 1000)
+	benchmarkPoolGetPut(b, pool.NewChannelPool, 1000)
+}
+
+func BenchmarkRingPoolGetPut10Conns(b *testing.B) {
+	benchmarkPoolGetPut(b, pool.NewRingPool, 10)
+}
+
+func BenchmarkRingPoolGetPut100Conns(b *testing.B) {
+	benchmarkPoolGetPut(b, pool.NewRingPool, 100)
+}
+
+func BenchmarkRingPoolGetPut1000Conns(b *testing.B) {
+	benchmarkPoolGetPut(b, pool.NewRingPool, 1000)
 }
 
 func benchmarkPoolGetRemove(b *testing.B, poolSize int) {
@@ -91,3 +104,135 @@ func BenchmarkPoolGetRemove100Conns(b *testing.B) {
 func BenchmarkPoolGetRemove1000Conns(b *testing.B) {
 	benchmarkPoolGetRemove(b, 1000)
 }
+
+func BenchmarkPoolGetPutAllocs(b *testing.B) {
+	connPool := pool.NewChannelPool(&pool.PoolConfig{
+		MaxCap:      10,
+		Factory:     dummyDialer,
+		IdleTimeout: 15 * time.Second,
+	})
+	defer connPool.Release()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		cn, err := connPool.Get()
+		if err != nil {
+			b.Fatal(err)
+		}
+		if err = connPool.Put(cn); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkPoolGetRemoveAllocs(b *testing.B) {
+	connPool := pool.NewChannelPool(&pool.PoolConfig{
+		MaxCap:  10,
+		Factory: dummyDialer,
+	})
+	defer connPool.Release()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		cn, err := connPool.Get()
+		if err != nil {
+			b.Fatal(err)
+		}
+		if err = connPool.Close(cn); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func benchmarkPoolContention(b *testing.B, newPool func(*pool.PoolConfig) pool.Pooler, goroutines int) {
+	connPool := newPool(&pool.PoolConfig{
+		MaxCap:      100,
+		Factory:     dummyDialer,
+		IdleTimeout: 15 * time.Second,
+	})
+	defer connPool.Release()
+
+	procs := runtime.GOMAXPROCS(0)
+	b.SetParallelism((goroutines + procs - 1) / procs)
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			cn, err := connPool.Get()
+			if err != nil {
+				b.Fatal(err)
+			}
+			if err = connPool.Put(cn); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkPoolContention100Goroutines(b *testing.B) {
+	benchmarkPoolContention(b, pool.NewChannelPool, 100)
+}
+
+func BenchmarkPoolContention1000Goroutines(b *testing.B) {
+	benchmarkPoolContention(b, pool.NewChannelPool, 1000)
+}
+
+func BenchmarkRingPoolContention100Goroutines(b *testing.B) {
+	benchmarkPoolContention(b, pool.NewRingPool, 100)
+}
+
+func BenchmarkRingPoolContention1000Goroutines(b *testing.B) {
+	benchmarkPoolContention(b, pool.NewRingPool, 1000)
+}
+
+func BenchmarkPoolGetPutCoarseClock(b *testing.B) {
+	connPool := pool.NewChannelPool(&pool.PoolConfig{
+		MaxCap:          10,
+		Factory:         dummyDialer,
+		IdleTimeout:     15 * time.Second,
+		ClockResolution: 10 * time.Millisecond,
+	})
+	defer connPool.Release()
+
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			cn, err := connPool.Get()
+			if err != nil {
+				b.Fatal(err)
+			}
+			if err = connPool.Put(cn); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkPoolGetPutSticky(b *testing.B) {
+	connPool := pool.NewChannelPool(&pool.PoolConfig{
+		MaxCap:      10,
+		Factory:     dummyDialer,
+		IdleTimeout: 15 * time.Second,
+		StickyStash: true,
+	})
+	defer connPool.Release()
+
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			cn, err := connPool.Get()
+			if err != nil {
+				b.Fatal(err)
+			}
+			if err = connPool.Put(cn); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,81 @@
+package pool
+
+import (
+	"log/slog"
+	"time"
+)
+
+// BorrowAction 连接借出超过MaxBorrowDuration时的处理方式
+type BorrowAction int
+
+const (
+	// BorrowLog 只记录日志并发送EventBorrowExceeded事件
+	BorrowLog BorrowAction = iota
+	// BorrowMarkBroken 将连接标记为不可用，持有方之后的读写失败，放回时关闭，
+	// 连接需实现MarkUnusable，如配置IOTimeout后的DeadlineConn
+	BorrowMarkBroken
+	// BorrowForceClose 同ForceClose，立即关闭连接并回收名额，之后的Put返回ErrUnknownConn
+	BorrowForceClose
+)
+
+var borrowActionNames = [...]string{
+	BorrowLog:        "log",
+	BorrowMarkBroken: "mark broken",
+	BorrowForceClose: "force close",
+}
+
+func (a BorrowAction) String() string {
+	if a < 0 || int(a) >= len(borrowActionNames) {
+		return "unknown"
+	}
+	return borrowActionNames[a]
+}
+
+// borrowCheckDivisor 检查借出时长的间隔为MaxBorrowDuration除以该值
+const borrowCheckDivisor = 4
+
+// enforceBorrowLoop 定期检查借出时长超过max的连接并按policy处理，每条连接每次借出只处理一次
+func (c *ChannelPool) enforceBorrowLoop(max time.Duration, policy func(conn interface{}, info ConnInfo) BorrowAction) {
+	c.every(max/borrowCheckDivisor, func() {
+		c.enforceBorrow(max, policy)
+	})
+}
+
+// enforceBorrow 处理借出时长超过max的连接
+func (c *ChannelPool) enforceBorrow(max time.Duration, policy func(conn interface{}, info ConnInfo) BorrowAction) {
+	type overdue struct {
+		conn interface{}
+		info ConnInfo
+	}
+	now := c.now()
+	var found []overdue
+	c.tracked.each(func(_ interface{}, wrapConn *idleConn) {
+		if !wrapConn.lent || wrapConn.overdue || wrapConn.conn == nil || now.Sub(wrapConn.lentAt) <= max {
+			return
+		}
+		wrapConn.overdue = true
+		found = append(found, overdue{wrapConn.conn, wrapConn.info()})
+	})
+
+	for _, o := range found {
+		action := BorrowLog
+		if policy != nil {
+			action = policy(o.conn, o.info)
+		}
+		c.logAttrs(slog.LevelWarn, "connection borrowed too long",
+			slog.Uint64("conn_id", o.info.ID), slog.Duration("held", now.Sub(o.info.LentAt)),
+			slog.String("owner", o.info.Owner), slog.String("action", action.String()))
+		c.emit(Event{Type: EventBorrowExceeded, Conn: o.conn, ConnID: o.info.ID})
+
+		switch action {
+		case BorrowMarkBroken:
+			if m, ok := o.conn.(markUnusable); ok {
+				m.MarkUnusable()
+			}
+		case BorrowForceClose:
+			c.ForceClose(func(conn interface{}, info ConnInfo) bool {
+				return info.ID == o.info.ID && conn == o.conn
+			})
+		}
+	}
+}
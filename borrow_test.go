@@ -0,0 +1,91 @@
+//go:build !pooldebug && !poolnostats
+
+package pool_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hms58/pool"
+	"github.com/hms58/pool/poolsim"
+)
+
+// markableConn 可被标记为不可用的测试连接
+type markableConn struct {
+	broken bool
+}
+
+func (c *markableConn) MarkUnusable()  { c.broken = true }
+func (c *markableConn) Unusable() bool { return c.broken }
+
+func TestMaxBorrowDuration(t *testing.T) {
+	sim := poolsim.New(time.Now())
+	var actions []pool.BorrowAction
+	policy := pool.BorrowForceClose
+	p := pool.New(sim.Config(&pool.PoolConfig{
+		MaxCap:            2,
+		MaxBorrowDuration: time.Minute,
+		OnBorrowExceeded: func(conn interface{}, info pool.ConnInfo) pool.BorrowAction {
+			actions = append(actions, policy)
+			return policy
+		},
+	}))
+	defer p.Release()
+	events := p.Events()
+
+	c, _ := p.Get()
+	sim.Clock.Advance(45 * time.Second)
+	if len(actions) != 0 {
+		t.Fatalf("policy called before MaxBorrowDuration")
+	}
+	sim.Clock.Advance(30 * time.Second)
+	if len(actions) != 1 || sim.Closed() != 1 {
+		t.Fatalf("policy calls = %d, closed = %d, want 1 1", len(actions), sim.Closed())
+	}
+	if err := p.Put(c); !errors.Is(err, pool.ErrUnknownConn) {
+		t.Errorf("Put() of force closed conn err = %v, want ErrUnknownConn", err)
+	}
+	exceeded := 0
+	for len(events) > 0 {
+		if ev := <-events; ev.Type == pool.EventBorrowExceeded {
+			exceeded++
+		}
+	}
+	if exceeded != 1 {
+		t.Errorf("EventBorrowExceeded sent %d times, want 1", exceeded)
+	}
+
+	policy = pool.BorrowLog
+	c, _ = p.Get()
+	sim.Clock.Advance(5 * time.Minute)
+	if len(actions) != 2 || sim.Open() != 1 {
+		t.Fatalf("policy calls = %d, open = %d after logging policy, want 2 1", len(actions), sim.Open())
+	}
+	p.Put(c)
+}
+
+func TestMaxBorrowDurationMarkBroken(t *testing.T) {
+	sim := poolsim.New(time.Now())
+	cfg := sim.Config(&pool.PoolConfig{
+		MaxCap:            1,
+		MaxBorrowDuration: time.Second,
+		OnBorrowExceeded: func(interface{}, pool.ConnInfo) pool.BorrowAction {
+			return pool.BorrowMarkBroken
+		},
+	})
+	cfg.Factory = func() (interface{}, error) { return &markableConn{}, nil }
+	cfg.Close = nil
+	p := pool.New(cfg)
+	defer p.Release()
+
+	c, _ := p.Get()
+	sim.Clock.Advance(2 * time.Second)
+	if !c.(*markableConn).broken {
+		t.Fatal("overdue conn was not marked unusable")
+	}
+	p.Put(c)
+	if p.Len() != 0 || p.Stats().Closes[pool.CloseBroken] != 1 {
+		t.Errorf("broken conn returned to the pool: Len = %d", p.Len())
+	}
+}
@@ -0,0 +1,160 @@
+package pool
+
+import (
+	"bytes"
+	"math/bits"
+	"sync/atomic"
+)
+
+// BufferPoolConfig 缓冲区池相关配置
+type BufferPoolConfig struct {
+	//最小的缓冲区大小，向上取整为2的幂，默认64
+	MinSize int
+	//最大的缓冲区大小，向上取整为2的幂，默认64KB，更大的缓冲区不入池
+	MaxSize int
+	//每个大小等级保留的最大空闲缓冲区数，默认64
+	MaxIdle int
+	//按大小等级指定保留的最大空闲缓冲区数，未指定的等级使用MaxIdle
+	ClassMaxIdle map[int]int
+}
+
+// BufferClassStats 单个大小等级的统计信息，借出的缓冲区不再计入，TotalConns即空闲缓冲区数
+type BufferClassStats struct {
+	Size  int
+	Stats *Stats
+}
+
+// BufferPool 按2的幂划分大小等级的[]byte缓冲区池，每个等级由一个连接池管理空闲缓冲区
+// 缓冲区无法追踪，取出后即脱离连接池，放回时作为新的空闲缓冲区入池，不做借出归还的计数
+type BufferPool struct {
+	minShift int
+	classes  []*ChannelPool
+}
+
+// NewBufferPool 初始化缓冲区池
+func NewBufferPool(cfg *BufferPoolConfig) *BufferPool {
+	if cfg.MinSize <= 0 {
+		cfg.MinSize = 64
+	}
+	if cfg.MaxSize <= 0 {
+		cfg.MaxSize = 64 << 10
+	}
+	if cfg.MaxSize < cfg.MinSize {
+		cfg.MaxSize = cfg.MinSize
+	}
+	if cfg.MaxIdle <= 0 {
+		cfg.MaxIdle = 64
+	}
+
+	b := &BufferPool{minShift: classShift(cfg.MinSize)}
+	for shift := b.minShift; shift <= classShift(cfg.MaxSize); shift++ {
+		size := 1 << uint(shift)
+		maxIdle := cfg.MaxIdle
+		if n, ok := cfg.ClassMaxIdle[size]; ok && n > 0 {
+			maxIdle = n
+		}
+		b.classes = append(b.classes, newPool(&PoolConfig{
+			MaxCap:  maxIdle,
+			Factory: func() (interface{}, error) { return make([]byte, size), nil },
+		}, newChanQueue))
+	}
+	return b
+}
+
+// classShift 返回容纳size字节的最小2的幂的指数
+func classShift(size int) int {
+	if size <= 1 {
+		return 0
+	}
+	return bits.Len(uint(size - 1))
+}
+
+// class 返回容纳size字节的大小等级，超出最大等级时返回nil
+func (b *BufferPool) class(size int) *ChannelPool {
+	i := classShift(size) - b.minShift
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(b.classes) {
+		return nil
+	}
+	return b.classes[i]
+}
+
+// Get 取一个长度为size的缓冲区，容量为对应大小等级，超出最大等级时直接分配
+func (b *BufferPool) Get(size int) []byte {
+	class := b.class(size)
+	if class == nil {
+		return make([]byte, size)
+	}
+	buf, ok := class.takeBuffer()
+	if !ok {
+		return make([]byte, 1<<uint(classShift(size)))[:size]
+	}
+	return buf[:size]
+}
+
+// Put 将缓冲区放回对应的大小等级，容量不是某个等级大小的缓冲区将被丢弃
+func (b *BufferPool) Put(buf []byte) {
+	class := b.class(cap(buf))
+	if class == nil || cap(buf) != 1<<uint(classShift(cap(buf))) || cap(buf) < 1<<uint(b.minShift) {
+		return
+	}
+	class.pushBuffer(buf[:cap(buf)])
+}
+
+// GetBuffer 取一个容量至少为size的空bytes.Buffer
+func (b *BufferPool) GetBuffer(size int) *bytes.Buffer {
+	return bytes.NewBuffer(b.Get(size)[:0])
+}
+
+// PutBuffer 将bytes.Buffer的底层缓冲区放回缓冲区池，之后不可再使用buf
+func (b *BufferPool) PutBuffer(buf *bytes.Buffer) {
+	buf.Reset()
+	b.Put(buf.Bytes())
+}
+
+// Stats 返回各大小等级的统计信息
+func (b *BufferPool) Stats() []BufferClassStats {
+	stats := make([]BufferClassStats, len(b.classes))
+	for i, class := range b.classes {
+		stats[i] = BufferClassStats{Size: 1 << uint(b.minShift+i), Stats: class.Stats()}
+	}
+	return stats
+}
+
+// Release 释放所有空闲缓冲区
+func (b *BufferPool) Release() {
+	for _, class := range b.classes {
+		class.Release()
+	}
+}
+
+// takeBuffer 取出一个空闲缓冲区，取出后即脱离连接池，不计入借出
+func (c *ChannelPool) takeBuffer() ([]byte, bool) {
+	conns := c.getConns()
+	if conns == nil {
+		return nil, false
+	}
+	wrapConn, skipped := c.popIdle(conns)
+	if wrapConn == nil {
+		if instrumented {
+			atomic.AddUint64(&c.counters.shard().misses, 1)
+		}
+		return nil, false
+	}
+	c.hit(skipped)
+	return c.detach(wrapConn).conn.([]byte), true
+}
+
+// pushBuffer 将缓冲区作为新的空闲缓冲区放入，空闲缓冲区已达上限时关闭
+func (c *ChannelPool) pushBuffer(buf []byte) {
+	if c.getConns() == nil {
+		return
+	}
+	if _, ok := c.reserve(); !ok {
+		return
+	}
+	now := c.now()
+	c.adopt(idleConn{conn: buf, t: now, created: now})
+}
@@ -0,0 +1,69 @@
+//go:build !poolnostats
+
+package pool_test
+
+import (
+	"testing"
+
+	"github.com/hms58/pool"
+)
+
+func TestBufferPool(t *testing.T) {
+	b := pool.NewBufferPool(&pool.BufferPoolConfig{MinSize: 64, MaxSize: 1024, MaxIdle: 2})
+	defer b.Release()
+
+	buf := b.Get(100)
+	if len(buf) != 100 || cap(buf) != 128 {
+		t.Fatalf("Get(100) len=%d cap=%d, want 100 128", len(buf), cap(buf))
+	}
+	b.Put(buf)
+	again := b.Get(120)
+	if &again[0] != &buf[0] {
+		t.Error("Get(120) did not reuse the 128-byte buffer")
+	}
+
+	if big := b.Get(4096); len(big) != 4096 {
+		t.Errorf("Get(4096) len=%d, want 4096", len(big))
+	}
+	b.Put(make([]byte, 100))
+
+	stats := b.Stats()
+	if len(stats) != 5 || stats[1].Size != 128 {
+		t.Fatalf("Stats() = %d classes, want 5 starting at 64", len(stats))
+	}
+	if stats[1].Stats.Hits != 1 || stats[1].Stats.Misses != 1 {
+		t.Errorf("128-byte class Hits=%d Misses=%d, want 1 1", stats[1].Stats.Hits, stats[1].Stats.Misses)
+	}
+
+	w := b.GetBuffer(10)
+	w.WriteString("hello")
+	b.PutBuffer(w)
+}
+
+func TestBufferPoolAccounting(t *testing.T) {
+	b := pool.NewBufferPool(&pool.BufferPoolConfig{MinSize: 64, MaxSize: 1024, MaxIdle: 2})
+	defer b.Release()
+
+	// 不是由Get取出的缓冲区作为新的空闲缓冲区入池
+	b.Put(make([]byte, 128))
+	if s := b.Stats()[1].Stats; s.BusyConns != 0 || s.TotalConns != 1 || s.IdleConns != 1 {
+		t.Errorf("after Put of a foreign buffer Busy=%d Total=%d Idle=%d, want 0 1 1", s.BusyConns, s.TotalConns, s.IdleConns)
+	}
+
+	// 增长后的bytes.Buffer放回时不会遗留借出计数
+	w := b.GetBuffer(100)
+	w.Write(make([]byte, 300))
+	b.PutBuffer(w)
+	for _, class := range b.Stats() {
+		if class.Stats.BusyConns != 0 {
+			t.Errorf("%d-byte class BusyConns = %d after PutBuffer, want 0", class.Size, class.Stats.BusyConns)
+		}
+	}
+
+	for i := 0; i < 3; i++ {
+		b.Put(make([]byte, 256))
+	}
+	if s := b.Stats()[2].Stats; s.TotalConns != 2 {
+		t.Errorf("256-byte class TotalConns = %d, want MaxIdle 2", s.TotalConns)
+	}
+}
@@ -1,232 +1,1908 @@
 package pool
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"log"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"reflect"
 	"sync"
 	"sync/atomic"
 	"time"
 )
 
-//PoolConfig 连接池相关配置
+// PoolConfig 连接池相关配置
 type PoolConfig struct {
-	// 连接池中拥有的最小连接数
-	// InitialCap int
+	//创建连接池时预先建立的连接数，不超过MaxIdle
+	InitialCap int
+	//预建连接的速率（每秒新建连接数），大于0时在后台按该速率逐步建立，为0时创建连接池时同步建立
+	WarmupRate int
 	//连接池中拥有的最大的连接数
 	MaxCap int
+	//连接池中保留的最大空闲连接数，超出的连接放回时将被关闭，默认与MaxCap相同
+	MaxIdle int
+	//连接数达到MaxCap后允许额外创建的溢出连接数，溢出连接放回时总是关闭
+	//为0且未开启Wait时不限制连接数，也不区分溢出连接
+	MaxOverflow int
+	//连接数达到MaxCap+MaxOverflow时Get阻塞等待，而不是返回ErrPoolExhausted
+	Wait bool
+	//没有空闲连接时Get等方法是否调用工厂方法新建连接，为nil时默认为true；
+	//设为false即严格模式，没有空闲连接时按Wait阻塞等待或返回ErrNoIdleConn，
+	//连接只能通过InitialCap预建、Dial或Reserve显式创建
+	DialOnEmpty *bool
+	//为true时丢弃过期或校验失败的空闲连接后才取到连接的Get不计入Hits，只计入StaleHits，
+	//使命中率只反映有效的复用
+	ExcludeStaleHits bool
+	//为true时每个P暂存一条最近放回的连接，同一goroutine随后的Get直接取回，适用于热点请求循环；
+	//有等待者时不暂存，暂存的连接计入MaxIdle，取出时同样校验，Release及Drain时关闭
+	StickyStash bool
+	//Get阻塞等待的最长时间，为0时一直等待
+	WaitTimeout time.Duration
+	//Release时并发关闭空闲连接的goroutine数，默认8
+	ReleaseConcurrency int
+	//异步关闭队列长度，大于0时连接池丢弃的连接交由后台goroutine关闭，队列已满时同步关闭
+	AsyncCloseQueue int
+	//关闭单条连接的超时时间，超时后放弃等待关闭方法返回
+	CloseTimeout time.Duration
 	//生成连接的方法
-	Factory func() (interface{}, error)
+	Factory Factory
+	//生成连接并返回其标签的方法，配置后代替Factory，可用GetMatching按标签取出连接，
+	//使能力不同的连接（如是否TLS、连接的库）共用一个连接池
+	LabeledFactory LabeledFactory
+	//新建连接后、可被Get取出前执行的准备方法，如握手、AUTH、协议协商，返回错误则关闭该连接。
+	//预建的连接在后台goroutine中准备完成后才放入空闲连接，使Get的耗时不包含准备时间；
+	//Get新建连接时同步执行
+	Prepare func(interface{}) error
+	//大于0时缓存工厂方法返回的错误，该时间内的新建连接直接返回该错误，避免后端故障时每个Get都等待拨号超时
+	DialErrorTTL time.Duration
+	//Get同时进行的拨号数上限，为0时不限制。冷启动时大量调用者同时到达空连接池，
+	//最多该数量的调用者拨号，其余调用者排队等待放回的连接或空出的拨号名额，
+	//排队不依赖Wait，同样受WaitTimeout和ctx限制
+	MaxConcurrentDials int
 	//关闭链接的方法
 	Close func(interface{}) error
+	//大于0时Factory生成的net.Conn被包装为DeadlineConn，每次读写的超时时间，
+	//读写出错的连接放回时关闭，Close方法收到的也是DeadlineConn
+	IOTimeout time.Duration
+	//为true时Factory生成的net.Conn被包装为DeadlineConn，读写遇到连接重置、EOF等
+	//永久错误的连接放回时关闭，超时的连接仍可复用
+	DiscardOnNetError bool
+	//大于0时放回的不可用连接（如读写出错的DeadlineConn）先搁置该时长，期间不借出，到期后清除不可用标记
+	//并用BrokenProbe探测一次，成功则放回空闲连接，否则关闭，用于从服务端短暂停顿等瞬时故障中恢复
+	//连接需实现MarkUsable，未实现的不可用连接照常立即关闭
+	BrokenLinger time.Duration
+	//BrokenLinger到期后的探测方法，为空时使用Ping，两者都为空时不搁置
+	BrokenProbe func(interface{}) error
 	//链接最大空闲时间，超过该事件则将失效
 	IdleTimeout time.Duration
+	//链接最大存活时间，超过该时间的链接将被关闭
+	MaxLifetime time.Duration
+	//IdleTimeout及MaxLifetime的随机抖动比例，如0.1表示每条连接在±10%范围内随机，
+	//避免同时建立的连接同时过期重建
+	ExpiryJitter float64
+	//大于0时使用该精度的粗粒度时钟判断空闲及存活时间，减少time.Now的调用开销
+	ClockResolution time.Duration
+	//连接池使用的时钟，默认使用系统时间，配置后忽略ClockResolution，测试中可使用poolsim的虚拟时钟
+	Clock Clock
+	//取出空闲链接时校验链接是否可用，返回错误则关闭该链接
+	Ping func(interface{}) error
+	//取出空闲连接时回调，idleFor为连接的空闲时长，可用于刷新会话令牌，返回错误则关闭并替换该连接
+	OnBorrow func(conn interface{}, idleFor time.Duration) error
+	//定期对空闲连接发送的保活探测，如写入应用层ping，返回错误则关闭该连接
+	KeepAlive func(interface{}) error
+	//保活探测的间隔，大于0且配置了KeepAlive时启动后台goroutine
+	KeepAliveInterval time.Duration
+	//放回连接池前重置连接或对象的状态，返回错误则关闭该连接
+	Reset func(interface{}) error
+	//连接池关闭链接时回调，reason为关闭原因
+	OnClose func(conn interface{}, reason CloseReason)
+	//生成连接来源标签的方法，如远端地址，用于ConnInfo
+	Origin func(interface{}) string
+	//为连接创建派生资源的方法，key区分不同种类的资源，如预编译语句的SQL，参见Children
+	ChildFactory func(parent interface{}, key string) (interface{}, error)
+	//关闭派生资源的方法
+	ChildClose func(interface{}) error
+	//空闲连接超出上限时的淘汰策略，为空时丢弃正在放回的连接
+	Eviction EvictionPolicy
+	//按QoS等级预留的连接数，如{"critical": 2}，GetQoS指定等级时可使用其预留，
+	//Get及未预留的等级只能使用MaxCap(+MaxOverflow)减去所有预留后的部分
+	QoSReserved map[string]int
+	//GetFor时每个使用方最多可同时借出的连接数，为0时不限制
+	OwnerQuota int
+	//按使用方指定的配额，未指定的使用方使用OwnerQuota
+	OwnerQuotas map[string]int
+	//连接借出超过该时长时调用OnBorrowExceeded并按其返回值处理，大于0时启动后台检查，
+	//检查间隔为该时长的1/4，用于多租户共享的连接池主动回收长期占用的连接，
+	//使用pooldebug构建时连接池不持有借出连接的引用，不做检查
+	MaxBorrowDuration time.Duration
+	//借出超时的处理策略，返回记录日志、标记不可用或强制关闭，为空时只记录日志
+	OnBorrowExceeded func(conn interface{}, info ConnInfo) BorrowAction
+	//每秒最多取出的连接数，大于0时启用令牌桶限流，与连接数上限无关，用于遵守后端的QPS限制
+	//令牌不足时Get等待，ctx截止或WaitTimeout之前无法取得令牌时返回ErrRateLimited，TryGet、GetIdle不等待
+	GetRateLimit float64
+	//令牌桶的容量，即允许的突发取出数，默认为GetRateLimit，至少为1
+	GetRateBurst int
+	//连接数已达上限时回调，waiters为当前等待者数，可用于应用层限流
+	OnExhausted func(waiters int)
+	//事件通道的缓冲长度，默认64，参见ChannelPool.Events
+	EventBuffer int
+	//日志输出，为空时使用标准库log
+	Logger *log.Logger
+	//结构化日志输出，配置后优先于Logger，并额外输出连接关闭(Debug)和拨号失败(Warn)的记录
+	Slog *slog.Logger
+	//连接池名称，附加到日志、事件、StatsD标签、expvar、Prometheus及DumpState中，用于区分多个连接池
+	Name string
+	//附加的标签，与Name一样传递到日志、事件、StatsD标签、expvar、Prometheus及DumpState中
+	Labels map[string]string
+	//连接池状态变化时回调
+	OnStateChange func(from, to State)
+	//为true时每次存取连接后校验内部不变式：空闲加借出的连接数不超过上限、同一连接不会同时空闲和借出、
+	//已关闭的连接池不再借出连接。用于测试及排查，空闲连接的存取将被串行化
+	CheckInvariants bool
+	//违反不变式时回调，为空时panic
+	OnInvariantViolation func(error)
+	//Healthy使用的阈值
+	Health HealthThresholds
+	//持续不健康时自动重建连接池的看门狗
+	Watchdog WatchdogConfig
+	//短时间内大量连接失败时清空空闲连接
+	FlushOnErrors FlushConfig
 }
 
-//channelPool 存放链接信息
-type channelPool struct {
+// ChannelPool 存放链接信息
+type ChannelPool struct {
 	mu          sync.Mutex
-	conns       chan *idleConn
-	factory     func() (interface{}, error)
+	conns       idleQueue
+	factory     LabeledFactory
 	close       func(interface{}) error
-	idleTimeout time.Duration
+	ping        func(interface{}) error
+	prepare     func(interface{}) error
+	onBorrow    func(interface{}, time.Duration) error
+	reset       func(interface{}) error
+	onClose     func(interface{}, CloseReason)
+	origin      func(interface{}) string
+	ioTimeout   time.Duration
+	netErrOnly  bool
+	maxCap      int
+	maxOverflow int
+	wait        bool
+	noDial      bool
+	strictHits  bool
+	eviction    EvictionPolicy
+	now         func() time.Time
+	clock       *coarseClock
+	timeSource  Clock
+	logger      *log.Logger
+	slog        *slog.Logger
+	name        string
+	labels      map[string]string
+	//logf输出的前缀，包含名称及标签
+	logPrefix string
+	//Release时关闭，通知后台goroutine及Wait退出
+	done chan struct{}
+	//离开StateOpen时关闭，唤醒阻塞在Get中的等待者
+	stopping chan struct{}
+	//状态变化时回调
+	onStateChange func(from, to State)
 
-	busyConnsMu sync.Mutex
-	busyConns   []*idleConn
+	//关闭单条连接的超时时间，用于日志
+	closeTimeout time.Duration
+	//Use追加的拦截器，类型为[]InterceptorFunc，写时复制
+	interceptors atomic.Value
 
-	stats Stats
+	//开启CheckInvariants时的空闲及借出连接集合
+	inv *invariants
+
+	//每个P暂存的最近放回的连接，为nil时未开启StickyStash
+	stash   *stickyStash
+	stashed int32
+
+	//可由UpdateConfig在运行时修改的配置，修改时持有mu，读取时使用原子操作
+	idleTimeout int64
+	maxLifetime int64
+	waitTimeout int64
+	maxIdle     int32
+
+	//IdleTimeout及MaxLifetime的抖动比例
+	expiryJitter float64
+	//Release时并发关闭空闲连接的goroutine数
+	releaseConcurrency int
+	//Rotate后台补充连接的速率，同WarmupRate
+	warmupRate int
+	//Get的拨号名额，为nil时不限制同时拨号数
+	dialSem chan struct{}
+	//通过Pin固定的连接数，为0时Put无需查找PinnedConn
+	numPinned int32
+	//只借出特定空闲连接的等待者数，及唤醒它们的广播通道
+	filterWaiters int32
+	changedMu     sync.Mutex
+	changed       chan struct{}
+	//串行化按条件查找空闲连接，保证等待者重新查找时能看到其他查找者暂时取出的连接
+	scanMu sync.Mutex
+	//GetRateLimit的令牌桶，未配置时为nil
+	limiter *tokenBucket
+	//FlushOnErrors的失败统计，未配置时为nil
+	flush *errorFlush
+	//不可用连接关闭前的搁置时长及探测方法，未配置时brokenProbe为nil
+	brokenLinger time.Duration
+	brokenProbe  func(interface{}) error
+	//搁置等待探测的不可用连接及取消探测的方法
+	lingerMu  sync.Mutex
+	lingering map[*idleConn]func()
+
+	//Healthy的阈值，累计的拨号次数、失败次数，及Healthy计算失败率的窗口
+	health       HealthThresholds
+	dialAttempts uint64
+	dialFailures uint64
+	healthWindow dialWindow
+	//连接数达到上限的起始时间(UnixNano)，为0时未饱和
+	saturatedAt int64
+	//看门狗重建后的预建是否正在进行
+	rewarming int32
+
+	//最近分配的连接编号
+	nextID uint64
+
+	//当前工厂方法的代数，SetFactory时递增，由mu保护
+	gen uint32
+	//缓存的新建连接错误及其过期时间，由mu保护
+	dialErrTTL   time.Duration
+	dialErr      error
+	dialErrUntil time.Time
+	//代数小于minGen的连接在取出或放回时关闭
+	minGen uint32
+
+	//连接池状态，Get/Put据此无锁判断
+	state int32
+	//已创建且尚未关闭的连接数
+	numOpen int32
+	//已借出的连接数
+	numBusy int32
+	//正在新建的连接数
+	numDialing int32
+	//借出连接数降为0时关闭，由mu保护，Wait时按需创建
+	quiet chan struct{}
+	//等待连接的goroutine数
+	waiters int32
+	//借出连接数和等待者数的高水位
+	maxBusy    int32
+	maxWaiters int32
+	//空闲连接数的低水位和高水位，ResetStats及每次StartStatsReporter回调后重置为当前值
+	idleLow  int32
+	idleHigh int32
+	//有连接放回或名额释放时通知等待者
+	avail chan struct{}
+	//GetN串行执行
+	batchMu sync.Mutex
+
+	//GetFor的使用方配额及统计，由ownersMu保护
+	ownersMu          sync.Mutex
+	owners            map[string]*OwnerStats
+	ownerQuotas       map[string]int
+	defaultOwnerQuota int
+
+	//派生资源的创建及关闭方法
+	childFactory func(interface{}, string) (interface{}, error)
+	childClose   func(interface{}) error
+
+	onExhausted func(waiters int)
+
+	//事件通道，调用Events后eventsOn置1开始发送
+	events        chan Event
+	eventsOn      int32
+	eventsDropped uint64
+
+	//GetAffinity使用的key到连接trackKey的映射，及其反向索引，连接关闭时据此删除
+	affinityMu   sync.Mutex
+	affinity     map[string]interface{}
+	affinityKeys map[interface{}]map[string]struct{}
+
+	//各QoS等级预留的连接数及其总和
+	qosReserved      map[string]int
+	qosReservedTotal int32
+
+	//连接池创建的所有连接
+	tracked *connTracker
+
+	//异步关闭队列，Release时关闭并等待后台goroutine处理完毕
+	closeMu    sync.RWMutex
+	closeQueue chan closeRequest
+	closeDone  chan struct{}
+	//Release后异步关闭失败的错误，closeDone关闭前仅由后台goroutine访问
+	closeErrs []error
+
+	counters statsCounters
+	//统计区间的起始时间(UnixNano)，创建连接池及ResetStats时更新
+	statsSince int64
+	//工厂方法单次调用耗时的高水位(纳秒)，ResetStats时清零
+	maxDial int64
 }
 
 type idleConn struct {
-	conn interface{}
-	t    time.Time
+	conn    interface{}
+	t       time.Time
+	created time.Time
+	origin  string
+	labels  map[string]string
+	gen     uint32
+	//连接编号，用于日志中区分连接
+	id uint64
+	//IdleTimeout及MaxLifetime的抖动系数，范围为±ExpiryJitter
+	jitter float64
+	//所在的追踪分片，无法追踪的连接为nil
+	shard *trackShard
+	//以下字段由shard.mu保护
+	lent     bool
+	lentAt   time.Time
+	uses     uint64
+	owner    string
+	children *ChildPool
+	pin      *PinnedConn
+	//借出时长已超过MaxBorrowDuration并已处理
+	overdue bool
+}
+
+// info 返回连接的元数据快照
+func (ic *idleConn) info() ConnInfo {
+	return ConnInfo{
+		ID:       ic.id,
+		Created:  ic.created,
+		LastUsed: ic.t,
+		UseCount: ic.uses,
+		Origin:   ic.origin,
+		Busy:     ic.lent,
+		LentAt:   ic.lentAt,
+		Owner:    ic.owner,
+		Labels:   ic.labels,
+	}
 }
 
-type Stats struct {
-	Hits   uint32 // number of times free connection was found in the pool
-	Misses uint32 // number of times free connection was NOT found in the pool
+// idleConnPool 回收idleConn，避免Get/Put时重复分配
+var idleConnPool = sync.Pool{
+	New: func() interface{} { return new(idleConn) },
+}
 
-	TotalConns uint32 // number of total connections in the pool
+// closeRequest 异步关闭队列中的连接及关闭原因
+type closeRequest struct {
+	conn   interface{}
+	id     uint64
+	reason CloseReason
 }
 
-var _ Pooler = (*channelPool)(nil)
+var _ Pooler = (*ChannelPool)(nil)
+
+// defaultReleaseConcurrency Release时默认并发关闭连接的goroutine数
+const defaultReleaseConcurrency = 8
 
 // NewChannelPool 初始化链接
 func NewChannelPool(poolConfig *PoolConfig) Pooler {
+	return New(poolConfig)
+}
+
+// New 同NewChannelPool，返回*ChannelPool以使用Pooler之外的扩展方法
+func New(poolConfig *PoolConfig) *ChannelPool {
+	return newPool(poolConfig, newChanQueue)
+}
+
+// newPool 使用newQueue创建的空闲连接容器初始化连接池
+func newPool(poolConfig *PoolConfig, newQueue func(capacity int) idleQueue) *ChannelPool {
 	if poolConfig.MaxCap <= 0 {
 		poolConfig.MaxCap = 10
 	}
+	if poolConfig.MaxIdle <= 0 || poolConfig.MaxIdle > poolConfig.MaxCap {
+		poolConfig.MaxIdle = poolConfig.MaxCap
+	}
 
-	c := &channelPool{
-		conns:       make(chan *idleConn, poolConfig.MaxCap),
-		busyConns:   make([]*idleConn, 0, poolConfig.MaxCap),
-		factory:     poolConfig.Factory,
+	c := &ChannelPool{
+		conns:       newQueue(poolConfig.MaxCap),
+		factory:     unlabeled(poolConfig.Factory),
 		close:       poolConfig.Close,
-		idleTimeout: poolConfig.IdleTimeout,
+		ping:        poolConfig.Ping,
+		prepare:     poolConfig.Prepare,
+		onBorrow:    poolConfig.OnBorrow,
+		reset:       poolConfig.Reset,
+		onClose:     poolConfig.OnClose,
+		origin:      poolConfig.Origin,
+		ioTimeout:   poolConfig.IOTimeout,
+		netErrOnly:  poolConfig.DiscardOnNetError,
+		maxCap:      poolConfig.MaxCap,
+		maxOverflow: poolConfig.MaxOverflow,
+		wait:        poolConfig.Wait,
+		noDial:      poolConfig.DialOnEmpty != nil && !*poolConfig.DialOnEmpty,
+		strictHits:  poolConfig.ExcludeStaleHits,
+		eviction:    poolConfig.Eviction,
+		tracked:     newConnTracker(),
+		now:         time.Now,
+		timeSource:  systemClock{},
+		counters:    newStatsCounters(),
+		logger:      poolConfig.Logger,
+		done:        make(chan struct{}),
+		stopping:    make(chan struct{}),
+		avail:       make(chan struct{}, 1),
+		idleTimeout: int64(poolConfig.IdleTimeout),
+		maxLifetime: int64(poolConfig.MaxLifetime),
+		waitTimeout: int64(poolConfig.WaitTimeout),
+		maxIdle:     int32(poolConfig.MaxIdle),
+	}
+	c.onStateChange = poolConfig.OnStateChange
+	c.health = poolConfig.Health
+	c.name = poolConfig.Name
+	c.labels = make(map[string]string, len(poolConfig.Labels))
+	for k, v := range poolConfig.Labels {
+		c.labels[k] = v
+	}
+	c.logPrefix = c.labelPrefix()
+	if c.slog = poolConfig.Slog; c.slog != nil {
+		c.slog = c.slog.With(c.labelAttrs()...)
+	}
+	c.owners = make(map[string]*OwnerStats)
+	c.affinity = make(map[string]interface{})
+	c.affinityKeys = make(map[interface{}]map[string]struct{})
+	if poolConfig.EventBuffer <= 0 {
+		poolConfig.EventBuffer = defaultEventBuffer
+	}
+	c.events = make(chan Event, poolConfig.EventBuffer)
+	c.onExhausted = poolConfig.OnExhausted
+	c.dialErrTTL = poolConfig.DialErrorTTL
+	c.expiryJitter = poolConfig.ExpiryJitter
+	if poolConfig.ReleaseConcurrency <= 0 {
+		poolConfig.ReleaseConcurrency = defaultReleaseConcurrency
+	}
+	c.releaseConcurrency = poolConfig.ReleaseConcurrency
+	c.warmupRate = poolConfig.WarmupRate
+	if poolConfig.CheckInvariants {
+		c.inv = newInvariants(poolConfig.OnInvariantViolation)
+		c.conns = checkedQueue{idleQueue: c.conns, c: c}
+	}
+	if poolConfig.StickyStash {
+		c.stash = newStash()
+	}
+	if poolConfig.MaxConcurrentDials > 0 {
+		c.dialSem = make(chan struct{}, poolConfig.MaxConcurrentDials)
+	}
+	c.childFactory = poolConfig.ChildFactory
+	c.childClose = poolConfig.ChildClose
+	c.ownerQuotas = poolConfig.OwnerQuotas
+	c.defaultOwnerQuota = poolConfig.OwnerQuota
+	c.qosReserved = poolConfig.QoSReserved
+	for _, n := range c.qosReserved {
+		c.qosReservedTotal += int32(n)
+	}
+
+	if poolConfig.Clock != nil {
+		c.timeSource = poolConfig.Clock
+		c.now = poolConfig.Clock.Now
+	} else if poolConfig.ClockResolution > 0 {
+		c.clock = newCoarseClock(poolConfig.ClockResolution)
+		c.now = c.clock.Now
+	}
+	c.statsSince = c.now().UnixNano()
+
+	if poolConfig.LabeledFactory != nil {
+		c.factory = poolConfig.LabeledFactory
 	}
 
-	// for i := 0; i < poolConfig.InitialCap; i++ {
-	// 	conn, err := c.factory()
-	// 	if err != nil {
-	// 		c.Release()
-	// 		return nil, fmt.Errorf("factory is not able to fill the pool: %s", err)
-	// 	}
-	// 	c.conns <- &idleConn{conn: conn, t: time.Now()}
-	// }
+	if poolConfig.GetRateLimit > 0 {
+		c.limiter = newTokenBucket(poolConfig.GetRateLimit, poolConfig.GetRateBurst, c.now())
+	}
+
+	if c.close != nil && poolConfig.CloseTimeout > 0 {
+		c.closeTimeout = poolConfig.CloseTimeout
+		c.close = withCloseTimeout(c.close, poolConfig.CloseTimeout)
+	}
+
+	if poolConfig.MaxBorrowDuration > 0 {
+		c.enforceBorrowLoop(poolConfig.MaxBorrowDuration, poolConfig.OnBorrowExceeded)
+	}
+
+	if poolConfig.KeepAlive != nil && poolConfig.KeepAliveInterval > 0 {
+		c.keepAliveLoop(poolConfig.KeepAlive, poolConfig.KeepAliveInterval)
+	}
+
+	if poolConfig.AsyncCloseQueue > 0 {
+		c.closeQueue = make(chan closeRequest, poolConfig.AsyncCloseQueue)
+		c.closeDone = make(chan struct{})
+		go c.closeWorker(c.closeQueue, c.close)
+	}
+
+	if poolConfig.BrokenLinger > 0 {
+		c.brokenLinger = poolConfig.BrokenLinger
+		if c.brokenProbe = poolConfig.BrokenProbe; c.brokenProbe == nil {
+			c.brokenProbe = poolConfig.Ping
+		}
+	}
+
+	if poolConfig.FlushOnErrors.Failures > 0 {
+		c.flush = newErrorFlush(poolConfig.FlushOnErrors)
+	}
+
+	if poolConfig.Watchdog.Interval > 0 {
+		if poolConfig.Watchdog.Rewarm <= 0 {
+			poolConfig.Watchdog.Rewarm = poolConfig.InitialCap
+		}
+		c.watchdogLoop(poolConfig.Watchdog, poolConfig.WarmupRate)
+	}
+
+	if poolConfig.InitialCap > 0 {
+		if poolConfig.WarmupRate > 0 {
+			go c.warmup(poolConfig.InitialCap, poolConfig.WarmupRate)
+		} else {
+			c.warmup(poolConfig.InitialCap, 0)
+		}
+	}
 
 	return c
 }
 
-//getConns 获取所有连接
-func (c *channelPool) getConns() chan *idleConn {
-	c.mu.Lock()
-	conns := c.conns
-	c.mu.Unlock()
-	return conns
+// getConns 获取所有连接，连接池排空或已释放时返回nil
+func (c *ChannelPool) getConns() idleQueue {
+	if c.State() != StateOpen {
+		return nil
+	}
+	return c.conns
 }
 
 // Get 从pool中取一个连接
-func (c *channelPool) Get() (interface{}, error) {
-	conns := c.getConns()
-	if conns == nil {
-		return nil, ErrClosed
+func (c *ChannelPool) Get() (interface{}, error) {
+	return c.GetContext(context.Background())
+}
+
+// GetContext 从pool中取一个连接，连接数已达上限且配置了Wait时阻塞等待，
+// 直到有连接可用、超过WaitTimeout或ctx结束
+func (c *ChannelPool) GetContext(ctx context.Context) (interface{}, error) {
+	return c.get(ctx, c.qosLimit(""), nil)
+}
+
+// get 经过拦截器取一个连接，busyLimit大于0时借出连接数达到busyLimit视为连接池已满，
+// filter不为nil时按其限定借出的空闲连接，返回的错误均为*GetError
+func (c *ChannelPool) get(ctx context.Context, busyLimit int32, filter *idleFilter) (interface{}, error) {
+	var (
+		conn interface{}
+		err  error
+	)
+	closedBefore := c.inv != nil && c.State() == StateClosed
+	if !c.intercepted() {
+		conn, err = c.getConn(ctx, busyLimit, filter)
+	} else {
+		conn, err = c.intercept(ctx, OpGet, nil, func(ctx context.Context, _ interface{}) (interface{}, error) {
+			conn, err := c.getConn(ctx, busyLimit, filter)
+			if err != nil {
+				return nil, getErr(err)
+			}
+			return conn, nil
+		})
 	}
+	if err != nil {
+		return nil, getErr(err)
+	}
+	c.checkClosedGet(closedBefore, conn)
+	return conn, nil
+}
+
+// getConn 取一个连接
+func (c *ChannelPool) getConn(ctx context.Context, busyLimit int32, filter *idleFilter) (interface{}, error) {
+	conns, err := c.admit(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		timeout   <-chan time.Time
+		waitStart time.Time
+		//只借出特定空闲连接的等待者等待空闲连接变化的广播，而不是单个唤醒
+		changed <-chan struct{}
+	)
+	strict := filter != nil && filter.strict
+	waiting := false
+	defer func() {
+		if waiting {
+			atomic.AddInt32(&c.waiters, -1)
+			if strict {
+				atomic.AddInt32(&c.filterWaiters, -1)
+			}
+		}
+		if instrumented && !waitStart.IsZero() {
+			shard := c.counters.shard()
+			atomic.AddUint64(&shard.waits, 1)
+			atomic.AddUint64(&shard.waitNanos, uint64(time.Since(waitStart)))
+		}
+	}()
+
 	for {
-		select {
-		case wrapConn := <-conns:
-			if wrapConn == nil {
-				continue
-				// return nil, ErrClosed
-			}
-			// 判断是否超时，超时则丢弃
-			if timeout := c.idleTimeout; timeout > 0 {
-				if wrapConn.t.Add(timeout).Before(time.Now()) {
-					// 丢弃并关闭该链接
-					c.Close(wrapConn.conn)
-					continue
+		if strict && waiting {
+			changed = c.idleChanged()
+		}
+		dialLimited := false
+		if busyLimit <= 0 || atomic.LoadInt32(&c.numBusy) < busyLimit {
+			if wrapConn, skipped := c.takeIdle(conns, filter); wrapConn != nil {
+				c.hit(skipped)
+				if waiting {
+					c.notifyWaiter()
+				} else {
+					c.clearSaturated()
 				}
+				return c.lend(wrapConn, false), nil
 			}
 
-			// c.pushBusy(wrapConn)
-			atomic.AddUint32(&c.stats.Hits, 1)
-			return wrapConn.conn, nil
-		default:
-			conn, err := c.factory()
-			if err != nil {
+			n, ok := c.reserveGet()
+			if !ok && filter != nil && filter.evict && c.evictIdle(conns) {
+				n, ok = c.reserveGet()
+			}
+			if ok {
+				if c.acquireDial() {
+					if waiting {
+						c.notifyWaiter()
+					} else {
+						c.clearSaturated()
+					}
+					conn, err := c.dial(n)
+					c.releaseDial()
+					return conn, err
+				}
+				// 拨号名额已满，归还连接名额后排队，不唤醒其他等待者以免空转
+				atomic.AddInt32(&c.numOpen, -1)
+				dialLimited = true
+			}
+		}
+		if !c.wait && !dialLimited {
+			if c.noDial {
+				return nil, ErrNoIdleConn
+			}
+			c.exhausted()
+			return nil, ErrPoolExhausted
+		}
+
+		// 先登记为等待者再重新检查一次，避免错过等待前放回的连接
+		if !waiting {
+			waiting = true
+			updateMax(&c.maxWaiters, atomic.AddInt32(&c.waiters, 1))
+			if strict {
+				atomic.AddInt32(&c.filterWaiters, 1)
+			}
+			if c.stash != nil {
+				c.flushStash()
+			}
+			if !dialLimited && !c.noDial {
+				c.exhausted()
+			}
+			continue
+		}
+		if waitTimeout := c.loadWaitTimeout(); timeout == nil && waitTimeout > 0 {
+			timer := time.NewTimer(waitTimeout)
+			defer timer.Stop()
+			timeout = timer.C
+		}
+		if instrumented && waitStart.IsZero() {
+			waitStart = time.Now()
+		}
+		if strict {
+			if err := c.awaitChange(ctx, timeout, changed); err != nil {
 				return nil, err
 			}
-			// c.pushBusy(&idleConn{conn: conn, t: time.Now()})
-			atomic.AddUint32(&c.stats.Misses, 1)
-			return conn, nil
+			continue
+		}
+		if err := c.awaitAvail(ctx, timeout); err != nil {
+			return nil, err
 		}
 	}
 }
 
-// Put 将连接放回pool中
-func (c *channelPool) Put(conn interface{}) error {
-	if conn == nil {
-		return errors.New("pool is nil. rejecting")
+// admit 所有取连接方法的入口检查：连接池须未停止，并按GetRateLimit取一个令牌，
+// 不论随后借出空闲连接还是新建；block为false时不等待令牌
+func (c *ChannelPool) admit(ctx context.Context, block bool) (idleQueue, error) {
+	conns := c.getConns()
+	if conns == nil {
+		return nil, c.stateErr()
+	}
+	if c.limiter == nil {
+		return conns, nil
+	}
+	var err error
+	if block {
+		err = c.waitToken(ctx)
+	} else {
+		err = c.takeToken()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return conns, nil
+}
+
+// acquireDial 占用一个拨号名额，已满时返回false
+func (c *ChannelPool) acquireDial() bool {
+	if c.dialSem == nil {
+		return true
+	}
+	select {
+	case c.dialSem <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// releaseDial 归还拨号名额，并唤醒一个排队的调用者
+func (c *ChannelPool) releaseDial() {
+	if c.dialSem == nil {
+		return
 	}
+	<-c.dialSem
+	c.notifyWaiter()
+}
+
+// exhausted 连接数已达上限时发送事件并回调OnExhausted
+func (c *ChannelPool) exhausted() {
+	c.markSaturated()
+	c.emit(Event{Type: EventPoolExhausted})
+	if c.onExhausted != nil {
+		c.onExhausted(int(atomic.LoadInt32(&c.waiters)))
+	}
+}
+
+// Saturated 连接数已达上限且有等待者，可据此在应用层拒绝请求
+func (c *ChannelPool) Saturated() bool {
+	limit := c.limit()
+	return limit > 0 && atomic.LoadInt32(&c.numOpen) >= limit && atomic.LoadInt32(&c.waiters) > 0
+}
+
+// awaitAvail 等待有连接放回或名额释放，超时、ctx结束或连接池停止时返回错误，
+// ctx结束的等待者计入ShedWaiters
+func (c *ChannelPool) awaitAvail(ctx context.Context, timeout <-chan time.Time) error {
+	select {
+	case <-c.avail:
+		// 被唤醒时ctx已结束则放弃，把唤醒让给下一个等待者，避免取出的连接随即被丢弃
+		if err := ctx.Err(); err != nil {
+			if instrumented {
+				atomic.AddUint64(&c.counters.shard().shed, 1)
+			}
+			c.notifyWaiter()
+			return err
+		}
+		return nil
+	case <-timeout:
+		return ErrGetTimeout
+	case <-ctx.Done():
+		if instrumented {
+			atomic.AddUint64(&c.counters.shard().shed, 1)
+		}
+		return ctx.Err()
+	case <-c.stopping:
+		return c.stateErr()
+	}
+}
+
+// awaitChange 同awaitAvail，等待changed被关闭，用于只借出特定空闲连接的等待者，
+// 被唤醒时无需把唤醒让给其他等待者
+func (c *ChannelPool) awaitChange(ctx context.Context, timeout <-chan time.Time, changed <-chan struct{}) error {
+	select {
+	case <-changed:
+		return ctx.Err()
+	case <-timeout:
+		return ErrGetTimeout
+	case <-ctx.Done():
+		if instrumented {
+			atomic.AddUint64(&c.counters.shard().shed, 1)
+		}
+		return ctx.Err()
+	case <-c.stopping:
+		return c.stateErr()
+	}
+}
 
+// GetN 取出n个连接，全部取到才返回，任一个失败时放回已取出的连接
+// 多个GetN串行执行，避免各自持有部分连接而互相等待
+func (c *ChannelPool) GetN(ctx context.Context, n int) ([]interface{}, error) {
+	if limit := c.limit(); limit > 0 && n > int(limit) {
+		return nil, ErrPoolExhausted
+	}
+
+	c.batchMu.Lock()
+	defer c.batchMu.Unlock()
+
+	conns := make([]interface{}, 0, n)
+	for len(conns) < n {
+		conn, err := c.GetContext(ctx)
+		if err != nil {
+			for _, conn := range conns {
+				c.Put(conn)
+			}
+			return nil, err
+		}
+		conns = append(conns, conn)
+	}
+	return conns, nil
+}
+
+// TryGet 有空闲连接时立即取出，否则返回false，从不新建连接或阻塞等待
+func (c *ChannelPool) TryGet() (interface{}, bool) {
+	conn, err := c.getIdle()
+	return conn, err == nil
+}
+
+// GetIdle 只从空闲连接中取出，没有空闲连接时返回ErrNoIdleConn
+func (c *ChannelPool) GetIdle() (interface{}, error) {
+	conn, err := c.getIdle()
+	if err != nil {
+		return nil, getErr(err)
+	}
+	return conn, nil
+}
+
+// getIdle 同GetIdle，返回未包装的错误
+func (c *ChannelPool) getIdle() (interface{}, error) {
+	conns, err := c.admit(context.Background(), false)
+	if err != nil {
+		return nil, err
+	}
+	wrapConn, skipped := c.popIdle(conns)
+	if wrapConn == nil {
+		return nil, ErrNoIdleConn
+	}
+	c.hit(skipped)
+	return c.lend(wrapConn, false), nil
+}
+
+// Dial 总是新建一条连接，仍计入连接数上限，已达上限时返回ErrPoolExhausted，
+// 新建失败时与Get一样返回*GetError
+func (c *ChannelPool) Dial() (interface{}, error) {
+	if c.getConns() == nil {
+		return nil, c.stateErr()
+	}
+	n, ok := c.reserve()
+	if !ok {
+		c.exhausted()
+		return nil, ErrPoolExhausted
+	}
+	conn, err := c.dial(n)
+	if err != nil {
+		return nil, getErr(err)
+	}
+	return conn, nil
+}
+
+// popIdle 取出一条可用的空闲连接，超时或校验失败的连接将被关闭并计入StaleSkips，
+// 没有时返回nil，同时返回本次丢弃的连接数
+func (c *ChannelPool) popIdle(conns idleQueue) (*idleConn, int) {
+	skipped := 0
+	if c.stash != nil {
+		if wrapConn := c.stashGet(); wrapConn != nil {
+			reason, ok := c.reusable(wrapConn)
+			if ok {
+				return wrapConn, 0
+			}
+			c.discard(wrapConn, reason)
+			if instrumented {
+				atomic.AddUint64(&c.counters.shard().staleSkips, 1)
+			}
+			skipped++
+		}
+	}
+	for {
+		wrapConn := conns.pop()
+		updateMin(&c.idleLow, int32(conns.len()))
+		if wrapConn == nil {
+			return nil, skipped
+		}
+		if reason, ok := c.reusable(wrapConn); !ok {
+			c.discard(wrapConn, reason)
+			if instrumented {
+				atomic.AddUint64(&c.counters.shard().staleSkips, 1)
+			}
+			skipped++
+			continue
+		}
+		return wrapConn, skipped
+	}
+}
+
+// hit 记录一次命中，skipped为取到可用连接前丢弃的空闲连接数，
+// 大于0时计入StaleHits，配置了ExcludeStaleHits时不计入Hits
+func (c *ChannelPool) hit(skipped int) {
+	if !instrumented {
+		return
+	}
+	shard := c.counters.shard()
+	if skipped > 0 {
+		atomic.AddUint64(&shard.staleHits, 1)
+		if c.strictHits {
+			return
+		}
+	}
+	atomic.AddUint64(&shard.hits, 1)
+}
+
+// reusable 判断取出的空闲连接能否借出，不能时返回关闭原因
+func (c *ChannelPool) reusable(wrapConn *idleConn) (CloseReason, bool) {
+	// 判断是否超时，超时则丢弃
+	if timeout := c.loadIdleTimeout(); timeout > 0 {
+		if wrapConn.t.Add(wrapConn.jittered(timeout)).Before(c.now()) {
+			return CloseIdleTimeout, false
+		}
+	}
+	if c.expired(wrapConn) {
+		return CloseLifetime, false
+	}
+	if c.stale(wrapConn) {
+		return CloseDrained, false
+	}
+	if c.ping != nil && c.ping(wrapConn.conn) != nil {
+		return CloseValidation, false
+	}
+	if c.onBorrow != nil && c.onBorrow(wrapConn.conn, c.now().Sub(wrapConn.t)) != nil {
+		return CloseValidation, false
+	}
+	return 0, true
+}
+
+// limit 连接数上限，为0时不限制
+func (c *ChannelPool) limit() int32 {
+	if c.maxOverflow > 0 || c.wait {
+		return int32(c.maxCap + c.maxOverflow)
+	}
+	return 0
+}
+
+// reserve 占用一个新建连接的名额，返回占用后的连接数
+func (c *ChannelPool) reserve() (int32, bool) {
+	limit := c.limit()
+	for {
+		n := atomic.LoadInt32(&c.numOpen)
+		if limit > 0 && n >= limit {
+			return n, false
+		}
+		if atomic.CompareAndSwapInt32(&c.numOpen, n, n+1) {
+			return n + 1, true
+		}
+	}
+}
+
+// reserveGet Get新建连接前占用名额，严格模式下从不新建连接，总是返回false
+func (c *ChannelPool) reserveGet() (int32, bool) {
+	if c.noDial {
+		return 0, false
+	}
+	return c.reserve()
+}
+
+// unreserve 归还一个连接名额，并唤醒一个等待者
+func (c *ChannelPool) unreserve() {
+	atomic.AddInt32(&c.numOpen, -1)
+	c.notifyWaiter()
+}
+
+// dial 使用已占用的名额新建连接，n为占用后的连接数
+func (c *ChannelPool) dial(n int32) (interface{}, error) {
+	wrapConn, err := c.newConn(n)
+	if err != nil {
+		return nil, err
+	}
+	if c.prepare != nil {
+		if err := c.prepare(wrapConn.conn); err != nil {
+			c.discard(wrapConn, CloseValidation)
+			return nil, &GetError{Kind: KindValidation, Err: err}
+		}
+	}
+	if instrumented {
+		atomic.AddUint64(&c.counters.shard().misses, 1)
+	}
+	return c.lend(wrapConn, true), nil
+}
+
+// newConn 使用已占用的第n个名额新建连接，失败时释放名额
+func (c *ChannelPool) newConn(n int32) (*idleConn, error) {
 	c.mu.Lock()
-	// defer c.mu.Unlock()
+	factory, gen := c.factory, c.gen
+	if c.dialErr != nil && c.now().Before(c.dialErrUntil) {
+		err := c.dialErr
+		c.mu.Unlock()
+		c.unreserve()
+		if instrumented {
+			atomic.AddUint64(&c.counters.shard().dialErrHits, 1)
+		}
+		return nil, &GetError{Kind: KindCircuitOpen, Err: err}
+	}
+	c.mu.Unlock()
 
-	if c.conns == nil {
+	start := c.timeSource.Now()
+	atomic.AddUint64(&c.dialAttempts, 1)
+	var (
+		conn   interface{}
+		labels map[string]string
+		err    error
+	)
+	atomic.AddInt32(&c.numDialing, 1)
+	if c.intercepted() {
+		conn, labels, err = c.interceptDial(factory)
+	} else {
+		conn, labels, err = factory()
+	}
+	atomic.AddInt32(&c.numDialing, -1)
+	took := c.timeSource.Now().Sub(start)
+	if instrumented {
+		c.counters.shard().addDial(took, err != nil)
+		updateMax64(&c.maxDial, int64(took))
+	}
+	if err != nil {
+		atomic.AddUint64(&c.dialFailures, 1)
+		c.unreserve()
+		c.cacheDialErr(err)
+		c.logDialFailed(err, took)
+		return nil, &GetError{Kind: KindDialFailed, Err: err}
+	}
+	if c.dialErrTTL > 0 {
+		c.mu.Lock()
+		c.dialErr = nil
 		c.mu.Unlock()
-		return c.Close(conn)
 	}
+	if nc, ok := conn.(net.Conn); ok && (c.ioTimeout > 0 || c.netErrOnly) {
+		conn = newDeadlineConn(nc, c.ioTimeout, c.netErrOnly)
+	}
+	if c.maxOverflow > 0 && int(n) > c.maxCap {
+		if instrumented {
+			atomic.AddUint64(&c.counters.shard().overflows, 1)
+		}
+	}
+	wrapConn := c.popBusy(conn, c.now())
+	wrapConn.labels = labels
+	wrapConn.gen = gen
+	wrapConn.id = atomic.AddUint64(&c.nextID, 1)
+	if c.expiryJitter > 0 {
+		wrapConn.jitter = (rand.Float64()*2 - 1) * c.expiryJitter
+	}
+	if c.origin != nil {
+		wrapConn.origin = c.origin(conn)
+	}
+	c.logCreated(wrapConn)
+	c.emit(Event{Type: EventConnCreated, Conn: conn, ConnID: wrapConn.id})
+	return wrapConn, nil
+}
+
+// interceptDial 经拦截器调用工厂方法，与newConn分开以免闭包捕获的变量在未配置拦截器时也逃逸到堆上
+func (c *ChannelPool) interceptDial(factory LabeledFactory) (conn interface{}, labels map[string]string, err error) {
+	conn, err = c.intercept(context.Background(), OpDial, nil, func(context.Context, interface{}) (interface{}, error) {
+		var conn interface{}
+		conn, labels, err = factory()
+		return conn, err
+	})
+	return conn, labels, err
+}
+
+// cacheDialErr 缓存工厂方法返回的错误，DialErrorTTL内的新建连接直接返回该错误
+func (c *ChannelPool) cacheDialErr(err error) {
+	if c.dialErrTTL <= 0 {
+		return
+	}
+	now := c.now()
+	c.mu.Lock()
+	opened := c.dialErr == nil || !now.Before(c.dialErrUntil)
+	c.dialErr = err
+	c.dialErrUntil = now.Add(c.dialErrTTL)
 	c.mu.Unlock()
+	if opened {
+		c.emit(Event{Type: EventCircuitOpened})
+	}
+}
 
-	// cn := c.popBusy()
-	// if cn != nil {
-	// 	cn.conn = conn
-	// 	cn.t = time.Now()
-	// }
+// notifyWaiter 有连接放回或名额释放时唤醒一个等待者，并唤醒所有只借出特定空闲连接的等待者
+func (c *ChannelPool) notifyWaiter() {
+	if atomic.LoadInt32(&c.waiters) == 0 {
+		return
+	}
+	c.signalWaiter()
+	if atomic.LoadInt32(&c.filterWaiters) > 0 {
+		c.broadcastChange()
+	}
+}
 
+// signalWaiter 唤醒一个等待c.avail的等待者
+func (c *ChannelPool) signalWaiter() {
 	select {
-	case c.conns <- &idleConn{conn: conn, t: time.Now()}:
-		// case c.conns <- cn:
-		return nil
+	case c.avail <- struct{}{}:
 	default:
-		// 连接池已满，直接关闭该链接
-		return c.Close(conn)
 	}
 }
 
-//Close 关闭单条连接
-func (c *channelPool) Close(conn interface{}) error {
+// idleChanged 返回下次有连接放回或名额释放时关闭的通道
+func (c *ChannelPool) idleChanged() <-chan struct{} {
+	c.changedMu.Lock()
+	defer c.changedMu.Unlock()
+	if c.changed == nil {
+		c.changed = make(chan struct{})
+	}
+	return c.changed
+}
+
+// broadcastChange 唤醒所有等待idleChanged的等待者
+func (c *ChannelPool) broadcastChange() {
+	c.changedMu.Lock()
+	if c.changed != nil {
+		close(c.changed)
+		c.changed = nil
+	}
+	c.changedMu.Unlock()
+}
+
+// updateMin 更新低水位
+func updateMin(min *int32, n int32) {
+	if !instrumented {
+		return
+	}
+	for {
+		cur := atomic.LoadInt32(min)
+		if n >= cur || atomic.CompareAndSwapInt32(min, cur, n) {
+			return
+		}
+	}
+}
+
+// updateMax 更新高水位
+func updateMax(max *int32, n int32) {
+	if !instrumented {
+		return
+	}
+	for {
+		old := atomic.LoadInt32(max)
+		if n <= old || atomic.CompareAndSwapInt32(max, old, n) {
+			return
+		}
+	}
+}
+
+// updateMax64 同updateMax，用于int64
+func updateMax64(max *int64, n int64) {
+	if !instrumented {
+		return
+	}
+	for {
+		old := atomic.LoadInt64(max)
+		if n <= old || atomic.CompareAndSwapInt64(max, old, n) {
+			return
+		}
+	}
+}
+
+// Put 将连接放回pool中
+func (c *ChannelPool) Put(conn interface{}) error {
+	if !c.intercepted() {
+		return c.put(conn)
+	}
+	_, err := c.intercept(context.Background(), OpPut, conn, func(_ context.Context, conn interface{}) (interface{}, error) {
+		return nil, c.put(conn)
+	})
+	return err
+}
+
+// put 放回连接
+func (c *ChannelPool) put(conn interface{}) error {
 	if conn == nil {
 		return errors.New("pool is nil. rejecting")
 	}
-	if c.close != nil {
-		return c.close(conn)
+
+	if parked, err := c.parkPinned(conn); parked {
+		return err
 	}
-	return nil
+	wrapConn, err := c.giveBack(conn)
+	if err != nil {
+		return err
+	}
+	return c.putIdle(wrapConn)
 }
 
-//Release 释放连接池中所有链接
-func (c *channelPool) Release() {
+// PutAll 将多个连接放回pool中，超出空闲上限的连接被关闭
+// 返回所有放回失败的错误
+func (c *ChannelPool) PutAll(conns []interface{}) error {
+	var errs []error
+	wrapConns := make([]*idleConn, 0, len(conns))
+	for _, conn := range conns {
+		if conn == nil {
+			errs = append(errs, errors.New("pool is nil. rejecting"))
+			continue
+		}
+		if parked, err := c.parkPinned(conn); parked {
+			if err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		wrapConn, err := c.markReturned(conn)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		wrapConns = append(wrapConns, wrapConn)
+	}
+
+	for _, wrapConn := range wrapConns {
+		c.releaseBusy()
+		if err := c.putIdle(wrapConn); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// putIdle 将已归还的连接放入空闲连接中，不满足复用条件时关闭
+func (c *ChannelPool) putIdle(wrapConn *idleConn) error {
+	conns := c.getConns()
+	if conns == nil {
+		return c.discardStopped(wrapConn)
+	}
+
+	if isUnusable(wrapConn.conn) {
+		if c.lingerable(wrapConn.conn) {
+			c.linger(wrapConn)
+			return nil
+		}
+		return c.discard(wrapConn, CloseBroken)
+	}
+	if c.expired(wrapConn) {
+		return c.discard(wrapConn, CloseLifetime)
+	}
+	if c.stale(wrapConn) {
+		return c.discard(wrapConn, CloseDrained)
+	}
+
+	// 连接数超出MaxCap，说明有溢出连接未关闭，直接关闭放回的连接
+	if c.maxOverflow > 0 && int(atomic.LoadInt32(&c.numOpen)) > c.maxCap {
+		return c.discard(wrapConn, CloseOverflow)
+	}
+
+	if c.reset != nil {
+		if err := c.reset(wrapConn.conn); err != nil {
+			return c.discard(wrapConn, CloseReset)
+		}
+	}
+
+	if c.stash != nil && c.idleLen(conns) < c.loadMaxIdle() && c.stashPut(wrapConn) {
+		return nil
+	}
+	if c.idleLen(conns) < c.loadMaxIdle() && conns.push(wrapConn) {
+		updateMax(&c.idleHigh, int32(conns.len()))
+		c.drainIfClosed()
+		c.notifyWaiter()
+		return nil
+	}
+
+	if c.eviction != nil {
+		return c.evict(wrapConn)
+	}
+	// 空闲连接已达上限，直接关闭该链接
+	return c.discard(wrapConn, ClosePoolFull)
+}
+
+// expired 判断连接的存活时间是否超过MaxLifetime
+func (c *ChannelPool) expired(wrapConn *idleConn) bool {
+	maxLifetime := c.loadMaxLifetime()
+	return maxLifetime > 0 && c.now().Sub(wrapConn.created) > wrapConn.jittered(maxLifetime)
+}
+
+// jittered 按连接的抖动系数调整超时时间
+func (ic *idleConn) jittered(d time.Duration) time.Duration {
+	if ic.jitter == 0 {
+		return d
+	}
+	return time.Duration(float64(d) * (1 + ic.jitter))
+}
+
+// SetFactory 替换生成连接的方法，之后新建的连接均由f生成
+// drainOld为true时，此前创建的连接在下一次取出或放回时关闭，使流量逐步迁移到新连接
+func (c *ChannelPool) SetFactory(f Factory, drainOld bool) {
 	c.mu.Lock()
-	conns := c.conns
-	c.conns = nil
-	c.factory = nil
-	closeFun := c.close
-	c.close = nil
+	c.factory = unlabeled(f)
+	c.gen++
+	c.dialErr = nil
+	if drainOld {
+		atomic.StoreUint32(&c.minGen, c.gen)
+	}
 	c.mu.Unlock()
+}
+
+// stale 判断连接是否由已替换的工厂方法生成且需要关闭
+func (c *ChannelPool) stale(wrapConn *idleConn) bool {
+	return wrapConn.gen < atomic.LoadUint32(&c.minGen)
+}
 
+// evict 连接池已满时，由淘汰策略从空闲连接和正在放回的连接中选出一条关闭
+func (c *ChannelPool) evict(wrapConn *idleConn) error {
+	conns := c.getConns()
 	if conns == nil {
-		return
+		return c.discardStopped(wrapConn)
+	}
+	c.flushStash()
+
+	candidates := append(sampleIdle(conns, evictSample), wrapConn)
+
+	infos := make([]ConnInfo, len(candidates))
+	for i, ic := range candidates {
+		infos[i] = ic.info()
+	}
+	victim := c.eviction.Victim(infos)
+	if victim < 0 || victim >= len(candidates) {
+		victim = len(candidates) - 1
+	}
+
+	for i, ic := range candidates {
+		if i == victim {
+			continue
+		}
+		if c.idleLen(conns) < c.loadMaxIdle() && conns.push(ic) {
+			continue
+		}
+		c.discard(ic, ClosePoolFull)
+	}
+	c.drainIfClosed()
+	return c.discard(candidates[victim], ClosePoolFull)
+}
+
+// Close 关闭单条连接
+func (c *ChannelPool) Close(conn interface{}) error {
+	if !c.intercepted() {
+		return c.closeWith(conn, CloseBroken)
+	}
+	_, err := c.intercept(context.Background(), OpClose, conn, func(_ context.Context, conn interface{}) (interface{}, error) {
+		return nil, c.closeWith(conn, CloseBroken)
+	})
+	return err
+}
+
+// closeWith 以reason关闭借出的连接
+func (c *ChannelPool) closeWith(conn interface{}, reason CloseReason) error {
+	if conn == nil {
+		return errors.New("pool is nil. rejecting")
 	}
+	wrapConn, err := c.giveBack(conn)
+	switch err {
+	case nil:
+	case ErrUnknownConn:
+		// 不属于本连接池的连接，仅关闭不计数
+		if c.close != nil {
+			return c.close(conn)
+		}
+		return nil
+	default:
+		return err
+	}
+	req := c.retire(wrapConn, reason)
+	c.unreserve()
+	return c.closeConn(c.close, req)
+}
+
+// discard 关闭连接池主动丢弃的连接，配置了异步关闭时交由后台goroutine关闭
+func (c *ChannelPool) discard(wrapConn *idleConn, reason CloseReason) error {
+	req := c.retire(wrapConn, reason)
+	c.unreserve()
 
-	close(conns)
-	for wrapConn := range conns {
-		closeFun(wrapConn.conn)
+	c.closeMu.RLock()
+	if c.closeQueue != nil {
+		select {
+		case c.closeQueue <- req:
+			c.closeMu.RUnlock()
+			return nil
+		default:
+		}
 	}
+	c.closeMu.RUnlock()
+	return c.closeConn(c.close, req)
+}
+
+// retire 记录关闭日志并停止追踪wrapConn，返回关闭请求，之后wrapConn会被回收复用
+func (c *ChannelPool) retire(wrapConn *idleConn, reason CloseReason) closeRequest {
+	c.logClosed(wrapConn, reason)
+	req := closeRequest{conn: wrapConn.conn, id: wrapConn.id, reason: reason}
+	c.untrack(wrapConn)
+	return req
 }
 
-//Len 连接池中已有的连接
-func (c *channelPool) Len() int {
-	return len(c.getConns())
+// closeConn 记录关闭原因并调用关闭方法
+func (c *ChannelPool) closeConn(closeFun func(interface{}) error, req closeRequest) error {
+	conn := req.conn
+	if instrumented {
+		atomic.AddUint64(&c.counters.shard().closes[req.reason], 1)
+	}
+	c.emit(Event{Type: EventConnClosed, Conn: conn, ConnID: req.id, Reason: req.reason})
+	if c.onClose != nil {
+		c.onClose(conn, req.reason)
+	}
+	if c.flush != nil {
+		c.recordFailure(req.reason)
+	}
+	if closeFun == nil {
+		return nil
+	}
+	err := closeFun(conn)
+	if err == ErrCloseTimeout {
+		c.logAttrs(slog.LevelWarn, "close timed out, abandoned",
+			slog.Uint64("conn_id", req.id), slog.String("conn_type", fmt.Sprintf("%T", conn)), slog.Duration("timeout", c.closeTimeout))
+	}
+	return err
+}
+
+// withCloseTimeout 为关闭方法增加超时，超时后放弃等待并返回ErrCloseTimeout
+func withCloseTimeout(closeFun func(interface{}) error, timeout time.Duration) func(interface{}) error {
+	return func(conn interface{}) error {
+		done := make(chan error, 1)
+		go func() {
+			done <- closeFun(conn)
+		}()
+
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		select {
+		case err := <-done:
+			return err
+		case <-timer.C:
+			return ErrCloseTimeout
+		}
+	}
+}
+
+// closeWorker 后台关闭被丢弃的连接，直到队列关闭
+func (c *ChannelPool) closeWorker(queue chan closeRequest, closeFun func(interface{}) error) {
+	defer close(c.closeDone)
+	for req := range queue {
+		err := c.closeConn(closeFun, req)
+		if err == nil {
+			continue
+		}
+		// Release之后的关闭错误由ReleaseErr返回
+		select {
+		case <-c.done:
+			c.closeErrs = append(c.closeErrs, err)
+		default:
+		}
+	}
 }
 
-func (p *channelPool) popBusy() *idleConn {
-	p.busyConnsMu.Lock()
-	defer p.busyConnsMu.Unlock()
+// Release 释放连接池中所有链接
+func (c *ChannelPool) Release() {
+	c.ReleaseErr()
+}
 
-	if len(p.busyConns) == 0 {
+// ReleaseErr 同Release，并发关闭空闲连接，返回所有关闭失败的错误，
+// 包括异步关闭队列中尚未关闭的连接，重复调用时返回nil
+func (c *ChannelPool) ReleaseErr() error {
+	if !c.transition(StateOpen, StateClosed) && !c.transition(StateDraining, StateClosed) {
 		return nil
 	}
 
-	idx := len(p.busyConns) - 1
-	cn := p.busyConns[idx]
-	p.busyConns = p.busyConns[:idx]
+	close(c.done)
+	err := c.drain(CloseRelease)
+	if c.clock != nil {
+		c.clock.Stop()
+	}
+
+	// 等待异步关闭队列中的连接全部关闭
+	c.closeMu.Lock()
+	queue := c.closeQueue
+	c.closeQueue = nil
+	c.closeMu.Unlock()
+	if queue != nil {
+		close(queue)
+		<-c.closeDone
+		err = errors.Join(append([]error{err}, c.closeErrs...)...)
+	}
+	return err
+}
+
+// drain 以reason关闭所有空闲连接，最多releaseConcurrency个并发，返回所有关闭失败的错误
+func (c *ChannelPool) drain(reason CloseReason) error {
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	sem := make(chan struct{}, c.releaseConcurrency)
+	c.flushStash()
+	c.closeLingering(func(interface{}, ConnInfo) bool { return true }, reason)
+	for {
+		wrapConn := c.conns.pop()
+		if wrapConn == nil {
+			break
+		}
+		req := c.retire(wrapConn, reason)
+		c.unreserve()
+
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if err := c.closeConn(c.close, req); err != nil {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// drainIfClosed 放回连接的同时连接池被排空或释放时，关闭放回的连接
+func (c *ChannelPool) drainIfClosed() {
+	switch c.State() {
+	case StateDraining:
+		c.drain(CloseDrained)
+	case StateClosed:
+		c.drain(CloseRelease)
+	}
+}
+
+// discardStopped 连接池排空或已释放时关闭放回的连接，并返回对应状态的错误
+func (c *ChannelPool) discardStopped(wrapConn *idleConn) error {
+	reason, stateErr := CloseRelease, ErrClosed
+	if c.State() == StateDraining {
+		reason, stateErr = CloseDrained, ErrDraining
+	}
+	if err := c.discard(wrapConn, reason); err != nil {
+		return err
+	}
+	return stateErr
+}
+
+// Len 连接池中已有的连接
+func (c *ChannelPool) Len() int {
+	conns := c.getConns()
+	if conns == nil {
+		return 0
+	}
+	return c.idleLen(conns)
+}
+
+// trackable 连接可作为map的key时才能追踪
+// 池内的连接只在创建时判断一次，之后以idleConn.shard是否为nil区分
+func trackable(conn interface{}) bool {
+	return reflect.TypeOf(conn).Comparable()
+}
+
+// track 开始追踪新建的连接
+func (c *ChannelPool) track(wrapConn *idleConn) {
+	if conn := wrapConn.conn; trackable(conn) {
+		c.tracked.add(trackKey(conn), wrapConn)
+	}
+}
+
+// lend 将连接标记为借出，dialed为true时表示新建的连接，同时开始追踪
+// 无法追踪的连接直接回收其idleConn
+func (c *ChannelPool) lend(wrapConn *idleConn, dialed bool) interface{} {
+	conn := wrapConn.conn
+	updateMax(&c.maxBusy, atomic.AddInt32(&c.numBusy, 1))
+	if dialed {
+		c.track(wrapConn)
+	}
+	s := wrapConn.shard
+	if s == nil {
+		c.pushBusy(wrapConn)
+		return conn
+	}
+
+	c.checkLend(wrapConn)
+	s.mu.Lock()
+	wrapConn.lent = true
+	wrapConn.overdue = false
+	wrapConn.lentAt = c.now()
+	wrapConn.uses++
+	c.onLend(wrapConn)
+	s.mu.Unlock()
+	return conn
+}
+
+// owns 判断连接是否由本连接池创建且尚未关闭
+func (c *ChannelPool) owns(conn interface{}) bool {
+	if !trackable(conn) {
+		return false
+	}
+	ok := false
+	c.tracked.lookup(trackKey(conn), func(wrapConn *idleConn) {
+		ok = wrapConn != nil
+	})
+	return ok
+}
+
+// giveBack 将借出的连接标记为已归还，并返回其idleConn
+func (c *ChannelPool) giveBack(conn interface{}) (*idleConn, error) {
+	wrapConn, err := c.markReturned(conn)
+	if err != nil {
+		return nil, err
+	}
+	c.releaseBusy()
+	return wrapConn, nil
+}
+
+// markReturned 同giveBack，调用方负责减少借出连接数
+func (c *ChannelPool) markReturned(conn interface{}) (*idleConn, error) {
+	// conn由调用方传入，不一定来自本连接池，需判断能否作为key
+	if !trackable(conn) {
+		return c.popBusy(conn, c.now()), nil
+	}
+
+	key := trackKey(conn)
+	s := c.tracked.shard(key)
+	s.mu.Lock()
+	wrapConn, ok := s.conns[key]
+	if !ok {
+		s.mu.Unlock()
+		return nil, ErrUnknownConn
+	}
+	if !wrapConn.lent {
+		s.mu.Unlock()
+		return nil, ErrDoublePut
+	}
+	wrapConn.lent = false
+	wrapConn.t = c.now()
+	c.checkGiveBack(conn)
+	if owner := wrapConn.owner; owner != "" {
+		wrapConn.owner = ""
+		c.releaseOwner(owner, false)
+	}
+	c.onGiveBack(wrapConn, conn)
+	s.mu.Unlock()
+	return wrapConn, nil
+}
+
+// untrack 停止追踪即将关闭的连接，并回收其idleConn
+func (c *ChannelPool) untrack(wrapConn *idleConn) {
+	var children *ChildPool
+	if s := wrapConn.shard; s != nil {
+		conn := wrapConn.conn
+		s.mu.Lock()
+		if key := trackKey(conn); s.conns[key] == wrapConn {
+			delete(s.conns, key)
+			c.checkGiveBack(conn)
+		}
+		c.unpinLocked(wrapConn)
+		children = wrapConn.children
+		s.mu.Unlock()
+		c.forgetAffinity(conn)
+	}
+	// 先于父连接关闭派生资源
+	if children != nil {
+		children.release()
+	}
+	c.pushBusy(wrapConn)
+}
+
+// popBusy 从回收的idleConn中取一个包装新的连接
+func (p *ChannelPool) popBusy(conn interface{}, now time.Time) *idleConn {
+	cn := idleConnPool.Get().(*idleConn)
+	cn.conn = conn
+	cn.t = now
+	cn.created = now
 	return cn
 }
 
-func (p *channelPool) pushBusy(cn *idleConn) {
+// pushBusy 回收已关闭连接的idleConn
+func (p *ChannelPool) pushBusy(cn *idleConn) {
 	if cn != nil {
-		p.busyConnsMu.Lock()
-		defer p.busyConnsMu.Unlock()
+		*cn = idleConn{}
+		idleConnPool.Put(cn)
+	}
+}
 
-		p.busyConns = append(p.busyConns, cn)
+// ConnInfo 返回连接池追踪的所有连接（空闲及借出）的元数据快照
+// 无法作为map key的连接不会被追踪，也不会出现在结果中
+func (c *ChannelPool) ConnInfo() []ConnInfo {
+	infos := make([]ConnInfo, 0, c.tracked.len())
+	c.tracked.each(func(_ interface{}, wrapConn *idleConn) {
+		infos = append(infos, wrapConn.info())
+	})
+	return infos
+}
+
+// ForEachIdle 遍历空闲连接，fn返回false时停止遍历
+// fn调用期间连接仍可能被其他goroutine借出，不应在fn中使用连接
+func (c *ChannelPool) ForEachIdle(fn func(conn interface{}, info ConnInfo) bool) {
+	type idleEntry struct {
+		conn interface{}
+		info ConnInfo
+	}
+	var entries []idleEntry
+	c.tracked.each(func(_ interface{}, wrapConn *idleConn) {
+		if !wrapConn.lent {
+			entries = append(entries, idleEntry{conn: wrapConn.conn, info: wrapConn.info()})
+		}
+	})
+
+	for _, e := range entries {
+		if !fn(e.conn, e.info) {
+			return
+		}
+	}
+}
+
+// EvictWhere 关闭所有满足pred的空闲连接及BrokenLinger搁置的连接，返回关闭的连接数
+// 每条连接判断期间暂时从连接池中取出，不会被借出
+func (c *ChannelPool) EvictWhere(pred func(conn interface{}, info ConnInfo) bool) int {
+	return c.filterIdle(pred, CloseEvicted) + c.closeLingering(pred, CloseEvicted)
+}
+
+// filterIdle 以reason关闭所有满足pred的空闲连接，返回关闭的连接数
+func (c *ChannelPool) filterIdle(pred func(conn interface{}, info ConnInfo) bool, reason CloseReason) int {
+	conns := c.getConns()
+	if conns == nil {
+		return 0
+	}
+	// 暂存的连接也需经过pred
+	c.flushStash()
+
+	// 逐条取出判断并立即放回，pred可能较慢（如KeepAlive的网络探测），期间其余空闲连接仍可被借出
+	evicted := 0
+	for n := conns.len(); n > 0; n-- {
+		wrapConn := conns.pop()
+		if wrapConn == nil {
+			break
+		}
+		if pred(wrapConn.conn, wrapConn.lockedInfo()) {
+			c.discard(wrapConn, reason)
+			evicted++
+			continue
+		}
+		if c.idleLen(conns) < c.loadMaxIdle() && conns.push(wrapConn) {
+			continue
+		}
+		c.discard(wrapConn, ClosePoolFull)
+	}
+	c.drainIfClosed()
+	c.notifyWaiter()
+	return evicted
+}
+
+// BusyLen 已借出的连接数
+func (p *ChannelPool) BusyLen() int {
+	return int(atomic.LoadInt32(&p.numBusy))
+}
+
+// releaseBusy 借出连接数减一，降为0时唤醒Wait
+func (c *ChannelPool) releaseBusy() {
+	if atomic.AddInt32(&c.numBusy, -1) != 0 {
+		return
+	}
+	c.mu.Lock()
+	if c.quiet != nil {
+		close(c.quiet)
+		c.quiet = nil
+	}
+	c.mu.Unlock()
+}
+
+// Wait 阻塞直到借出的连接全部放回或关闭，或ctx结束，连接池被释放时返回ErrClosed
+// 用于优雅关闭时在Release之前等待进行中的请求完成
+func (c *ChannelPool) Wait(ctx context.Context) error {
+	for {
+		c.mu.Lock()
+		if atomic.LoadInt32(&c.numBusy) == 0 {
+			c.mu.Unlock()
+			return nil
+		}
+		if c.quiet == nil {
+			c.quiet = make(chan struct{})
+		}
+		quiet := c.quiet
+		c.mu.Unlock()
+
+		select {
+		case <-quiet:
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-c.done:
+			return ErrClosed
+		}
 	}
 }
 
-func (p *channelPool) BusyLen() int {
-	p.busyConnsMu.Lock()
-	defer p.busyConnsMu.Unlock()
-	return len(p.busyConns)
+// clamp32 将v限制在[0, max]内
+func clamp32(v, max int32) int32 {
+	if v > max {
+		v = max
+	}
+	if v < 0 {
+		v = 0
+	}
+	return v
 }
 
-func (p *channelPool) Stats() *Stats {
-	return &Stats{
-		Hits:       atomic.LoadUint32(&p.stats.Hits),
-		Misses:     atomic.LoadUint32(&p.stats.Misses),
-		TotalConns: uint32(p.Len()),
+func (p *ChannelPool) Stats() *Stats {
+	// 空闲连接数取自空闲队列及暂存，各计数分别读取，借出与归还之间的变化可能使各项之和略大于total，
+	// 此时依次压低dialing、idle，保证IdleConns+BusyConns+DialingConns不超过TotalConns
+	var idle int32
+	if conns := p.getConns(); conns != nil {
+		idle = int32(p.idleLen(conns))
 	}
+	busy, dialing := atomic.LoadInt32(&p.numBusy), atomic.LoadInt32(&p.numDialing)
+	total := atomic.LoadInt32(&p.numOpen)
+	busy = clamp32(busy, total)
+	dialing = clamp32(dialing, total-busy)
+	idle = clamp32(idle, total-busy-dialing)
+	stats := &Stats{
+		TotalConns:   uint32(total),
+		IdleConns:    uint32(idle),
+		BusyConns:    uint32(busy),
+		DialingConns: uint32(dialing),
+
+		Waiters:    uint32(atomic.LoadInt32(&p.waiters)),
+		MaxBusy:    uint32(atomic.LoadInt32(&p.maxBusy)),
+		MaxWaiters: uint32(atomic.LoadInt32(&p.maxWaiters)),
+		IdleLow:    uint32(atomic.LoadInt32(&p.idleLow)),
+		IdleHigh:   uint32(atomic.LoadInt32(&p.idleHigh)),
+
+		MaxDialDuration: time.Duration(atomic.LoadInt64(&p.maxDial)),
+	}
+	p.counters.load(stats)
+	p.loadAges(stats)
+	return stats
+}
+
+// loadAges 统计空闲连接的空闲时长及存活时长分布
+func (p *ChannelPool) loadAges(stats *Stats) {
+	now := p.now()
+	p.tracked.each(func(_ interface{}, wrapConn *idleConn) {
+		if wrapConn.lent {
+			return
+		}
+		stats.IdleAges[ageBucket(now.Sub(wrapConn.t))]++
+		stats.ConnAges[ageBucket(now.Sub(wrapConn.created))]++
+	})
+}
+
+// ResetStats 将累计计数清零
+func (p *ChannelPool) ResetStats() {
+	p.counters.reset()
+	atomic.StoreInt64(&p.statsSince, p.now().UnixNano())
+	atomic.StoreInt64(&p.maxDial, 0)
+	atomic.StoreInt32(&p.maxBusy, atomic.LoadInt32(&p.numBusy))
+	atomic.StoreInt32(&p.maxWaiters, atomic.LoadInt32(&p.waiters))
+	p.resetIdleDepth()
 }
 
-func (p *channelPool) ShowStats() {
-	stats := p.Stats()
-	log.Printf("TotalConns: %d", stats.TotalConns)
-	log.Printf("Hits: %d	Misses: %d", stats.Hits, stats.Misses)
+// resetIdleDepth 将空闲连接数的高低水位重置为当前值，开始新的统计区间
+func (p *ChannelPool) resetIdleDepth() {
+	n := int32(p.Len())
+	atomic.StoreInt32(&p.idleLow, n)
+	atomic.StoreInt32(&p.idleHigh, n)
+}
+
+func (p *ChannelPool) ShowStats() {
+	p.logStats(p.Stats())
+}
+
+func (p *ChannelPool) logStats(stats *Stats) {
+	if p.slog != nil {
+		p.logAttrs(slog.LevelInfo, "stats", statsAttrs(stats)...)
+		return
+	}
+	p.logf("TotalConns: %d	IdleConns: %d	BusyConns: %d	DialingConns: %d", stats.TotalConns, stats.IdleConns, stats.BusyConns, stats.DialingConns)
+	p.logf("Hits: %d	Misses: %d	StaleHits: %d	StaleSkips: %d", stats.Hits, stats.Misses, stats.StaleHits, stats.StaleSkips)
+	p.logf("Overflows: %d	DialErrorCacheHits: %d	ShedWaiters: %d	RateLimited: %d	Recovered: %d", stats.Overflows, stats.DialErrorCacheHits, stats.ShedWaiters, stats.RateLimited, stats.Recovered)
+	p.logf("WaitCount: %d	WaitDuration: %v", stats.WaitCount, stats.WaitDuration)
+	p.logf("DialCount: %d	DialErrors: %d	DialDuration: %v	MaxDialDuration: %v", stats.DialCount, stats.DialErrors, stats.DialDuration, stats.MaxDialDuration)
+	for i, name := range dialBucketNames {
+		p.logf("DialDurations(<%s): %d", name, stats.DialDurations[i])
+	}
+	p.logf("Waiters: %d	MaxBusy: %d	MaxWaiters: %d", stats.Waiters, stats.MaxBusy, stats.MaxWaiters)
+	p.logf("IdleLow: %d	IdleHigh: %d", stats.IdleLow, stats.IdleHigh)
+	p.logf("StaleConns: %d	FullDiscards: %d", stats.StaleConns, stats.FullDiscards)
+	for reason, n := range stats.Closes {
+		p.logf("Closes(%v): %d", CloseReason(reason), n)
+	}
+	for i, name := range ageBucketNames {
+		p.logf("IdleAges(<%s): %d	ConnAges(<%s): %d", name, stats.IdleAges[i], name, stats.ConnAges[i])
+	}
+}
+
+// logf 输出日志，未配置Logger时使用标准库log
+func (p *ChannelPool) logf(format string, v ...interface{}) {
+	if p.logPrefix != "" {
+		format = p.logPrefix + format
+	}
+	if p.logger != nil {
+		p.logger.Printf(format, v...)
+		return
+	}
+	log.Printf(format, v...)
 }
@@ -0,0 +1,1843 @@
+package pool_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hms58/pool"
+	"github.com/hms58/pool/poolsim"
+)
+
+type testConn struct {
+	id     int
+	closed bool
+}
+
+func newTestPool(cfg *pool.PoolConfig) (*pool.ChannelPool, *[]*testConn) {
+	var dialed []*testConn
+	cfg.Factory = func() (interface{}, error) {
+		c := &testConn{id: len(dialed)}
+		dialed = append(dialed, c)
+		return c, nil
+	}
+	cfg.Close = func(v interface{}) error {
+		v.(*testConn).closed = true
+		return nil
+	}
+	return pool.New(cfg), &dialed
+}
+
+func TestEvictionPolicy(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy pool.EvictionPolicy
+		victim int
+	}{
+		{"default", nil, 2},
+		{"lifo", pool.EvictLIFO, 2},
+		{"oldest", pool.EvictOldest, 0},
+		{"lru", pool.EvictLRU, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, dialed := newTestPool(&pool.PoolConfig{MaxCap: 2, Eviction: tt.policy})
+			defer p.Release()
+
+			var conns []interface{}
+			for i := 0; i < 3; i++ {
+				cn, err := p.Get()
+				if err != nil {
+					t.Fatal(err)
+				}
+				conns = append(conns, cn)
+			}
+			// conn 1 最早放回，conn 0 最早创建
+			for _, i := range []int{1, 0, 2} {
+				if err := p.Put(conns[i]); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			for i, cn := range *dialed {
+				if cn.closed != (i == tt.victim) {
+					t.Errorf("conn %d closed=%v, want victim %d", i, cn.closed, tt.victim)
+				}
+			}
+			if p.Len() != 2 {
+				t.Errorf("Len() = %d, want 2", p.Len())
+			}
+		})
+	}
+}
+
+func TestEvictionSamplesQueueHead(t *testing.T) {
+	most := 0
+	policy := pool.EvictionFunc(func(conns []pool.ConnInfo) int {
+		if len(conns) > most {
+			most = len(conns)
+		}
+		return 0
+	})
+	p, dialed := newTestPool(&pool.PoolConfig{MaxCap: 20, MaxIdle: 16, Eviction: policy})
+	defer p.Release()
+
+	var conns []interface{}
+	for i := 0; i < 17; i++ {
+		cn, _ := p.Get()
+		conns = append(conns, cn)
+	}
+	for _, cn := range conns {
+		p.Put(cn)
+	}
+	// 只从队列头部取出候选，不清空空闲队列
+	if most == 0 || most > 9 {
+		t.Errorf("policy saw %d candidates, want at most 8 idle plus the returning one", most)
+	}
+	if !(*dialed)[0].closed || p.Len() != 16 {
+		t.Errorf("conn 0 closed=%v Len()=%d, want the queue head evicted and 16 idle", (*dialed)[0].closed, p.Len())
+	}
+}
+
+func TestMaxIdle(t *testing.T) {
+	p, dialed := newTestPool(&pool.PoolConfig{MaxCap: 10, MaxIdle: 2})
+	defer p.Release()
+
+	var conns []interface{}
+	for i := 0; i < 5; i++ {
+		cn, err := p.Get()
+		if err != nil {
+			t.Fatal(err)
+		}
+		conns = append(conns, cn)
+	}
+	for _, cn := range conns {
+		if err := p.Put(cn); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if p.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", p.Len())
+	}
+	closed := 0
+	for _, cn := range *dialed {
+		if cn.closed {
+			closed++
+		}
+	}
+	if closed != 3 {
+		t.Errorf("closed %d conns, want 3", closed)
+	}
+}
+
+func TestAsyncClose(t *testing.T) {
+	unblock := make(chan struct{})
+	var closed int32
+	p := pool.New(&pool.PoolConfig{
+		MaxCap:          1,
+		AsyncCloseQueue: 4,
+		Factory:         func() (interface{}, error) { return &testConn{}, nil },
+		Close: func(v interface{}) error {
+			<-unblock
+			atomic.AddInt32(&closed, 1)
+			return nil
+		},
+	})
+
+	a, _ := p.Get()
+	b, _ := p.Get()
+	p.Put(a)
+	// 连接池已满，b交由后台关闭，Put不应阻塞
+	if err := p.Put(b); err != nil {
+		t.Fatal(err)
+	}
+
+	close(unblock)
+	p.Release()
+	if n := atomic.LoadInt32(&closed); n != 2 {
+		t.Errorf("closed %d conns after Release, want 2", n)
+	}
+}
+
+func TestCloseTimeout(t *testing.T) {
+	hang := make(chan struct{})
+	defer close(hang)
+	p := pool.New(&pool.PoolConfig{
+		MaxCap:       1,
+		CloseTimeout: 10 * time.Millisecond,
+		Factory:      func() (interface{}, error) { return &testConn{}, nil },
+		Close: func(v interface{}) error {
+			<-hang
+			return nil
+		},
+	})
+
+	cn, _ := p.Get()
+	if err := p.Close(cn); err != pool.ErrCloseTimeout {
+		t.Errorf("Close() err = %v, want ErrCloseTimeout", err)
+	}
+}
+
+func TestRingPoolConcurrent(t *testing.T) {
+	var dialed int32
+	p := pool.NewRingPool(&pool.PoolConfig{
+		MaxCap: 8,
+		Factory: func() (interface{}, error) {
+			return &testConn{id: int(atomic.AddInt32(&dialed, 1))}, nil
+		},
+	})
+	defer p.Release()
+
+	var inUse sync.Map
+	var wg sync.WaitGroup
+	for g := 0; g < 16; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				cn, err := p.Get()
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if _, loaded := inUse.LoadOrStore(cn, true); loaded {
+					t.Error("conn handed out twice")
+					return
+				}
+				inUse.Delete(cn)
+				if err := p.Put(cn); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := p.Len(); n > 8 {
+		t.Errorf("Len() = %d, want <= 8", n)
+	}
+}
+
+func TestPutDetection(t *testing.T) {
+	p, _ := newTestPool(&pool.PoolConfig{MaxCap: 2})
+	defer p.Release()
+
+	cn, _ := p.Get()
+	if err := p.Put(cn); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Put(cn); err != pool.ErrDoublePut {
+		t.Errorf("second Put() err = %v, want ErrDoublePut", err)
+	}
+	if err := p.Close(cn); err != pool.ErrDoublePut {
+		t.Errorf("Close() after Put err = %v, want ErrDoublePut", err)
+	}
+	if err := p.Put(&testConn{}); err != pool.ErrUnknownConn {
+		t.Errorf("foreign Put() err = %v, want ErrUnknownConn", err)
+	}
+	if p.Len() != 1 {
+		t.Errorf("Len() = %d, want 1", p.Len())
+	}
+}
+
+func TestStatsReporter(t *testing.T) {
+	p, _ := newTestPool(&pool.PoolConfig{MaxCap: 2})
+
+	reports := make(chan *pool.Stats, 1)
+	p.StartStatsReporter(time.Millisecond, func(stats *pool.Stats) {
+		select {
+		case reports <- stats:
+		default:
+		}
+	})
+
+	select {
+	case <-reports:
+	case <-time.After(time.Second):
+		t.Fatal("no stats reported")
+	}
+	p.Release()
+}
+
+func TestWaitTimeout(t *testing.T) {
+	p, _ := newTestPool(&pool.PoolConfig{MaxCap: 1, Wait: true, WaitTimeout: 10 * time.Millisecond})
+	defer p.Release()
+
+	p.Get()
+	if _, err := p.Get(); !errors.Is(err, pool.ErrGetTimeout) {
+		t.Errorf("Get() err = %v, want ErrGetTimeout", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := p.GetContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("GetContext() err = %v, want context.Canceled", err)
+	}
+}
+
+func TestConnInfo(t *testing.T) {
+	p, _ := newTestPool(&pool.PoolConfig{
+		MaxCap: 2,
+		Origin: func(v interface{}) string { return fmt.Sprintf("conn-%d", v.(*testConn).id) },
+	})
+	defer p.Release()
+
+	a, _ := p.Get()
+	p.Put(a)
+	a, _ = p.Get()
+	b, _ := p.Get()
+	p.Put(b)
+
+	infos := p.ConnInfo()
+	if len(infos) != 2 {
+		t.Fatalf("len(ConnInfo()) = %d, want 2", len(infos))
+	}
+	for _, info := range infos {
+		switch info.Origin {
+		case "conn-0":
+			if !info.Busy || info.UseCount != 2 {
+				t.Errorf("conn-0: Busy=%v UseCount=%d, want true 2", info.Busy, info.UseCount)
+			}
+		case "conn-1":
+			if info.Busy || info.UseCount != 1 {
+				t.Errorf("conn-1: Busy=%v UseCount=%d, want false 1", info.Busy, info.UseCount)
+			}
+		default:
+			t.Errorf("unexpected origin %q", info.Origin)
+		}
+	}
+}
+
+func TestState(t *testing.T) {
+	var changes []string
+	p, dialed := newTestPool(&pool.PoolConfig{
+		MaxCap: 2,
+		OnStateChange: func(from, to pool.State) {
+			changes = append(changes, from.String()+"->"+to.String())
+		},
+	})
+
+	a, _ := p.Get()
+	b, _ := p.Get()
+	p.Put(a)
+
+	p.Drain()
+	if p.State() != pool.StateDraining || p.IsClosed() {
+		t.Fatalf("State() = %v after Drain, want draining", p.State())
+	}
+	if !(*dialed)[0].closed {
+		t.Error("idle conn not closed by Drain")
+	}
+	if _, err := p.Get(); !errors.Is(err, pool.ErrDraining) {
+		t.Errorf("Get() while draining err = %v, want ErrDraining", err)
+	}
+	if err := p.Put(b); err != pool.ErrDraining {
+		t.Errorf("Put() while draining err = %v, want ErrDraining", err)
+	}
+	if !(*dialed)[1].closed {
+		t.Error("conn put back while draining not closed")
+	}
+
+	p.Release()
+	if !p.IsClosed() {
+		t.Errorf("State() = %v after Release, want closed", p.State())
+	}
+	if _, err := p.Get(); !errors.Is(err, pool.ErrClosed) {
+		t.Errorf("Get() after Release err = %v, want ErrClosed", err)
+	}
+	if got := fmt.Sprint(changes); got != "[open->draining draining->closed]" {
+		t.Errorf("state changes = %s", got)
+	}
+}
+
+func TestWaitBusy(t *testing.T) {
+	p, _ := newTestPool(&pool.PoolConfig{MaxCap: 2})
+	defer p.Release()
+
+	if err := p.Wait(context.Background()); err != nil {
+		t.Fatalf("Wait() on idle pool err = %v", err)
+	}
+
+	a, _ := p.Get()
+	b, _ := p.Get()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := p.Wait(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Wait() with busy conns err = %v, want DeadlineExceeded", err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- p.Wait(context.Background()) }()
+	p.Put(a)
+	p.Close(b)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Wait() err = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Wait() did not return after all conns returned")
+	}
+}
+
+func TestTryGet(t *testing.T) {
+	p, dialed := newTestPool(&pool.PoolConfig{MaxCap: 2})
+	defer p.Release()
+
+	if _, ok := p.TryGet(); ok {
+		t.Fatal("TryGet() on empty pool returned a conn")
+	}
+	if len(*dialed) != 0 {
+		t.Fatalf("TryGet() dialed %d conns, want 0", len(*dialed))
+	}
+
+	cn, _ := p.Get()
+	p.Put(cn)
+	got, ok := p.TryGet()
+	if !ok || got != cn {
+		t.Errorf("TryGet() = %v, %v, want idle conn", got, ok)
+	}
+}
+
+func TestGetIdleDial(t *testing.T) {
+	p, dialed := newTestPool(&pool.PoolConfig{MaxCap: 1, Wait: true})
+	defer p.Release()
+
+	if _, err := p.GetIdle(); !errors.Is(err, pool.ErrNoIdleConn) {
+		t.Fatalf("GetIdle() on empty pool err = %v, want ErrNoIdleConn", err)
+	}
+	cn, err := p.Dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Dial(); err != pool.ErrPoolExhausted {
+		t.Errorf("Dial() over MaxCap err = %v, want ErrPoolExhausted", err)
+	}
+	p.Put(cn)
+	if got, err := p.GetIdle(); err != nil || got != cn {
+		t.Errorf("GetIdle() = %v, %v, want idle conn", got, err)
+	}
+	if len(*dialed) != 1 {
+		t.Errorf("dialed %d conns, want 1", len(*dialed))
+	}
+}
+
+func TestKeepAlive(t *testing.T) {
+	closed := make(chan pool.CloseReason, 2)
+	p, _ := newTestPool(&pool.PoolConfig{
+		MaxCap: 2,
+		KeepAlive: func(v interface{}) error {
+			if v.(*testConn).id == 0 {
+				return errors.New("probe failed")
+			}
+			return nil
+		},
+		KeepAliveInterval: 5 * time.Millisecond,
+		OnClose:           func(_ interface{}, reason pool.CloseReason) { closed <- reason },
+	})
+	defer p.Release()
+
+	a, _ := p.Get()
+	b, _ := p.Get()
+	p.Put(a)
+	p.Put(b)
+
+	select {
+	case reason := <-closed:
+		if reason != pool.CloseKeepAlive {
+			t.Errorf("close reason = %v, want keepalive failed", reason)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("conn failing keepalive was not closed")
+	}
+	if p.Len() != 1 {
+		t.Errorf("Len() = %d, want 1", p.Len())
+	}
+}
+
+func TestKeepAliveProbesOneAtATime(t *testing.T) {
+	probing := make(chan struct{}, 1)
+	release := make(chan struct{})
+	p, dialed := newTestPool(&pool.PoolConfig{
+		MaxCap: 2,
+		KeepAlive: func(interface{}) error {
+			select {
+			case probing <- struct{}{}:
+			default:
+			}
+			<-release
+			return nil
+		},
+		KeepAliveInterval: 5 * time.Millisecond,
+	})
+	defer p.Release()
+
+	a, _ := p.Get()
+	b, _ := p.Get()
+	p.Put(a)
+	p.Put(b)
+
+	select {
+	case <-probing:
+	case <-time.After(time.Second):
+		t.Fatal("keepalive probe not started")
+	}
+	// 探测期间另一条空闲连接仍可借出
+	cn, err := p.Get()
+	close(release)
+	if err != nil || len(*dialed) != 2 {
+		t.Fatalf("Get() during probe err = %v, dialed %d, want the other idle conn", err, len(*dialed))
+	}
+	p.Put(cn)
+}
+
+func TestOnBorrow(t *testing.T) {
+	var idle time.Duration
+	p, dialed := newTestPool(&pool.PoolConfig{
+		MaxCap: 2,
+		OnBorrow: func(v interface{}, idleFor time.Duration) error {
+			idle = idleFor
+			if idleFor > 5*time.Millisecond {
+				return errors.New("token expired")
+			}
+			return nil
+		},
+	})
+	defer p.Release()
+
+	cn, _ := p.Get()
+	p.Put(cn)
+	time.Sleep(10 * time.Millisecond)
+	cn, _ = p.Get()
+	if idle < 10*time.Millisecond {
+		t.Errorf("idleFor = %v, want >= 10ms", idle)
+	}
+	if cn.(*testConn).id != 1 || !(*dialed)[0].closed {
+		t.Error("conn rejected by OnBorrow was not replaced")
+	}
+}
+
+func TestGetN(t *testing.T) {
+	p, _ := newTestPool(&pool.PoolConfig{MaxCap: 3, Wait: true})
+	defer p.Release()
+
+	if _, err := p.GetN(context.Background(), 4); !errors.Is(err, pool.ErrPoolExhausted) {
+		t.Errorf("GetN(4) over limit err = %v, want ErrPoolExhausted", err)
+	}
+
+	held, _ := p.Get()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := p.GetN(ctx, 3); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("GetN(3) with one held err = %v, want DeadlineExceeded", err)
+	}
+	if p.Len() != 2 {
+		t.Errorf("Len() = %d after rollback, want 2", p.Len())
+	}
+
+	p.Put(held)
+	conns, err := p.GetN(context.Background(), 3)
+	if err != nil || len(conns) != 3 {
+		t.Errorf("GetN(3) = %d conns, %v", len(conns), err)
+	}
+}
+
+func TestPutAll(t *testing.T) {
+	p, dialed := newTestPool(&pool.PoolConfig{MaxCap: 3, MaxIdle: 2})
+	defer p.Release()
+
+	conns, err := p.GetN(context.Background(), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.PutAll(conns); err != nil {
+		t.Fatal(err)
+	}
+	if p.Len() != 2 || !(*dialed)[2].closed {
+		t.Errorf("Len() = %d, want 2 with the extra conn closed", p.Len())
+	}
+	if err := p.PutAll(conns[:1]); !errors.Is(err, pool.ErrDoublePut) {
+		t.Errorf("PutAll() of returned conn err = %v, want ErrDoublePut", err)
+	}
+	if busy := p.BusyLen(); busy != 0 {
+		t.Errorf("BusyLen() = %d, want 0", busy)
+	}
+}
+
+func TestGetFor(t *testing.T) {
+	p, _ := newTestPool(&pool.PoolConfig{
+		MaxCap:      4,
+		OwnerQuota:  1,
+		OwnerQuotas: map[string]int{"batch": 2},
+	})
+	defer p.Release()
+
+	a, err := p.GetFor(context.Background(), "api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.GetFor(context.Background(), "api"); !errors.Is(err, pool.ErrOwnerQuota) {
+		t.Errorf("second GetFor(api) err = %v, want ErrOwnerQuota", err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := p.GetFor(context.Background(), "batch"); err != nil {
+			t.Errorf("GetFor(batch) #%d err = %v", i, err)
+		}
+	}
+
+	p.Put(a)
+	if _, err := p.GetFor(context.Background(), "api"); err != nil {
+		t.Errorf("GetFor(api) after Put err = %v", err)
+	}
+
+	stats := p.OwnerStats()
+	if api := stats["api"]; api.Busy != 1 || api.Gets != 2 || api.Rejected != 1 {
+		t.Errorf("OwnerStats()[api] = %+v, want Busy 1 Gets 2 Rejected 1", api)
+	}
+	if batch := stats["batch"]; batch.MaxBusy != 2 {
+		t.Errorf("OwnerStats()[batch].MaxBusy = %d, want 2", batch.MaxBusy)
+	}
+}
+
+func TestGetQoS(t *testing.T) {
+	p, _ := newTestPool(&pool.PoolConfig{
+		MaxCap:      5,
+		Wait:        true,
+		WaitTimeout: 10 * time.Millisecond,
+		QoSReserved: map[string]int{"critical": 1},
+	})
+	defer p.Release()
+
+	var bulk []interface{}
+	for i := 0; i < 4; i++ {
+		cn, err := p.Get()
+		if err != nil {
+			t.Fatal(err)
+		}
+		bulk = append(bulk, cn)
+	}
+	if _, err := p.Get(); !errors.Is(err, pool.ErrGetTimeout) {
+		t.Errorf("bulk Get() beyond its share err = %v, want ErrGetTimeout", err)
+	}
+	cn, err := p.GetQoS(context.Background(), "critical")
+	if err != nil {
+		t.Fatalf("GetQoS(critical) err = %v", err)
+	}
+	p.Put(cn)
+	p.PutAll(bulk)
+}
+
+func TestGetAffinity(t *testing.T) {
+	p, _ := newTestPool(&pool.PoolConfig{MaxCap: 3})
+	defer p.Release()
+
+	a, _ := p.GetAffinity(context.Background(), "session-a")
+	b, _ := p.Get()
+	c, _ := p.Get()
+	p.PutAll([]interface{}{a, b, c})
+
+	for i := 0; i < 3; i++ {
+		got, err := p.GetAffinity(context.Background(), "session-a")
+		if err != nil || got != a {
+			t.Fatalf("GetAffinity() = %v, %v, want the conn used before", got, err)
+		}
+		p.Put(got)
+	}
+	if p.Len() != 3 {
+		t.Errorf("Len() = %d, want 3", p.Len())
+	}
+
+	cn, _ := p.GetAffinity(context.Background(), "session-a")
+	p.Close(cn)
+	if got, _ := p.GetAffinity(context.Background(), "session-a"); got == a {
+		t.Error("GetAffinity() returned a closed conn")
+	}
+}
+
+func TestGetAffinityForgetsClosedConn(t *testing.T) {
+	p := pool.New(&pool.PoolConfig{
+		MaxCap:  1,
+		Factory: func() (interface{}, error) { return new(testConn), nil },
+	})
+	defer p.Release()
+
+	collected := make(chan struct{})
+	cn, _ := p.GetAffinity(context.Background(), "session-a")
+	p.Close(cn)
+	runtime.SetFinalizer(cn, func(*testConn) { close(collected) })
+	cn = nil
+	// 关闭的连接不应被key到连接的映射持有
+	for i := 0; i < 50; i++ {
+		runtime.GC()
+		select {
+		case <-collected:
+			return
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	t.Error("closed conn still referenced after Close")
+}
+
+func TestChildren(t *testing.T) {
+	var closed []string
+	p, _ := newTestPool(&pool.PoolConfig{
+		MaxCap: 1,
+		ChildFactory: func(parent interface{}, key string) (interface{}, error) {
+			return fmt.Sprintf("%d:%s", parent.(*testConn).id, key), nil
+		},
+		ChildClose: func(v interface{}) error {
+			closed = append(closed, v.(string))
+			return nil
+		},
+	})
+	defer p.Release()
+
+	cn, _ := p.Get()
+	children, err := p.Children(cn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stmt, _ := children.Get("select 1")
+	children.Put("select 1", stmt)
+	if again, _ := children.Get("select 1"); again != stmt {
+		t.Errorf("Get() = %v, want reused %v", again, stmt)
+	}
+	children.Put("select 1", stmt)
+	lent, _ := children.Get("select 2")
+
+	p.Close(cn)
+	if len(closed) != 1 || closed[0] != "0:select 1" {
+		t.Errorf("closed children = %v, want idle child closed with parent", closed)
+	}
+	children.Put("select 2", lent)
+	if len(closed) != 2 {
+		t.Errorf("child put back after parent closed was not closed")
+	}
+}
+
+func TestReserve(t *testing.T) {
+	p, dialed := newTestPool(&pool.PoolConfig{MaxCap: 1, Wait: true, WaitTimeout: 10 * time.Millisecond})
+	defer p.Release()
+
+	r, err := p.Reserve(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Get(); !errors.Is(err, pool.ErrGetTimeout) {
+		t.Errorf("Get() with capacity reserved err = %v, want ErrGetTimeout", err)
+	}
+	r.Cancel()
+	if len(*dialed) != 0 {
+		t.Errorf("dialed %d conns, want 0 after Cancel", len(*dialed))
+	}
+
+	r, _ = p.Reserve(context.Background())
+	cn, err := r.Dial()
+	if err != nil || cn == nil {
+		t.Fatalf("Dial() = %v, %v", cn, err)
+	}
+	if _, err := r.Dial(); err != pool.ErrReservationUsed {
+		t.Errorf("second Dial() err = %v, want ErrReservationUsed", err)
+	}
+	r.Cancel()
+	p.Put(cn)
+	if p.Len() != 1 {
+		t.Errorf("Len() = %d, want 1", p.Len())
+	}
+}
+
+func TestSaturated(t *testing.T) {
+	var reported int32 = -1
+	p, _ := newTestPool(&pool.PoolConfig{
+		MaxCap:      1,
+		Wait:        true,
+		OnExhausted: func(waiters int) { atomic.StoreInt32(&reported, int32(waiters)) },
+	})
+	defer p.Release()
+
+	cn, _ := p.Get()
+	if p.Saturated() {
+		t.Error("Saturated() = true without waiters")
+	}
+
+	got := make(chan interface{})
+	go func() {
+		c, _ := p.Get()
+		got <- c
+	}()
+	for !p.Saturated() {
+		time.Sleep(time.Millisecond)
+	}
+	if w := atomic.LoadInt32(&reported); w != 1 {
+		t.Errorf("OnExhausted waiters = %d, want 1", w)
+	}
+	p.Put(cn)
+	p.Put(<-got)
+	if p.Saturated() {
+		t.Error("Saturated() = true after waiter served")
+	}
+}
+
+func TestInitialCap(t *testing.T) {
+	p, dialed := newTestPool(&pool.PoolConfig{MaxCap: 5, InitialCap: 3})
+	defer p.Release()
+	if p.Len() != 3 || len(*dialed) != 3 {
+		t.Fatalf("Len() = %d, dialed %d, want 3 prefilled", p.Len(), len(*dialed))
+	}
+	if misses := p.Stats().Misses; misses != 0 {
+		t.Errorf("Misses = %d after prefill, want 0", misses)
+	}
+
+	var n int32
+	slow := pool.New(&pool.PoolConfig{
+		MaxCap:     5,
+		InitialCap: 3,
+		WarmupRate: 50,
+		Factory: func() (interface{}, error) {
+			return &testConn{id: int(atomic.AddInt32(&n, 1))}, nil
+		},
+	})
+	defer slow.Release()
+	if got := atomic.LoadInt32(&n); got > 1 {
+		t.Errorf("dialed %d conns immediately with WarmupRate, want <= 1", got)
+	}
+	deadline := time.Now().Add(time.Second)
+	for slow.Len() < 3 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if slow.Len() != 3 {
+		t.Errorf("Len() = %d after warmup, want 3", slow.Len())
+	}
+}
+
+func TestExpiryJitter(t *testing.T) {
+	p, dialed := newTestPool(&pool.PoolConfig{
+		MaxCap:       20,
+		MaxLifetime:  100 * time.Millisecond,
+		ExpiryJitter: 0.5,
+	})
+	defer p.Release()
+
+	conns, _ := p.GetN(context.Background(), 20)
+	p.PutAll(conns)
+	time.Sleep(100 * time.Millisecond)
+	for i := 0; i < 20; i++ {
+		cn, _ := p.Get()
+		defer p.Put(cn)
+	}
+	// ±50%抖动下，到达MaxLifetime时应有部分连接已过期、部分仍存活
+	expired := len(*dialed) - 20
+	if expired == 0 || expired == 20 {
+		t.Errorf("%d of 20 conns expired at MaxLifetime, want a jittered mix", expired)
+	}
+}
+
+func TestReleaseErr(t *testing.T) {
+	var active, peak int32
+	p := pool.New(&pool.PoolConfig{
+		MaxCap:             8,
+		ReleaseConcurrency: 4,
+		Factory:            func() (interface{}, error) { return &testConn{}, nil },
+		Close: func(v interface{}) error {
+			n := atomic.AddInt32(&active, 1)
+			for {
+				old := atomic.LoadInt32(&peak)
+				if n <= old || atomic.CompareAndSwapInt32(&peak, old, n) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			atomic.AddInt32(&active, -1)
+			return errors.New("close failed")
+		},
+	})
+
+	conns, _ := p.GetN(context.Background(), 8)
+	p.PutAll(conns)
+	err := p.ReleaseErr()
+	if err == nil || len(err.(interface{ Unwrap() []error }).Unwrap()) != 8 {
+		t.Errorf("ReleaseErr() = %v, want 8 joined errors", err)
+	}
+	if peak < 2 || peak > 4 {
+		t.Errorf("peak concurrent closes = %d, want 2..4", peak)
+	}
+	if err := p.ReleaseErr(); err != nil {
+		t.Errorf("second ReleaseErr() = %v, want nil", err)
+	}
+}
+
+func TestReleaseWakesWaiters(t *testing.T) {
+	p, _ := newTestPool(&pool.PoolConfig{MaxCap: 1, Wait: true})
+	p.Get()
+
+	errs := make(chan error, 3)
+	go func() {
+		_, err := p.Get()
+		errs <- err
+	}()
+	go func() {
+		_, err := p.Reserve(context.Background())
+		errs <- err
+	}()
+	go func() { errs <- p.Wait(context.Background()) }()
+	for p.Stats().Waiters != 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	p.Release()
+	for i := 0; i < 3; i++ {
+		select {
+		case err := <-errs:
+			if !errors.Is(err, pool.ErrClosed) {
+				t.Errorf("blocked call err = %v, want ErrClosed", err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("blocked call not woken by Release")
+		}
+	}
+}
+
+func TestReleaseErrAsync(t *testing.T) {
+	release := make(chan struct{})
+	p := pool.New(&pool.PoolConfig{
+		MaxCap:          2,
+		MaxIdle:         1,
+		AsyncCloseQueue: 4,
+		Factory:         func() (interface{}, error) { return &testConn{}, nil },
+		Close: func(interface{}) error {
+			<-release
+			return errors.New("close failed")
+		},
+	})
+
+	a, _ := p.Get()
+	b, _ := p.Get()
+	p.Put(a)
+	p.Put(b) // queued for async close, blocked until release
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		close(release)
+	}()
+
+	err := p.ReleaseErr()
+	if err == nil || len(err.(interface{ Unwrap() []error }).Unwrap()) != 2 {
+		t.Errorf("ReleaseErr() = %v, want idle and queued close errors", err)
+	}
+	p.Release()
+}
+
+func TestMaxConcurrentDials(t *testing.T) {
+	var dialing, maxDialing, dials int32
+	p := pool.New(&pool.PoolConfig{
+		MaxCap:             50,
+		MaxConcurrentDials: 2,
+		Factory: func() (interface{}, error) {
+			n := atomic.AddInt32(&dialing, 1)
+			for {
+				m := atomic.LoadInt32(&maxDialing)
+				if n <= m || atomic.CompareAndSwapInt32(&maxDialing, m, n) {
+					break
+				}
+			}
+			time.Sleep(20 * time.Millisecond)
+			atomic.AddInt32(&dialing, -1)
+			atomic.AddInt32(&dials, 1)
+			return new(int), nil
+		},
+	})
+	defer p.Release()
+
+	// 冷启动时大量调用者同时到达
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			v, err := p.GetContext(ctx)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			time.Sleep(time.Millisecond)
+			p.Put(v)
+		}()
+	}
+	wg.Wait()
+
+	if maxDialing > 2 {
+		t.Errorf("%d concurrent dials, want at most 2", maxDialing)
+	}
+	if dials >= 100 {
+		t.Errorf("%d dials for 100 callers, want queued callers to reuse conns", dials)
+	}
+}
+
+func TestMaxConcurrentDialsTimeout(t *testing.T) {
+	block := make(chan struct{})
+	p := pool.New(&pool.PoolConfig{
+		MaxCap:             2,
+		MaxConcurrentDials: 1,
+		WaitTimeout:        20 * time.Millisecond,
+		Factory: func() (interface{}, error) {
+			<-block
+			return new(int), nil
+		},
+	})
+	defer p.Release()
+
+	go p.Get()
+	time.Sleep(10 * time.Millisecond)
+	if _, err := p.Get(); !errors.Is(err, pool.ErrGetTimeout) {
+		t.Errorf("Get() while dial slot busy err = %v, want ErrGetTimeout", err)
+	}
+	close(block)
+}
+
+func TestStatsAges(t *testing.T) {
+	p := pool.New(&pool.PoolConfig{
+		MaxCap:  3,
+		Factory: func() (interface{}, error) { return new(int), nil },
+	})
+	defer p.Release()
+
+	conns, _ := p.GetN(context.Background(), 3)
+	p.Put(conns[0])
+	p.Put(conns[1])
+
+	stats := p.Stats()
+	if stats.IdleAges[0] != 2 || stats.ConnAges[0] != 2 {
+		t.Errorf("IdleAges = %v, ConnAges = %v, want 2 idle conns in first bucket", stats.IdleAges, stats.ConnAges)
+	}
+}
+
+func TestDumpState(t *testing.T) {
+	p := pool.New(&pool.PoolConfig{
+		MaxCap:       2,
+		DialErrorTTL: time.Minute,
+		Factory:      func() (interface{}, error) { return new(int), nil },
+	})
+	defer p.Release()
+
+	a, _ := p.GetFor(context.Background(), "worker")
+	b, _ := p.Get()
+	p.Put(b)
+
+	state := p.DumpState()
+	if state.State != "open" || state.Open != 2 || state.Config.MaxCap != 2 {
+		t.Errorf("State = %q, Open = %d, MaxCap = %d", state.State, state.Open, state.Config.MaxCap)
+	}
+	if len(state.Idle) != 1 || len(state.Busy) != 1 || state.Busy[0].Owner != "worker" {
+		t.Fatalf("Idle = %v, Busy = %v", state.Idle, state.Busy)
+	}
+	if state.Busy[0].CheckedOutFor < 0 || state.Busy[0].LentAt.IsZero() {
+		t.Errorf("Busy[0] = %+v, want checkout time recorded", state.Busy[0])
+	}
+	if state.Circuit.Open {
+		t.Error("Circuit.Open = true without dial errors")
+	}
+	if _, err := json.Marshal(state); err != nil {
+		t.Errorf("json.Marshal(DumpState()) err = %v", err)
+	}
+	p.Put(a)
+}
+
+func TestSlog(t *testing.T) {
+	var buf syncBuffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	fail := false
+	p := pool.New(&pool.PoolConfig{
+		MaxCap: 1,
+		Name:   "db",
+		Slog:   logger,
+		Factory: func() (interface{}, error) {
+			if fail {
+				return nil, errors.New("refused")
+			}
+			return new(int), nil
+		},
+		Ping: func(interface{}) error { return errors.New("stale") },
+	})
+	defer p.Release()
+
+	v, _ := p.Get()
+	p.Put(v)
+	fail = true
+	p.Get()
+	p.ShowStats()
+
+	out := buf.String()
+	for _, want := range []string{
+		`"msg":"connection closed","pool":"db","conn_id":1`,
+		`"reason":"validation failed"`,
+		`"msg":"dial failed","pool":"db","error":"refused"`,
+		`"msg":"stats","pool":"db","total_conns":0`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("log output missing %s:\n%s", want, out)
+		}
+	}
+}
+
+// syncBuffer 可并发写入的bytes.Buffer
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestHealthy(t *testing.T) {
+	fail := false
+	p := pool.New(&pool.PoolConfig{
+		MaxCap: 1,
+		Wait:   true,
+		Health: pool.HealthThresholds{MaxDialErrorRate: 0.5, MaxSaturation: 20 * time.Millisecond},
+		Factory: func() (interface{}, error) {
+			if fail {
+				return nil, errors.New("refused")
+			}
+			return new(int), nil
+		},
+	})
+
+	if ok, reasons := p.Healthy(); !ok {
+		t.Fatalf("new pool unhealthy: %v", reasons)
+	}
+
+	fail = true
+	p.Dial()
+	if ok, reasons := p.Healthy(); ok || !strings.Contains(reasons[0], "dial error rate") {
+		t.Errorf("Healthy() after failed dial = %v, %v", ok, reasons)
+	}
+	fail = false
+
+	v, _ := p.Get()
+	if _, err := p.Dial(); err != pool.ErrPoolExhausted {
+		t.Fatalf("Dial() err = %v, want ErrPoolExhausted", err)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if ok, reasons := p.Healthy(); ok || !strings.Contains(reasons[0], "saturated") {
+		t.Errorf("Healthy() while saturated = %v, %v", ok, reasons)
+	}
+	p.Put(v)
+	if ok, reasons := p.Healthy(); !ok {
+		t.Errorf("Healthy() after Put = %v, %v", ok, reasons)
+	}
+
+	p.Release()
+	if ok, reasons := p.Healthy(); ok || reasons[0] != "pool is closed" {
+		t.Errorf("Healthy() after Release = %v, %v", ok, reasons)
+	}
+}
+
+func TestInterceptors(t *testing.T) {
+	skipWithoutInterceptors(t)
+	p := pool.New(&pool.PoolConfig{
+		MaxCap:  2,
+		Factory: func() (interface{}, error) { return new(int), nil },
+	})
+	defer p.Release()
+
+	var calls []string
+	trace := func(name string) pool.InterceptorFunc {
+		return func(ctx context.Context, op pool.Op, conn interface{}, next pool.Invoker) (interface{}, error) {
+			calls = append(calls, name+">"+op.String())
+			v, err := next(ctx, conn)
+			calls = append(calls, name+"<"+op.String())
+			return v, err
+		}
+	}
+	p.Use(trace("a"))
+	p.Use(trace("b"))
+
+	v, _ := p.Get()
+	p.Put(v)
+	want := "a>get b>get a>dial b>dial b<dial a<dial b<get a<get a>put b>put b<put a<put"
+	if got := strings.Join(calls, " "); got != want {
+		t.Errorf("calls = %s\nwant    %s", got, want)
+	}
+
+	// 拦截器可直接拒绝操作
+	errDenied := errors.New("denied")
+	p.Use(func(ctx context.Context, op pool.Op, conn interface{}, next pool.Invoker) (interface{}, error) {
+		if op == pool.OpGet {
+			return nil, errDenied
+		}
+		return next(ctx, conn)
+	})
+	if _, err := p.Get(); !errors.Is(err, errDenied) {
+		t.Errorf("Get() err = %v, want errDenied", err)
+	}
+}
+
+func TestDialOnEmptyFalse(t *testing.T) {
+	dials := 0
+	p := pool.New(&pool.PoolConfig{
+		MaxCap:      2,
+		InitialCap:  1,
+		DialOnEmpty: new(bool),
+		Factory: func() (interface{}, error) {
+			dials++
+			return new(int), nil
+		},
+	})
+	defer p.Release()
+
+	v, err := p.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Get(); !errors.Is(err, pool.ErrNoIdleConn) {
+		t.Errorf("Get() on empty pool err = %v, want ErrNoIdleConn", err)
+	}
+	if dials != 1 {
+		t.Errorf("%d dials, want only the warm-up dial", dials)
+	}
+
+	// 显式Dial仍可新建连接
+	if _, err := p.Dial(); err != nil {
+		t.Errorf("Dial() err = %v", err)
+	}
+	p.Put(v)
+}
+
+func TestDialOnEmptyFalseWait(t *testing.T) {
+	p := pool.New(&pool.PoolConfig{
+		MaxCap:      2,
+		InitialCap:  1,
+		DialOnEmpty: new(bool),
+		Wait:        true,
+		Factory:     func() (interface{}, error) { return new(int), nil },
+	})
+	defer p.Release()
+
+	v, _ := p.Get()
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		p.Put(v)
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	got, err := p.GetContext(ctx)
+	if err != nil || got != v {
+		t.Errorf("GetContext() = %v, %v, want the returned conn", got, err)
+	}
+}
+
+func TestPrepare(t *testing.T) {
+	release := make(chan struct{})
+	p := pool.New(&pool.PoolConfig{
+		MaxCap:     1,
+		InitialCap: 1,
+		Wait:       true,
+		Factory:    func() (interface{}, error) { return new(int), nil },
+		Prepare: func(v interface{}) error {
+			<-release
+			*v.(*int) = 1
+			return nil
+		},
+	})
+	defer p.Release()
+
+	// 预建的连接准备完成前不可取出
+	if _, err := p.GetIdle(); !errors.Is(err, pool.ErrNoIdleConn) {
+		t.Fatalf("GetIdle() before Prepare err = %v, want ErrNoIdleConn", err)
+	}
+	close(release)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	v, err := p.GetContext(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *v.(*int) != 1 {
+		t.Error("got a conn that was not prepared")
+	}
+	p.Put(v)
+}
+
+func TestGetErrorKind(t *testing.T) {
+	errDial := errors.New("connection refused")
+	p := pool.New(&pool.PoolConfig{
+		MaxCap:       1,
+		Wait:         true,
+		WaitTimeout:  10 * time.Millisecond,
+		DialErrorTTL: time.Minute,
+		Factory:      func() (interface{}, error) { return nil, errDial },
+	})
+
+	tests := []struct {
+		name string
+		want pool.ErrorKind
+	}{
+		{"dial", pool.KindDialFailed},
+		{"cached dial error", pool.KindCircuitOpen},
+	}
+	for _, tt := range tests {
+		_, err := p.Get()
+		var ge *pool.GetError
+		if !errors.As(err, &ge) || ge.Kind != tt.want || !errors.Is(err, errDial) {
+			t.Errorf("%s: Get() err = %#v, want %v wrapping %v", tt.name, err, tt.want, errDial)
+		}
+	}
+
+	p.Release()
+	_, err := p.Get()
+	var ge *pool.GetError
+	if !errors.As(err, &ge) || ge.Kind != pool.KindClosed {
+		t.Errorf("Get() after Release err = %v, want KindClosed", err)
+	}
+
+	q, _ := newTestPool(&pool.PoolConfig{MaxCap: 1, Wait: true, WaitTimeout: 10 * time.Millisecond})
+	defer q.Release()
+	v, _ := q.Get()
+	defer q.Put(v)
+	if _, err := q.Get(); !errors.As(err, &ge) || ge.Kind != pool.KindTimeout || err.Error() != pool.ErrGetTimeout.Error() {
+		t.Errorf("Get() on a full pool err = %v, want KindTimeout", err)
+	}
+}
+
+func TestDialErrorKind(t *testing.T) {
+	errDial := errors.New("connection refused")
+	p := pool.New(&pool.PoolConfig{
+		MaxCap:  1,
+		Wait:    true,
+		Factory: func() (interface{}, error) { return nil, errDial },
+	})
+	defer p.Release()
+	var ge *pool.GetError
+	if _, err := p.Dial(); !errors.As(err, &ge) || ge.Kind != pool.KindDialFailed || !errors.Is(err, errDial) {
+		t.Errorf("Dial() err = %#v, want KindDialFailed wrapping %v", err, errDial)
+	}
+	r, err := p.Reserve(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Dial(); !errors.As(err, &ge) || ge.Kind != pool.KindDialFailed {
+		t.Errorf("Reservation.Dial() err = %#v, want KindDialFailed", err)
+	}
+}
+
+func TestStatsConnCountsUnderLoad(t *testing.T) {
+	const maxCap = 4
+	p := pool.New(&pool.PoolConfig{
+		MaxCap:  maxCap,
+		Wait:    true,
+		Factory: func() (interface{}, error) { return new(int), nil },
+	})
+	defer p.Release()
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				if v, err := p.Get(); err == nil {
+					p.Put(v)
+				}
+			}
+		}()
+	}
+
+	deadline := time.Now().Add(50 * time.Millisecond)
+	for time.Now().Before(deadline) {
+		s := p.Stats()
+		if s.IdleConns+s.BusyConns+s.DialingConns > s.TotalConns || s.TotalConns > maxCap {
+			t.Fatalf("inconsistent counts: total %d, idle %d, busy %d, dialing %d", s.TotalConns, s.IdleConns, s.BusyConns, s.DialingConns)
+		}
+	}
+	close(stop)
+	wg.Wait()
+
+	if s := p.Stats(); s.BusyConns != 0 || s.IdleConns != uint32(p.Len()) {
+		t.Errorf("after load: total %d, idle %d, busy %d, Len() %d", s.TotalConns, s.IdleConns, s.BusyConns, p.Len())
+	}
+}
+
+func TestStickyStash(t *testing.T) {
+	p, dialed := newTestPool(&pool.PoolConfig{MaxCap: 2, StickyStash: true})
+
+	v, _ := p.Get()
+	p.Put(v)
+	if p.Len() != 1 {
+		t.Errorf("Len() with a stashed conn = %d, want 1", p.Len())
+	}
+	for i := 0; i < 10; i++ {
+		got, err := p.Get()
+		if err != nil {
+			t.Fatal(err)
+		}
+		p.Put(got)
+	}
+	if len(*dialed) > 2 {
+		t.Errorf("%d dials, want stashed conns to be reused", len(*dialed))
+	}
+
+	p.Release()
+	for _, c := range *dialed {
+		if !c.closed {
+			t.Error("stashed conn not closed on Release")
+		}
+	}
+}
+
+func TestStickyStashMaxIdle(t *testing.T) {
+	p, dialed := newTestPool(&pool.PoolConfig{MaxCap: 4, MaxIdle: 1, StickyStash: true})
+	defer p.Release()
+
+	a, _ := p.Get()
+	b, _ := p.Get()
+	p.Put(a)
+	p.Put(b)
+	// 暂存的连接计入MaxIdle
+	if p.Len() != 1 {
+		t.Errorf("Len() = %d, want MaxIdle 1", p.Len())
+	}
+	closed := 0
+	for _, c := range *dialed {
+		if c.closed {
+			closed++
+		}
+	}
+	if closed != 1 {
+		t.Errorf("%d conns closed, want the one over MaxIdle", closed)
+	}
+}
+
+func TestStickyStashEvictWhere(t *testing.T) {
+	p, dialed := newTestPool(&pool.PoolConfig{MaxCap: 4, StickyStash: true})
+	defer p.Release()
+
+	v, _ := p.Get()
+	p.Put(v)
+	all := func(interface{}, pool.ConnInfo) bool { return true }
+	if n := p.EvictWhere(all); n != 1 {
+		t.Errorf("EvictWhere() = %d, want the stashed conn", n)
+	}
+	if !(*dialed)[0].closed {
+		t.Error("stashed conn not closed by EvictWhere")
+	}
+
+	v, _ = p.Get()
+	p.Put(v)
+	if n := p.ForceCloseAll(); n != 1 {
+		t.Errorf("ForceCloseAll() = %d, want the stashed conn", n)
+	}
+	if !(*dialed)[1].closed {
+		t.Error("stashed conn not closed by ForceCloseAll")
+	}
+	if p.Len() != 0 {
+		t.Errorf("Len() = %d, want 0", p.Len())
+	}
+}
+
+func TestStickyStashWaiter(t *testing.T) {
+	p := pool.New(&pool.PoolConfig{
+		MaxCap:      1,
+		Wait:        true,
+		WaitTimeout: time.Second,
+		StickyStash: true,
+		Factory:     func() (interface{}, error) { return new(int), nil },
+	})
+	defer p.Release()
+
+	// 放回的连接可能暂存在其他P，等待者仍应取到
+	for i := 0; i < 100; i++ {
+		v, err := p.Get()
+		if err != nil {
+			t.Fatal(err)
+		}
+		done := make(chan error)
+		go func() {
+			v, err := p.Get()
+			if err == nil {
+				p.Put(v)
+			}
+			done <- err
+		}()
+		time.Sleep(100 * time.Microsecond)
+		p.Put(v)
+		if err := <-done; err != nil {
+			t.Fatalf("waiter Get() err = %v", err)
+		}
+	}
+}
+
+func TestCheckInvariants(t *testing.T) {
+	var violations []error
+	p, _ := newTestPool(&pool.PoolConfig{
+		MaxCap:               2,
+		Wait:                 true,
+		CheckInvariants:      true,
+		OnInvariantViolation: func(err error) { violations = append(violations, err) },
+	})
+
+	a, _ := p.Get()
+	b, _ := p.Get()
+	p.Put(a)
+	p.Close(b)
+	a, _ = p.Get()
+	p.Put(a)
+	p.Release()
+	if _, err := p.Get(); err == nil {
+		t.Error("Get() after Release succeeded")
+	}
+	if len(violations) != 0 {
+		t.Errorf("violations on a correct pool: %v", violations)
+	}
+}
+
+func TestCheckInvariantsConcurrent(t *testing.T) {
+	p := pool.New(&pool.PoolConfig{
+		MaxCap:               3,
+		Wait:                 true,
+		CheckInvariants:      true,
+		OnInvariantViolation: func(err error) { t.Error(err) },
+		Factory:              func() (interface{}, error) { return new(int), nil },
+	})
+	defer p.Release()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				v, err := p.Get()
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if (i+j)%7 == 0 {
+					p.Close(v)
+				} else {
+					p.Put(v)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestGetRateLimitWait(t *testing.T) {
+	p := pool.New(&pool.PoolConfig{
+		MaxCap:       1,
+		GetRateLimit: 50,
+		GetRateBurst: 1,
+		Factory:      func() (interface{}, error) { return new(int), nil },
+	})
+	defer p.Release()
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		c, err := p.Get()
+		if err != nil {
+			t.Fatal(err)
+		}
+		p.Put(c)
+	}
+	if took := time.Since(start); took < 30*time.Millisecond {
+		t.Errorf("3 Gets at 50/s with burst 1 took %v, want about 40ms", took)
+	}
+}
+
+func TestGetRateLimitIdleHits(t *testing.T) {
+	sim := poolsim.New(time.Now())
+	p := pool.New(sim.Config(&pool.PoolConfig{
+		MaxCap:       1,
+		GetRateLimit: 0.01,
+		GetRateBurst: 1,
+	}))
+	defer p.Release()
+	ctx := context.Background()
+
+	c, _ := p.GetAffinity(ctx, "a")
+	p.Put(c)
+	gets := map[string]func() (interface{}, error){
+		"GetAffinity": func() (interface{}, error) { return p.GetAffinity(ctx, "a") },
+		"GetMatching": func() (interface{}, error) { return p.GetMatching(ctx, pool.Selector{}) },
+	}
+	for name, get := range gets {
+		// 空闲连接充足时同样需要令牌，令牌按连接池的时钟补充
+		got := make(chan interface{})
+		go func() {
+			c, _ := get()
+			got <- c
+		}()
+		select {
+		case <-got:
+			t.Fatalf("%s() hit an idle conn without a token", name)
+		case <-time.After(20 * time.Millisecond):
+		}
+		sim.Clock.Advance(100 * time.Second)
+		select {
+		case c := <-got:
+			p.Put(c)
+		case <-time.After(time.Second):
+			t.Fatalf("%s() still waiting after the clock advanced", name)
+		}
+	}
+}
+
+func TestGetMatching(t *testing.T) {
+	var dialed int
+	p := pool.New(&pool.PoolConfig{
+		MaxCap: 4,
+		LabeledFactory: func() (interface{}, map[string]string, error) {
+			dialed++
+			return &testConn{id: dialed}, map[string]string{"tls": fmt.Sprint(dialed%2 == 1)}, nil
+		},
+	})
+	defer p.Release()
+	ctx := context.Background()
+	tls, _ := pool.ParseSelector("tls=true")
+	plain, _ := pool.ParseSelector("tls=false")
+
+	c1, err := p.GetMatching(ctx, tls)
+	if err != nil || c1.(*testConn).id != 1 {
+		t.Fatalf("GetMatching(tls=true) = %v, %v", c1, err)
+	}
+	p.Put(c1)
+	c2, err := p.GetMatching(ctx, plain)
+	if err != nil || c2.(*testConn).id != 2 {
+		t.Fatalf("GetMatching(tls=false) with only a tls conn idle = %v, %v, want new conn", c2, err)
+	}
+	p.Put(c2)
+	if c, _ := p.GetMatching(ctx, tls); c != c1 {
+		t.Errorf("GetMatching(tls=true) = %v, want idle conn %v", c, c1)
+	} else {
+		p.Put(c)
+	}
+
+	_, err = p.GetMatching(ctx, pool.Selector{"db": "analytics"})
+	var ge *pool.GetError
+	if !errors.Is(err, pool.ErrNoMatchingConn) || !errors.As(err, &ge) || ge.Kind != pool.KindExhausted {
+		t.Errorf("GetMatching(db=analytics) err = %v, want ErrNoMatchingConn", err)
+	}
+	if p.Len() != 3 {
+		t.Errorf("Len() = %d, want non-matching new conn kept idle", p.Len())
+	}
+	for _, info := range p.ConnInfo() {
+		if want := fmt.Sprint(info.ID%2 == 1); info.Labels["tls"] != want {
+			t.Errorf("conn %d labels = %v, want tls=%s", info.ID, info.Labels, want)
+		}
+	}
+}
+
+func TestGetMatchingWait(t *testing.T) {
+	skipWithoutInterceptors(t)
+	var dialed int32
+	p := pool.New(&pool.PoolConfig{
+		MaxCap: 2,
+		Wait:   true,
+		LabeledFactory: func() (interface{}, map[string]string, error) {
+			id := atomic.AddInt32(&dialed, 1)
+			return &testConn{id: int(id)}, map[string]string{"tls": fmt.Sprint(id%2 == 1)}, nil
+		},
+	})
+	defer p.Release()
+	var gets int32
+	p.Use(func(ctx context.Context, op pool.Op, conn interface{}, next pool.Invoker) (interface{}, error) {
+		if op == pool.OpGet {
+			atomic.AddInt32(&gets, 1)
+		}
+		return next(ctx, conn)
+	})
+	ctx := context.Background()
+	tls, _ := pool.ParseSelector("tls=true")
+	plain, _ := pool.ParseSelector("tls=false")
+
+	c1, _ := p.GetMatching(ctx, tls)
+	c2, _ := p.GetMatching(ctx, plain)
+	p.Put(c2)
+
+	// 名额已满且空闲连接不匹配，应等待匹配的连接放回，而不是关闭空闲连接新建
+	got := make(chan interface{})
+	go func() {
+		c, err := p.GetMatching(ctx, tls)
+		if err != nil {
+			t.Errorf("GetMatching(tls=true) err = %v", err)
+		}
+		got <- c
+	}()
+	select {
+	case c := <-got:
+		t.Fatalf("GetMatching(tls=true) = %v while the tls conn is busy, want wait", c)
+	case <-time.After(20 * time.Millisecond):
+	}
+	p.Put(c1)
+	if c := <-got; c != c1 {
+		t.Errorf("GetMatching(tls=true) = %v, want %v put back", c, c1)
+	}
+	if n := atomic.LoadInt32(&dialed); n != 2 {
+		t.Errorf("dialed = %d, want 2", n)
+	}
+	if p.Len() != 1 {
+		t.Errorf("Len() = %d, want the tls=false conn still idle", p.Len())
+	}
+	if n := atomic.LoadInt32(&gets); n != 3 {
+		t.Errorf("interceptor saw %d gets, want 3", n)
+	}
+}
+
+func TestParseSelector(t *testing.T) {
+	sel, err := pool.ParseSelector(" tls=true, db=analytics ")
+	if err != nil || len(sel) != 2 || sel["db"] != "analytics" {
+		t.Fatalf("ParseSelector() = %v, %v", sel, err)
+	}
+	if !sel.Matches(map[string]string{"tls": "true", "db": "analytics", "zone": "a"}) || sel.Matches(map[string]string{"tls": "true"}) {
+		t.Errorf("Matches() gave wrong result for %v", sel)
+	}
+	if _, err := pool.ParseSelector("tls"); err == nil {
+		t.Errorf("ParseSelector(tls) succeeded, want error")
+	}
+}
+
+func TestPin(t *testing.T) {
+	p, _ := newTestPool(&pool.PoolConfig{MaxCap: 2})
+	defer p.Release()
+
+	conn, _ := p.Get()
+	pc, err := p.Pin(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := p.Pin(conn); again != pc {
+		t.Errorf("Pin() of pinned conn returned a different PinnedConn")
+	}
+	if _, err := pc.Get(); !errors.Is(err, pool.ErrPinnedInUse) {
+		t.Errorf("PinnedConn.Get() while in use err = %v, want ErrPinnedInUse", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := p.Put(conn); err != nil {
+			t.Fatal(err)
+		}
+		if p.Len() != i || int(p.Stats().BusyConns) != 1 {
+			t.Fatalf("pinned conn returned to the pool: Len = %d, BusyLen = %d", p.Len(), int(p.Stats().BusyConns))
+		}
+		if other, _ := p.Get(); other == conn {
+			t.Fatal("Get() returned the pinned conn to another caller")
+		} else {
+			p.Put(other)
+		}
+		if c, err := pc.Get(); err != nil || c != conn {
+			t.Fatalf("PinnedConn.Get() = %v, %v", c, err)
+		}
+	}
+
+	p.PutAll([]interface{}{conn})
+	if err := p.Put(conn); !errors.Is(err, pool.ErrDoublePut) {
+		t.Errorf("second Put() of parked conn err = %v, want ErrDoublePut", err)
+	}
+	if err := pc.Unpin(); err != nil {
+		t.Fatal(err)
+	}
+	if p.Len() != 2 || int(p.Stats().BusyConns) != 0 {
+		t.Errorf("after Unpin Len = %d, BusyLen = %d, want 2 0", p.Len(), int(p.Stats().BusyConns))
+	}
+	if _, err := pc.Get(); !errors.Is(err, pool.ErrNotPinned) {
+		t.Errorf("PinnedConn.Get() after Unpin err = %v, want ErrNotPinned", err)
+	}
+}
+
+func TestPinClose(t *testing.T) {
+	p, _ := newTestPool(&pool.PoolConfig{MaxCap: 1})
+	defer p.Release()
+
+	conn, _ := p.Get()
+	pc, _ := p.Pin(conn)
+	p.Close(conn)
+	if _, err := pc.Get(); !errors.Is(err, pool.ErrNotPinned) {
+		t.Errorf("PinnedConn.Get() after Close err = %v, want ErrNotPinned", err)
+	}
+	if err := pc.Unpin(); !errors.Is(err, pool.ErrNotPinned) {
+		t.Errorf("Unpin() after Close err = %v, want ErrNotPinned", err)
+	}
+	if c, err := p.Get(); err != nil {
+		t.Fatal(err)
+	} else if err := p.Put(c); err != nil {
+		t.Errorf("Put() of new conn after closing a pinned one: %v", err)
+	}
+}
+
+// blipConn 读写出错后标记为不可用、可被探测恢复的连接
+type blipConn struct {
+	id     int
+	broken atomic.Bool
+}
+
+func (c *blipConn) Unusable() bool { return c.broken.Load() }
+func (c *blipConn) MarkUsable()    { c.broken.Store(false) }
+
+func TestBrokenLingerClose(t *testing.T) {
+	sim := poolsim.New(time.Now())
+	closed := make(map[*blipConn]bool)
+	cfg := sim.Config(&pool.PoolConfig{
+		MaxCap:       2,
+		BrokenLinger: time.Minute,
+		BrokenProbe:  func(interface{}) error { return nil },
+	})
+	cfg.Factory = func() (interface{}, error) { return &blipConn{}, nil }
+	cfg.Close = func(conn interface{}) error {
+		closed[conn.(*blipConn)] = true
+		return nil
+	}
+	p := pool.New(cfg)
+
+	// linger 借出一条连接并标记为不可用后放回
+	linger := func() *blipConn {
+		c, err := p.Get()
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.(*blipConn).broken.Store(true)
+		p.Put(c)
+		return c.(*blipConn)
+	}
+
+	c := linger()
+	if n := p.EvictWhere(func(interface{}, pool.ConnInfo) bool { return true }); n != 1 || !closed[c] {
+		t.Errorf("EvictWhere() = %d, closed = %v, want lingering conn closed", n, closed[c])
+	}
+	c = linger()
+	if n := p.ForceCloseAll(); n != 1 || !closed[c] {
+		t.Errorf("ForceCloseAll() = %d, closed = %v, want lingering conn closed", n, closed[c])
+	}
+	c = linger()
+	p.Release()
+	if !closed[c] {
+		t.Error("Release() left a lingering conn open")
+	}
+	sim.Clock.Advance(time.Minute)
+}
@@ -0,0 +1,107 @@
+package pool
+
+import "sync"
+
+// ChildPool 附属于某个连接的派生资源池，如预编译语句、AMQP通道，
+// 父连接被连接池关闭时，空闲的派生资源随之关闭，借出的派生资源在放回时关闭
+type ChildPool struct {
+	parent  interface{}
+	factory func(parent interface{}, key string) (interface{}, error)
+	close   func(interface{}) error
+
+	mu     sync.Mutex
+	idle   map[string][]interface{}
+	closed bool
+}
+
+// Children 返回连接的派生资源池，首次调用时创建
+func (c *ChannelPool) Children(conn interface{}) (*ChildPool, error) {
+	if c.childFactory == nil {
+		return nil, ErrNoChildFactory
+	}
+	if !trackable(conn) {
+		return nil, ErrUnknownConn
+	}
+
+	var children *ChildPool
+	c.tracked.lookup(trackKey(conn), func(wrapConn *idleConn) {
+		if wrapConn == nil {
+			return
+		}
+		if wrapConn.children == nil {
+			wrapConn.children = &ChildPool{
+				parent:  conn,
+				factory: c.childFactory,
+				close:   c.childClose,
+				idle:    make(map[string][]interface{}),
+			}
+		}
+		children = wrapConn.children
+	})
+	if children == nil {
+		return nil, ErrUnknownConn
+	}
+	return children, nil
+}
+
+// Get 取一个key对应的派生资源，没有空闲的时由ChildFactory创建
+func (p *ChildPool) Get(key string) (interface{}, error) {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil, ErrClosed
+	}
+	if idle := p.idle[key]; len(idle) > 0 {
+		child := idle[len(idle)-1]
+		p.idle[key] = idle[:len(idle)-1]
+		p.mu.Unlock()
+		return child, nil
+	}
+	p.mu.Unlock()
+	return p.factory(p.parent, key)
+}
+
+// Put 放回key对应的派生资源，父连接已关闭时直接关闭
+func (p *ChildPool) Put(key string, child interface{}) error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return p.closeChild(child)
+	}
+	p.idle[key] = append(p.idle[key], child)
+	p.mu.Unlock()
+	return nil
+}
+
+// Len 空闲的派生资源数
+func (p *ChildPool) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	n := 0
+	for _, idle := range p.idle {
+		n += len(idle)
+	}
+	return n
+}
+
+// release 父连接关闭时关闭所有空闲的派生资源
+func (p *ChildPool) release() {
+	p.mu.Lock()
+	idle := p.idle
+	p.idle = nil
+	p.closed = true
+	p.mu.Unlock()
+
+	for _, children := range idle {
+		for _, child := range children {
+			p.closeChild(child)
+		}
+	}
+}
+
+func (p *ChildPool) closeChild(child interface{}) error {
+	if p.close != nil {
+		return p.close(child)
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package pool
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// coarseClock 由定时器周期更新的粗粒度时钟，以精度换取更低的取时开销
+type coarseClock struct {
+	nanos int64
+	stop  chan struct{}
+}
+
+// newCoarseClock 创建精度为resolution的时钟，并启动后台更新
+func newCoarseClock(resolution time.Duration) *coarseClock {
+	clk := &coarseClock{
+		nanos: time.Now().UnixNano(),
+		stop:  make(chan struct{}),
+	}
+	go clk.run(resolution)
+	return clk
+}
+
+func (clk *coarseClock) run(resolution time.Duration) {
+	ticker := time.NewTicker(resolution)
+	defer ticker.Stop()
+	for {
+		select {
+		case t := <-ticker.C:
+			atomic.StoreInt64(&clk.nanos, t.UnixNano())
+		case <-clk.stop:
+			return
+		}
+	}
+}
+
+// Now 返回最近一次更新的时间
+func (clk *coarseClock) Now() time.Time {
+	return time.Unix(0, atomic.LoadInt64(&clk.nanos))
+}
+
+// Stop 停止后台更新
+func (clk *coarseClock) Stop() {
+	close(clk.stop)
+}
+
+// Clock 连接池使用的时钟，配置后空闲超时、存活时间、新建连接错误缓存、拨号耗时
+// 以及保活、看门狗、统计上报等后台定时任务均以其为准，可替换为虚拟时钟进行确定性测试
+type Clock interface {
+	// Now 返回当前时间
+	Now() time.Time
+	// Every 每隔d调用一次f，直到调用返回的stop
+	Every(d time.Duration, f func()) (stop func())
+	// AfterFunc d之后调用一次f，在此之前调用返回的stop可取消
+	AfterFunc(d time.Duration, f func()) (stop func())
+}
+
+// systemClock 使用系统时间的时钟
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) Every(d time.Duration, f func()) func() {
+	stop := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(d)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				f()
+			case <-stop:
+				return
+			}
+		}
+	}()
+	return func() { close(stop) }
+}
+
+func (systemClock) AfterFunc(d time.Duration, f func()) func() {
+	t := time.AfterFunc(d, f)
+	return func() { t.Stop() }
+}
+
+// every 每隔d调用一次f，连接池Release后自动停止
+func (c *ChannelPool) every(d time.Duration, f func()) {
+	stop := c.timeSource.Every(d, f)
+	go func() {
+		<-c.done
+		stop()
+	}()
+}
@@ -0,0 +1,25 @@
+package pool
+
+import "time"
+
+// ConnInfo 连接的元数据快照
+type ConnInfo struct {
+	// 连接编号，同一连接池内唯一，与Slog日志中的conn_id一致
+	ID uint64
+	// 连接创建时间
+	Created time.Time
+	// 连接最近一次放回连接池的时间
+	LastUsed time.Time
+	// 连接被借出的次数
+	UseCount uint64
+	// 连接来源标签，由PoolConfig.Origin生成
+	Origin string
+	// 是否已借出
+	Busy bool
+	// 最近一次借出的时间
+	LentAt time.Time
+	// 通过GetFor借出时的使用方
+	Owner string
+	// 新建时由LabeledFactory给出的标签，与连接池共享，不可修改
+	Labels map[string]string
+}
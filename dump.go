@@ -0,0 +1,105 @@
+package pool
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// PoolState 连接池的完整快照，所有字段均可直接序列化，用于无需调试器的线上排查
+type PoolState struct {
+	// 快照时间
+	Time time.Time
+	// 连接池名称及标签
+	Name   string
+	Labels map[string]string
+	// 连接池状态，即State().String()
+	State string
+	// 当前生效的配置
+	Config StateConfig
+	// 已创建且尚未关闭的连接数，包括无法追踪的连接
+	Open int
+	// 等待连接的goroutine数
+	Waiters int
+	// 空闲连接
+	Idle []ConnInfo
+	// 借出的连接，不可作为map key的连接无法追踪，不在其中
+	Busy []BusyConnInfo
+	// 统计信息
+	Stats Stats
+	// 新建连接错误缓存，配置了DialErrorTTL时有效
+	Circuit CircuitState
+}
+
+// StateConfig PoolState中的配置，包含UpdateConfig修改后的值
+type StateConfig struct {
+	MaxCap             int
+	MaxIdle            int
+	MaxOverflow        int
+	Wait               bool
+	WaitTimeout        time.Duration
+	IdleTimeout        time.Duration
+	MaxLifetime        time.Duration
+	ExpiryJitter       float64
+	DialErrorTTL       time.Duration
+	MaxConcurrentDials int
+}
+
+// BusyConnInfo 借出连接的元数据
+type BusyConnInfo struct {
+	ConnInfo
+	// 本次借出至今的时长
+	CheckedOutFor time.Duration
+}
+
+// CircuitState 新建连接错误缓存的状态，Open为true时新建连接直接返回Err
+type CircuitState struct {
+	Open  bool
+	Until time.Time
+	Err   string
+}
+
+// DumpState 返回连接池的完整快照
+func (c *ChannelPool) DumpState() PoolState {
+	now := c.now()
+	state := PoolState{
+		Time:    now,
+		Name:    c.name,
+		Labels:  make(map[string]string, len(c.labels)),
+		State:   c.State().String(),
+		Open:    int(atomic.LoadInt32(&c.numOpen)),
+		Waiters: int(atomic.LoadInt32(&c.waiters)),
+		Stats:   *c.Stats(),
+		Config: StateConfig{
+			MaxCap:       c.maxCap,
+			MaxIdle:      c.loadMaxIdle(),
+			MaxOverflow:  c.maxOverflow,
+			Wait:         c.wait,
+			WaitTimeout:  c.loadWaitTimeout(),
+			IdleTimeout:  c.loadIdleTimeout(),
+			MaxLifetime:  c.loadMaxLifetime(),
+			ExpiryJitter: c.expiryJitter,
+			DialErrorTTL: c.dialErrTTL,
+		},
+	}
+	state.Config.MaxConcurrentDials = cap(c.dialSem)
+
+	for k, v := range c.labels {
+		state.Labels[k] = v
+	}
+
+	c.mu.Lock()
+	if c.dialErr != nil && now.Before(c.dialErrUntil) {
+		state.Circuit = CircuitState{Open: true, Until: c.dialErrUntil, Err: c.dialErr.Error()}
+	}
+	c.mu.Unlock()
+
+	c.tracked.each(func(_ interface{}, wrapConn *idleConn) {
+		info := wrapConn.info()
+		if !info.Busy {
+			state.Idle = append(state.Idle, info)
+			return
+		}
+		state.Busy = append(state.Busy, BusyConnInfo{ConnInfo: info, CheckedOutFor: now.Sub(info.LentAt)})
+	})
+	return state
+}
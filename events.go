@@ -0,0 +1,98 @@
+package pool
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// EventType 连接池事件类型
+type EventType int
+
+const (
+	// EventConnCreated 新建了一条连接
+	EventConnCreated EventType = iota
+	// EventConnClosed 连接池关闭了一条连接，Reason为关闭原因
+	EventConnClosed
+	// EventPoolExhausted 连接数已达上限，Get返回ErrPoolExhausted或开始等待
+	EventPoolExhausted
+	// EventCircuitOpened 新建连接失败，DialErrorTTL内的新建连接将直接返回该错误
+	EventCircuitOpened
+	// EventResized 通过UpdateConfig修改了MaxIdle，Size为新的MaxIdle
+	EventResized
+	// EventWatchdogRebuild 看门狗重建了连接池，Size为关闭的空闲连接数
+	EventWatchdogRebuild
+	// EventBorrowExceeded 连接借出超过MaxBorrowDuration
+	EventBorrowExceeded
+	// EventFlushed 短时间内失败过多，按FlushOnErrors清空了空闲连接，Size为关闭的空闲连接数
+	EventFlushed
+)
+
+var eventTypeNames = [...]string{
+	EventConnCreated:     "conn created",
+	EventConnClosed:      "conn closed",
+	EventPoolExhausted:   "pool exhausted",
+	EventCircuitOpened:   "circuit opened",
+	EventResized:         "resized",
+	EventWatchdogRebuild: "watchdog rebuild",
+	EventBorrowExceeded:  "borrow exceeded",
+	EventFlushed:         "flushed",
+}
+
+func (t EventType) String() string {
+	if t < 0 || int(t) >= len(eventTypeNames) {
+		return "unknown"
+	}
+	return eventTypeNames[t]
+}
+
+// Event 连接池事件
+type Event struct {
+	Type   EventType
+	Time   time.Time
+	Conn   interface{} // the connection concerned, for EventConnCreated and EventConnClosed
+	ConnID uint64      // ID of Conn, see ConnInfo.ID
+	Reason CloseReason // close reason, for EventConnClosed
+	Size   int         // new MaxIdle for EventResized, number of idle connections closed for EventWatchdogRebuild and EventFlushed
+
+	Pool   string            // PoolConfig.Name of the pool that emitted the event
+	Labels map[string]string // PoolConfig.Labels of the pool, shared and must not be modified
+}
+
+// defaultEventBuffer 事件通道的默认缓冲长度
+const defaultEventBuffer = 64
+
+// closedEvents 使用poolnostats构建时Events返回的已关闭通道
+var closedEvents = func() chan Event {
+	ch := make(chan Event)
+	close(ch)
+	return ch
+}()
+
+// Events 返回连接池事件通道，首次调用后开始发送事件
+// 通道缓冲已满时丢弃事件并计数，参见DroppedEvents；使用poolnostats构建时返回已关闭的通道
+func (c *ChannelPool) Events() <-chan Event {
+	if !instrumented {
+		return closedEvents
+	}
+	atomic.StoreInt32(&c.eventsOn, 1)
+	return c.events
+}
+
+// DroppedEvents 因事件通道已满而丢弃的事件数
+func (c *ChannelPool) DroppedEvents() uint64 {
+	return atomic.LoadUint64(&c.eventsDropped)
+}
+
+// emit 发送事件，未调用过Events时直接返回
+func (c *ChannelPool) emit(ev Event) {
+	if !instrumented || atomic.LoadInt32(&c.eventsOn) == 0 {
+		return
+	}
+	ev.Time = c.now()
+	ev.Pool, ev.Labels = c.name, c.labels
+	select {
+	case c.events <- ev:
+	default:
+		atomic.AddUint64(&c.eventsDropped, 1)
+	}
+}
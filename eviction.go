@@ -0,0 +1,87 @@
+package pool
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// EvictionPolicy 连接池空闲连接超出上限时，决定丢弃哪一条连接
+//
+// Victim 的参数为候选连接的元数据，最后一项为正在放回连接池的连接，
+// 返回值为需要丢弃的连接下标，越界时丢弃正在放回的连接
+//
+// 候选连接为空闲队列头部至多8条最早放回的连接，其余空闲连接在选择期间仍可被借出
+type EvictionPolicy interface {
+	Victim(conns []ConnInfo) int
+}
+
+// evictSample 淘汰时从空闲队列头部取出的候选连接数上限
+const evictSample = 8
+
+// sampleIdle 从空闲队列头部取出至多n条连接作为淘汰候选
+func sampleIdle(conns idleQueue, n int) []*idleConn {
+	candidates := make([]*idleConn, 0, n+1)
+	for len(candidates) < n {
+		wrapConn := conns.pop()
+		if wrapConn == nil {
+			break
+		}
+		candidates = append(candidates, wrapConn)
+	}
+	return candidates
+}
+
+// EvictionFunc 将普通函数转换为 EvictionPolicy
+type EvictionFunc func(conns []ConnInfo) int
+
+// Victim 实现 EvictionPolicy
+func (f EvictionFunc) Victim(conns []ConnInfo) int {
+	return f(conns)
+}
+
+var (
+	// EvictLIFO 丢弃正在放回的连接，即未配置淘汰策略时的行为
+	EvictLIFO EvictionPolicy = EvictionFunc(evictLIFO)
+	// EvictLRU 丢弃最久未被使用的连接
+	EvictLRU EvictionPolicy = EvictionFunc(evictLRU)
+	// EvictOldest 丢弃创建时间最早的连接
+	EvictOldest EvictionPolicy = EvictionFunc(evictOldest)
+	// EvictRandom 随机丢弃一条连接
+	EvictRandom EvictionPolicy = &randomEviction{rnd: rand.New(rand.NewSource(time.Now().UnixNano()))}
+)
+
+func evictLIFO(conns []ConnInfo) int {
+	return len(conns) - 1
+}
+
+func evictLRU(conns []ConnInfo) int {
+	victim := len(conns) - 1
+	for i, info := range conns {
+		if info.LastUsed.Before(conns[victim].LastUsed) {
+			victim = i
+		}
+	}
+	return victim
+}
+
+func evictOldest(conns []ConnInfo) int {
+	victim := len(conns) - 1
+	for i, info := range conns {
+		if info.Created.Before(conns[victim].Created) {
+			victim = i
+		}
+	}
+	return victim
+}
+
+type randomEviction struct {
+	mu  sync.Mutex
+	rnd *rand.Rand
+}
+
+func (r *randomEviction) Victim(conns []ConnInfo) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.rnd.Intn(len(conns))
+}
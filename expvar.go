@@ -0,0 +1,18 @@
+package pool
+
+import "expvar"
+
+// ExpvarFunc 返回以JSON输出p的统计信息的expvar.Func，连接池的Name(标签名pool)及Labels输出在labels中，
+// 如 expvar.Publish("redis_pool", pool.ExpvarFunc(p)) 后可在 /debug/vars 查看
+func ExpvarFunc(p Pooler) expvar.Func {
+	var labels map[string]string
+	if l, ok := p.(labeler); ok {
+		labels = l.telemetryLabels()
+	}
+	return func() interface{} {
+		return struct {
+			Labels map[string]string `json:"labels,omitempty"`
+			Stats  *Stats            `json:"stats"`
+		}{labels, p.Stats()}
+	}
+}
@@ -0,0 +1,132 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+)
+
+// defaultAttemptDelay RFC 8305推荐的连接尝试间隔
+const defaultAttemptDelay = 250 * time.Millisecond
+
+// WithAttemptDelay 设置双栈拨号时相邻两次连接尝试的间隔，仅对HappyEyeballsFactory有效
+func WithAttemptDelay(d time.Duration) TCPOption {
+	return func(o *tcpOptions) { o.attemptDelay = d }
+}
+
+// HappyEyeballsFactory 返回按RFC 8305双栈拨号的工厂方法：同时解析A和AAAA记录，
+// IPv6与IPv4地址交替排列，每隔一段时间发起下一次连接尝试，最先成功的连接放入连接池
+func HappyEyeballsFactory(addr string, opts ...TCPOption) Factory {
+	o := newTCPOptions(opts)
+	if o.attemptDelay <= 0 {
+		o.attemptDelay = defaultAttemptDelay
+	}
+	return func() (interface{}, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		ctx := context.Background()
+		if o.dialer.Timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, o.dialer.Timeout)
+			defer cancel()
+		}
+		ips, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		return o.race(ctx, interleaveFamilies(ips), port)
+	}
+}
+
+// interleaveFamilies 按IPv6优先交替排列两个地址族的地址
+func interleaveFamilies(ips []net.IPAddr) []net.IPAddr {
+	var v6, v4 []net.IPAddr
+	for _, ip := range ips {
+		if ip.IP.To4() != nil {
+			v4 = append(v4, ip)
+		} else {
+			v6 = append(v6, ip)
+		}
+	}
+	sorted := make([]net.IPAddr, 0, len(ips))
+	for i := 0; i < len(v6) || i < len(v4); i++ {
+		if i < len(v6) {
+			sorted = append(sorted, v6[i])
+		}
+		if i < len(v4) {
+			sorted = append(sorted, v4[i])
+		}
+	}
+	return sorted
+}
+
+// race 依次错开发起连接尝试，返回最先成功的连接并关闭其余连接
+func (o *tcpOptions) race(ctx context.Context, ips []net.IPAddr, port string) (net.Conn, error) {
+	if len(ips) == 0 {
+		return nil, errors.New("pool: no addresses to dial")
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		conn net.Conn
+		err  error
+	}
+	results := make(chan result, len(ips))
+	dialer := o.dialer
+	dialer.Timeout = 0
+	dial := func(ip net.IPAddr) {
+		conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(ip.String(), port))
+		results <- result{conn, err}
+	}
+
+	next, pending := 0, 0
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	var firstErr error
+	for {
+		select {
+		case <-timer.C:
+			if next < len(ips) {
+				go dial(ips[next])
+				next++
+				pending++
+				timer.Reset(o.attemptDelay)
+			}
+			continue
+		case r := <-results:
+			pending--
+			if r.err == nil {
+				cancel()
+				// 关闭其余尝试中成功的连接
+				go func(n int) {
+					for ; n > 0; n-- {
+						if r := <-results; r.conn != nil {
+							r.conn.Close()
+						}
+					}
+				}(pending)
+				return o.setNoDelay(r.conn)
+			}
+			if firstErr == nil {
+				firstErr = r.err
+			}
+			if pending == 0 && next == len(ips) {
+				return nil, firstErr
+			}
+			// 上一次尝试失败时立即发起下一次
+			if next < len(ips) {
+				if !timer.Stop() {
+					select {
+					case <-timer.C:
+					default:
+					}
+				}
+				timer.Reset(0)
+			}
+		}
+	}
+}
@@ -0,0 +1,126 @@
+// Package fatihpool 在ChannelPool之上提供与github.com/fatih/pool一致的接口，
+// 从该包迁移时只需替换import路径：
+//
+//	import pool "github.com/hms58/pool/fatihpool"
+package fatihpool
+
+import (
+	"errors"
+	"net"
+	"sync"
+
+	"github.com/hms58/pool"
+)
+
+// ErrClosed 连接池已关闭Error
+var ErrClosed = pool.ErrClosed
+
+// Pool 与fatih/pool相同的连接池接口
+type Pool interface {
+	// Get 取出一个连接，调用返回连接的Close将其放回连接池
+	Get() (net.Conn, error)
+
+	// Close 关闭连接池及其中的所有连接
+	Close()
+
+	// Len 连接池中空闲连接数
+	Len() int
+}
+
+// Factory 生成连接的方法
+type Factory func() (net.Conn, error)
+
+// ChannelPool 包装v1连接池
+type ChannelPool struct {
+	p pool.Pooler
+}
+
+// NewChannelPool 创建连接池并预先建立initialCap个连接，maxCap为保留的最大空闲连接数，
+// 与fatih/pool一致，借出的连接数不受限制，任一预建连接失败时返回错误
+func NewChannelPool(initialCap, maxCap int, factory Factory) (Pool, error) {
+	if initialCap < 0 || maxCap <= 0 || initialCap > maxCap {
+		return nil, errors.New("invalid capacity settings")
+	}
+	if factory == nil {
+		return nil, errors.New("invalid factory")
+	}
+
+	var (
+		mu       sync.Mutex
+		firstErr error
+	)
+	p := pool.NewChannelPool(&pool.PoolConfig{
+		InitialCap: initialCap,
+		MaxCap:     maxCap,
+		Factory: func() (interface{}, error) {
+			conn, err := factory()
+			if err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+				return nil, err
+			}
+			return conn, nil
+		},
+		Close: pool.CloseNetConn,
+	})
+
+	mu.Lock()
+	err := firstErr
+	mu.Unlock()
+	if err != nil {
+		p.Release()
+		return nil, errors.New("factory is not able to fill the pool: " + err.Error())
+	}
+	return &ChannelPool{p: p}, nil
+}
+
+func (c *ChannelPool) Get() (net.Conn, error) {
+	v, err := c.p.Get()
+	if err != nil {
+		return nil, err
+	}
+	return &PoolConn{Conn: v.(net.Conn), p: c.p}, nil
+}
+
+func (c *ChannelPool) Close() {
+	c.p.Release()
+}
+
+func (c *ChannelPool) Len() int {
+	return c.p.Len()
+}
+
+// PoolConn 借出的连接，Close时放回连接池，MarkUnusable后Close时关闭底层连接
+type PoolConn struct {
+	net.Conn
+	mu       sync.RWMutex
+	p        pool.Pooler
+	unusable bool
+}
+
+// Close 将连接放回连接池，连接池已关闭时关闭底层连接
+func (c *PoolConn) Close() error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var err error
+	if c.unusable {
+		err = c.p.Close(c.Conn)
+	} else {
+		err = c.p.Put(c.Conn)
+	}
+	if errors.Is(err, pool.ErrClosed) {
+		return nil
+	}
+	return err
+}
+
+// MarkUnusable 标记连接不可用，Close时关闭而不是放回连接池
+func (c *PoolConn) MarkUnusable() {
+	c.mu.Lock()
+	c.unusable = true
+	c.mu.Unlock()
+}
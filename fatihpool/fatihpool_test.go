@@ -0,0 +1,61 @@
+package fatihpool_test
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/hms58/pool/fatihpool"
+)
+
+func pipeFactory() (net.Conn, error) {
+	c, _ := net.Pipe()
+	return c, nil
+}
+
+func TestNewChannelPool(t *testing.T) {
+	p, err := fatihpool.NewChannelPool(2, 3, pipeFactory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	if p.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", p.Len())
+	}
+
+	if _, err := fatihpool.NewChannelPool(3, 2, pipeFactory); err == nil {
+		t.Error("NewChannelPool() with initialCap > maxCap succeeded")
+	}
+	failing := func() (net.Conn, error) { return nil, errors.New("refused") }
+	if _, err := fatihpool.NewChannelPool(1, 2, failing); err == nil {
+		t.Error("NewChannelPool() with failing factory succeeded")
+	}
+}
+
+func TestPoolConnClose(t *testing.T) {
+	p, _ := fatihpool.NewChannelPool(0, 2, pipeFactory)
+
+	// 借出的连接数不受maxCap限制
+	conns := make([]net.Conn, 3)
+	for i := range conns {
+		c, err := p.Get()
+		if err != nil {
+			t.Fatal(err)
+		}
+		conns[i] = c
+	}
+	conns[0].Close()
+	conns[1].(*fatihpool.PoolConn).MarkUnusable()
+	conns[1].Close()
+	if p.Len() != 1 {
+		t.Errorf("Len() = %d, want 1", p.Len())
+	}
+
+	p.Close()
+	if err := conns[2].Close(); err != nil {
+		t.Errorf("Close() after pool closed err = %v", err)
+	}
+	if _, err := p.Get(); !errors.Is(err, fatihpool.ErrClosed) {
+		t.Errorf("Get() after Close err = %v, want ErrClosed", err)
+	}
+}
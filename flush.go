@@ -0,0 +1,96 @@
+package pool
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// FlushConfig 失败激增时清空空闲连接的配置，Failures大于0时启用。后端重启后空闲连接通常已全部失效，
+// Window内Ping/OnBorrow校验失败、保活失败及读写出错被关闭的连接达到Failures条时，
+// 关闭所有空闲连接并发送EventFlushed事件，避免调用方逐条取到失效的连接
+type FlushConfig struct {
+	//Window内失败达到该次数时清空空闲连接
+	Failures int
+	//统计失败次数的窗口，默认10秒
+	Window time.Duration
+	//清空后抑制再次清空的时长，每次清空后翻倍直到MaxBackoff，抑制结束后超过MaxBackoff未再清空时重置，默认与Window相同
+	Backoff time.Duration
+	//抑制时长的上限，默认5分钟
+	MaxBackoff time.Duration
+}
+
+const (
+	// defaultFlushWindow 统计失败次数的默认窗口
+	defaultFlushWindow = 10 * time.Second
+	// defaultFlushMaxBackoff 抑制时长的默认上限
+	defaultFlushMaxBackoff = 5 * time.Minute
+)
+
+// errorFlush 按FlushConfig统计失败次数并决定是否清空空闲连接
+type errorFlush struct {
+	cfg FlushConfig
+
+	mu            sync.Mutex
+	windowStart   time.Time
+	failures      int
+	backoff       time.Duration
+	suppressUntil time.Time
+}
+
+func newErrorFlush(cfg FlushConfig) *errorFlush {
+	if cfg.Window <= 0 {
+		cfg.Window = defaultFlushWindow
+	}
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = cfg.Window
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = defaultFlushMaxBackoff
+	}
+	return &errorFlush{cfg: cfg, backoff: cfg.Backoff}
+}
+
+// record 记录一次失败，窗口内达到阈值且不在抑制期内时返回true
+func (f *errorFlush) record(now time.Time) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if now.Sub(f.windowStart) > f.cfg.Window {
+		f.windowStart = now
+		f.failures = 0
+	}
+	if f.failures++; f.failures < f.cfg.Failures {
+		return false
+	}
+	f.windowStart = now
+	f.failures = 0
+	if now.Before(f.suppressUntil) {
+		return false
+	}
+	if !f.suppressUntil.IsZero() && now.Sub(f.suppressUntil) > f.cfg.MaxBackoff {
+		f.backoff = f.cfg.Backoff
+	}
+	f.suppressUntil = now.Add(f.backoff)
+	if f.backoff *= 2; f.backoff > f.cfg.MaxBackoff {
+		f.backoff = f.cfg.MaxBackoff
+	}
+	return true
+}
+
+// recordFailure 连接因reason被关闭时计入失败次数，达到阈值时在后台清空空闲连接
+// 关闭连接时可能持有连接池的锁，因此不同步清空
+func (c *ChannelPool) recordFailure(reason CloseReason) {
+	if outlierReason(reason) && c.flush.record(c.now()) {
+		go c.flushIdle()
+	}
+}
+
+// flushIdle 以CloseFlushed关闭所有空闲连接
+func (c *ChannelPool) flushIdle() {
+	if c.getConns() == nil {
+		return
+	}
+	n := c.filterIdle(func(interface{}, ConnInfo) bool { return true }, CloseFlushed)
+	c.logAttrs(slog.LevelWarn, "flushed idle connections after repeated failures", slog.Int("conns", n))
+	c.emit(Event{Type: EventFlushed, Size: n})
+}
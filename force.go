@@ -0,0 +1,73 @@
+package pool
+
+// markUnusable 包装连接可被标记为不可用，强制关闭后持有方的读写将失败
+type markUnusable interface {
+	MarkUnusable()
+}
+
+// ForceClose 强制关闭满足pred的已借出连接，用于紧急切换，返回关闭的连接数
+// 持有方之后的读写将失败，Put返回ErrUnknownConn
+// 使用pooldebug构建时连接池不持有借出连接的引用，无法强制关闭
+func (c *ChannelPool) ForceClose(pred func(conn interface{}, info ConnInfo) bool) int {
+	type candidate struct {
+		key      interface{}
+		wrapConn *idleConn
+		info     ConnInfo
+	}
+	// pred在锁外调用，可在其中调用ConnInfo、ConnID等方法
+	var candidates []candidate
+	c.tracked.each(func(key interface{}, wrapConn *idleConn) {
+		if wrapConn.lent && wrapConn.conn != nil {
+			candidates = append(candidates, candidate{key, wrapConn, wrapConn.info()})
+		}
+	})
+
+	var matched []candidate
+	for _, cand := range candidates {
+		if pred(cand.wrapConn.conn, cand.info) {
+			matched = append(matched, cand)
+		}
+	}
+
+	var victims []*idleConn
+	var owners []string
+	for _, cand := range matched {
+		wrapConn := cand.wrapConn
+		s := wrapConn.shard
+		s.mu.Lock()
+		// 判断期间可能已归还或关闭
+		if s.conns[cand.key] != wrapConn || !wrapConn.lent || wrapConn.conn == nil {
+			s.mu.Unlock()
+			continue
+		}
+		wrapConn.lent = false
+		delete(s.conns, cand.key)
+		c.checkGiveBack(wrapConn.conn)
+		if wrapConn.owner != "" {
+			owners = append(owners, wrapConn.owner)
+		}
+		s.mu.Unlock()
+		victims = append(victims, wrapConn)
+	}
+
+	for _, owner := range owners {
+		c.releaseOwner(owner, false)
+	}
+	for _, wrapConn := range victims {
+		conn := wrapConn.conn
+		if m, ok := conn.(markUnusable); ok {
+			m.MarkUnusable()
+		}
+		c.releaseBusy()
+		req := c.retire(wrapConn, CloseForced)
+		c.unreserve()
+		c.closeConn(c.close, req)
+	}
+	return len(victims)
+}
+
+// ForceCloseAll 强制关闭所有连接，包括已借出和空闲的连接，返回关闭的连接数
+func (c *ChannelPool) ForceCloseAll() int {
+	all := func(interface{}, ConnInfo) bool { return true }
+	return c.ForceClose(all) + c.filterIdle(all, CloseForced) + c.closeLingering(all, CloseForced)
+}
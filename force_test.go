@@ -0,0 +1,76 @@
+//go:build !pooldebug && !poolnostats
+
+package pool_test
+
+import (
+	"testing"
+
+	"github.com/hms58/pool"
+)
+
+func TestForceClose(t *testing.T) {
+	p, dialed := newTestPool(&pool.PoolConfig{MaxCap: 3, OwnerQuota: 1})
+	defer p.Release()
+
+	a, _ := p.Get()
+	p.Get()
+	c, _ := p.Get()
+	p.Put(c)
+
+	n := p.ForceClose(func(conn interface{}, _ pool.ConnInfo) bool { return conn == a })
+	if n != 1 || !(*dialed)[0].closed || (*dialed)[1].closed {
+		t.Fatalf("ForceClose() = %d, want only conn a closed", n)
+	}
+	if err := p.Put(a); err != pool.ErrUnknownConn {
+		t.Errorf("Put() after ForceClose err = %v, want ErrUnknownConn", err)
+	}
+
+	if n := p.ForceCloseAll(); n != 2 {
+		t.Errorf("ForceCloseAll() = %d, want 2", n)
+	}
+	if !(*dialed)[1].closed || !(*dialed)[2].closed || p.Len() != 0 {
+		t.Error("ForceCloseAll() left connections open")
+	}
+	if got := p.Stats().Closes[pool.CloseForced]; got != 3 {
+		t.Errorf("Closes[CloseForced] = %d, want 3", got)
+	}
+}
+
+func TestForceClosePredCallsPool(t *testing.T) {
+	p, _ := newTestPool(&pool.PoolConfig{MaxCap: 2})
+	defer p.Release()
+
+	a, _ := p.Get()
+	id := p.ConnID(a)
+	// pred中调用连接池的方法不应死锁
+	n := p.ForceClose(func(conn interface{}, _ pool.ConnInfo) bool {
+		return p.ConnID(conn) == id && len(p.ConnInfo()) == 1
+	})
+	if n != 1 {
+		t.Errorf("ForceClose() = %d, want 1", n)
+	}
+}
+
+func TestForceCloseInvariants(t *testing.T) {
+	var violations []error
+	p, _ := newTestPool(&pool.PoolConfig{
+		MaxCap:               2,
+		Wait:                 true,
+		CheckInvariants:      true,
+		OnInvariantViolation: func(err error) { violations = append(violations, err) },
+	})
+	defer p.Release()
+
+	a, _ := p.Get()
+	b, _ := p.Get()
+	if n := p.ForceCloseAll(); n != 2 {
+		t.Fatalf("ForceCloseAll() = %d, want 2", n)
+	}
+	a, _ = p.Get()
+	b, _ = p.Get()
+	p.Put(a)
+	p.Put(b)
+	if len(violations) != 0 {
+		t.Errorf("violations after force closing busy conns: %v", violations)
+	}
+}
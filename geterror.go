@@ -0,0 +1,89 @@
+package pool
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrorKind 取连接失败的原因分类
+type ErrorKind int
+
+const (
+	// KindOther 未归类的错误，如拦截器返回的错误
+	KindOther ErrorKind = iota
+	// KindTimeout 等待连接超过WaitTimeout或ctx超时
+	KindTimeout
+	// KindCanceled ctx被取消
+	KindCanceled
+	// KindClosed 连接池已关闭或正在排空
+	KindClosed
+	// KindExhausted 连接数已达上限、没有空闲连接或使用方配额已满
+	KindExhausted
+	// KindDialFailed 工厂方法返回错误
+	KindDialFailed
+	// KindCircuitOpen 新建连接错误缓存生效中，未调用工厂方法直接返回缓存的错误
+	KindCircuitOpen
+	// KindValidation 新建连接的Prepare失败
+	KindValidation
+	// KindRateLimited 超过GetRateLimit
+	KindRateLimited
+
+	errorKindMax
+)
+
+var errorKindNames = [errorKindMax]string{
+	KindOther:       "other",
+	KindTimeout:     "timeout",
+	KindCanceled:    "canceled",
+	KindClosed:      "closed",
+	KindExhausted:   "exhausted",
+	KindDialFailed:  "dial failed",
+	KindCircuitOpen: "circuit open",
+	KindValidation:  "validation",
+	KindRateLimited: "rate limited",
+}
+
+func (k ErrorKind) String() string {
+	if k < 0 || k >= errorKindMax {
+		return "unknown"
+	}
+	return errorKindNames[k]
+}
+
+// GetError Get等取连接方法返回的错误，Kind为失败原因的分类，Err为原始错误
+// 可用errors.As取出Kind区分重试或限流策略，errors.Is(err, ErrGetTimeout)等判断仍然有效
+type GetError struct {
+	Kind ErrorKind
+	Err  error
+}
+
+func (e *GetError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *GetError) Unwrap() error {
+	return e.Err
+}
+
+// getErr 将取连接失败的错误包装为GetError，已包装的错误原样返回
+func getErr(err error) error {
+	var ge *GetError
+	if errors.As(err, &ge) {
+		return err
+	}
+	kind := KindOther
+	switch {
+	case errors.Is(err, ErrGetTimeout), errors.Is(err, context.DeadlineExceeded):
+		kind = KindTimeout
+	case errors.Is(err, context.Canceled):
+		kind = KindCanceled
+	case errors.Is(err, ErrClosed), errors.Is(err, ErrDraining):
+		kind = KindClosed
+	case errors.Is(err, ErrPoolExhausted), errors.Is(err, ErrNoIdleConn), errors.Is(err, ErrOwnerQuota),
+		errors.Is(err, ErrNoMatchingConn):
+		kind = KindExhausted
+	case errors.Is(err, ErrRateLimited):
+		kind = KindRateLimited
+	}
+	return &GetError{Kind: kind, Err: err}
+}
@@ -0,0 +1,28 @@
+package pool
+
+import (
+	"context"
+	"time"
+)
+
+// GetOptions GetWithOptions单次取连接的选项，零值与GetContext相同
+type GetOptions struct {
+	//只取空闲时间不超过该时长的连接，较旧的空闲连接保留给其他调用方，0表示不限制
+	//用于对延迟敏感、不希望取到可能已被服务端断开的连接的调用，不改变连接池的IdleTimeout
+	MaxIdleAge time.Duration
+}
+
+// GetWithOptions 按opts取一个连接，MaxIdleAge大于0时只借出足够新的空闲连接，没有时新建一条，
+// 名额已满时不经校验关闭一条较旧的空闲连接（CloseEvicted）腾出名额，没有可关闭的连接时与Get相同地按Wait等待
+func (c *ChannelPool) GetWithOptions(ctx context.Context, opts GetOptions) (interface{}, error) {
+	if opts.MaxIdleAge <= 0 {
+		return c.GetContext(ctx)
+	}
+	return c.get(ctx, c.qosLimit(""), &idleFilter{
+		match: func(wrapConn *idleConn) bool {
+			return c.now().Sub(wrapConn.t) <= opts.MaxIdleAge
+		},
+		strict: true,
+		evict:  true,
+	})
+}
@@ -0,0 +1,83 @@
+package pool
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// defaultGracefulTimeout GracefulOnSignal等待借出连接归还的默认时间
+const defaultGracefulTimeout = 30 * time.Second
+
+// GracefulOnSignal 收到signals中任一信号时优雅关闭连接池，未指定信号时监听SIGTERM和Interrupt，
+// 最多等待30秒，参见GracefulOnSignalTimeout
+func GracefulOnSignal(p Pooler, signals ...os.Signal) (<-chan error, func()) {
+	return GracefulOnSignalTimeout(p, defaultGracefulTimeout, signals...)
+}
+
+// drainer 可排空并等待借出连接归还的连接池，其他Pooler收到信号后直接Release
+type drainer interface {
+	Drain()
+	Wait(ctx context.Context) error
+	ReleaseErr() error
+}
+
+// releaseNotifier 连接池释放时关闭released返回的通道，GracefulOnSignal据此停止监听
+type releaseNotifier interface {
+	released() <-chan struct{}
+}
+
+// released 返回Release时关闭的通道
+func (c *ChannelPool) released() <-chan struct{} {
+	return c.done
+}
+
+// GracefulOnSignalTimeout 收到信号后Drain拒绝新的Get并关闭空闲连接，最多等待timeout让借出的连接归还，
+// 然后Release释放连接池。返回的通道在关闭完成后收到ReleaseErr的结果，可用于阻塞main直到关闭完成；
+// 调用stop或连接池已被其他调用释放时停止监听信号，通道直接关闭
+func GracefulOnSignalTimeout(p Pooler, timeout time.Duration, signals ...os.Signal) (done <-chan error, stop func()) {
+	if len(signals) == 0 {
+		signals = []os.Signal{syscall.SIGTERM, os.Interrupt}
+	}
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, signals...)
+
+	var released <-chan struct{}
+	if n, ok := p.(releaseNotifier); ok {
+		released = n.released()
+	}
+	stopped := make(chan struct{})
+	var once sync.Once
+	stop = func() { once.Do(func() { close(stopped) }) }
+
+	result := make(chan error, 1)
+	go func() {
+		defer close(result)
+		select {
+		case <-sig:
+		case <-stopped:
+			signal.Stop(sig)
+			return
+		case <-released:
+			signal.Stop(sig)
+			return
+		}
+		signal.Stop(sig)
+		d, ok := p.(drainer)
+		if !ok {
+			p.Release()
+			result <- nil
+			return
+		}
+		d.Drain()
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		// 超时后仍未归还的连接在放回时关闭
+		d.Wait(ctx)
+		cancel()
+		result <- d.ReleaseErr()
+	}()
+	return result, stop
+}
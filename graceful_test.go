@@ -0,0 +1,77 @@
+package pool_test
+
+import (
+	"errors"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/hms58/pool"
+)
+
+func TestGracefulOnSignal(t *testing.T) {
+	p := pool.New(&pool.PoolConfig{
+		MaxCap:  2,
+		Factory: func() (interface{}, error) { return new(int), nil },
+	})
+	v, _ := p.Get()
+	idle, _ := p.Get()
+	p.Put(idle)
+
+	done, stop := pool.GracefulOnSignalTimeout(p, time.Second, syscall.SIGUSR1)
+	defer stop()
+	syscall.Kill(syscall.Getpid(), syscall.SIGUSR1)
+
+	time.Sleep(20 * time.Millisecond)
+	if p.State() != pool.StateDraining {
+		t.Fatalf("State() = %v, want draining while a conn is checked out", p.State())
+	}
+	if _, err := p.Get(); !errors.Is(err, pool.ErrDraining) {
+		t.Errorf("Get() while draining err = %v, want ErrDraining", err)
+	}
+
+	p.Put(v)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("ReleaseErr() = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("pool not released after conns were returned")
+	}
+	if !p.IsClosed() {
+		t.Error("pool not closed")
+	}
+}
+
+func TestGracefulOnSignalStop(t *testing.T) {
+	p := pool.New(&pool.PoolConfig{
+		MaxCap:  1,
+		Factory: func() (interface{}, error) { return new(int), nil },
+	})
+	defer p.Release()
+
+	done, stop := pool.GracefulOnSignalTimeout(p, time.Second, syscall.SIGUSR2)
+	stop()
+	select {
+	case err, ok := <-done:
+		if ok {
+			t.Errorf("done received %v after stop, want closed", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("handler still running after stop")
+	}
+	if p.IsClosed() {
+		t.Error("pool closed without a signal")
+	}
+
+	// 连接池被其他调用释放后停止监听
+	done, stop = pool.GracefulOnSignalTimeout(p, time.Second, syscall.SIGUSR2)
+	defer stop()
+	p.Release()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("handler still running after Release")
+	}
+}
@@ -0,0 +1,103 @@
+// Package grpcpool 基于pool的*grpc.ClientConn连接池
+package grpcpool
+
+import (
+	"context"
+	"errors"
+
+	"github.com/hms58/pool"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/status"
+)
+
+// ErrNotReady 连接处于不可用状态Error
+var ErrNotReady = errors.New("grpcpool: connection is not ready")
+
+// Factory 返回创建到target的*grpc.ClientConn的工厂方法
+func Factory(target string, opts ...grpc.DialOption) pool.Factory {
+	return func() (interface{}, error) {
+		return grpc.NewClient(target, opts...)
+	}
+}
+
+// Close 关闭*grpc.ClientConn
+func Close(v interface{}) error {
+	return v.(*grpc.ClientConn).Close()
+}
+
+// Ping 根据连接状态校验连接，已关闭或连接失败时返回ErrNotReady，空闲时触发重连
+func Ping(v interface{}) error {
+	cc := v.(*grpc.ClientConn)
+	switch cc.GetState() {
+	case connectivity.Shutdown, connectivity.TransientFailure:
+		return ErrNotReady
+	case connectivity.Idle:
+		cc.Connect()
+	}
+	return nil
+}
+
+// Pool *grpc.ClientConn连接池，实现grpc.ClientConnInterface，
+// 可直接传给生成的客户端代码，每次调用从池中取一条连接
+type Pool struct {
+	*pool.ChannelPool
+}
+
+var _ grpc.ClientConnInterface = (*Pool)(nil)
+
+// New 创建到target的连接池，cfg中未设置的Factory、Close、Ping使用本包提供的方法
+func New(cfg *pool.PoolConfig, target string, opts ...grpc.DialOption) *Pool {
+	if cfg.Factory == nil {
+		cfg.Factory = Factory(target, opts...)
+	}
+	if cfg.Close == nil {
+		cfg.Close = Close
+	}
+	if cfg.Ping == nil {
+		cfg.Ping = Ping
+	}
+	return &Pool{pool.New(cfg)}
+}
+
+// Conn 从池中取一条*grpc.ClientConn，用完后需调用Put或Close
+func (p *Pool) Conn(ctx context.Context) (*grpc.ClientConn, error) {
+	v, err := p.GetContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return v.(*grpc.ClientConn), nil
+}
+
+// Invoke 实现grpc.ClientConnInterface，返回Unavailable时关闭所用的连接
+func (p *Pool) Invoke(ctx context.Context, method string, args, reply interface{}, opts ...grpc.CallOption) error {
+	cc, err := p.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	err = cc.Invoke(ctx, method, args, reply, opts...)
+	p.release(cc, err)
+	return err
+}
+
+// NewStream 实现grpc.ClientConnInterface
+// ClientConn支持多路复用，创建流后连接即放回池中，流的生命周期不占用连接
+func (p *Pool) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	cc, err := p.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := cc.NewStream(ctx, desc, method, opts...)
+	p.release(cc, err)
+	return stream, err
+}
+
+// release 调用出错且连接不可用时关闭连接，否则放回池中
+func (p *Pool) release(cc *grpc.ClientConn, err error) {
+	if status.Code(err) == codes.Unavailable {
+		p.Close(cc)
+		return
+	}
+	p.Put(cc)
+}
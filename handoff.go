@@ -0,0 +1,78 @@
+package pool
+
+import (
+	"net"
+	"os"
+	"sync"
+)
+
+// fileConn 能导出文件描述符的连接，如*net.TCPConn、*net.UnixConn
+type fileConn interface {
+	File() (*os.File, error)
+}
+
+// ExportIdle 取出所有能导出文件描述符的空闲net.Conn，返回复制的文件并以CloseHandoff关闭本进程中的连接，
+// 用于平滑升级时将预热的连接交给新进程，参见SendFiles、ImportConns及InheritFactory。
+// TLS等在用户态保存会话状态的连接无法交接，保留在连接池中
+func (c *ChannelPool) ExportIdle() ([]*os.File, error) {
+	var (
+		files    []*os.File
+		firstErr error
+	)
+	c.filterIdle(func(conn interface{}, _ ConnInfo) bool {
+		if dc, ok := conn.(*DeadlineConn); ok {
+			conn = dc.Unwrap()
+		}
+		fc, ok := conn.(fileConn)
+		if !ok {
+			return false
+		}
+		f, err := fc.File()
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			return false
+		}
+		files = append(files, f)
+		return true
+	}, CloseHandoff)
+	return files, firstErr
+}
+
+// ImportConns 将ExportIdle导出的文件还原为net.Conn，并关闭files
+func ImportConns(files []*os.File) ([]net.Conn, error) {
+	conns := make([]net.Conn, 0, len(files))
+	for i, f := range files {
+		conn, err := net.FileConn(f)
+		f.Close()
+		if err != nil {
+			for _, conn := range conns {
+				conn.Close()
+			}
+			for _, f := range files[i+1:] {
+				f.Close()
+			}
+			return nil, err
+		}
+		conns = append(conns, conn)
+	}
+	return conns, nil
+}
+
+// InheritFactory 返回先依次使用conns、用完后调用fallback的工厂方法，
+// 配合InitialCap为len(conns)使新进程的连接池直接预热为继承的连接
+func InheritFactory(conns []net.Conn, fallback Factory) Factory {
+	var mu sync.Mutex
+	return func() (interface{}, error) {
+		mu.Lock()
+		if n := len(conns); n > 0 {
+			conn := conns[n-1]
+			conns = conns[:n-1]
+			mu.Unlock()
+			return conn, nil
+		}
+		mu.Unlock()
+		return fallback()
+	}
+}
@@ -0,0 +1,106 @@
+//go:build unix && !poolnostats
+
+package pool_test
+
+import (
+	"bufio"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/hms58/pool"
+)
+
+// unixPair 返回一对相连的unix socket
+func unixPair(t *testing.T) (*net.UnixConn, *net.UnixConn) {
+	fds, err := syscall.Socketpair(syscall.AF_UNIX, syscall.SOCK_STREAM, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pair := make([]*net.UnixConn, 2)
+	for i, fd := range fds {
+		f := os.NewFile(uintptr(fd), "socketpair")
+		conn, err := net.FileConn(f)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		pair[i] = conn.(*net.UnixConn)
+	}
+	return pair[0], pair[1]
+}
+
+func TestHandoff(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	lines := make(chan string, 2)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				line, _ := bufio.NewReader(conn).ReadString('\n')
+				lines <- line
+			}()
+		}
+	}()
+
+	old := pool.New(&pool.PoolConfig{
+		MaxCap:     2,
+		InitialCap: 2,
+		Factory:    pool.TCPFactory(l.Addr().String()),
+		Close:      pool.CloseNetConn,
+	})
+	defer old.Release()
+
+	files, err := old.ExportIdle()
+	if err != nil || len(files) != 2 {
+		t.Fatalf("ExportIdle() = %d files, %v", len(files), err)
+	}
+	if old.Len() != 0 || old.Stats().Closes[pool.CloseHandoff] != 2 {
+		t.Errorf("old pool Len() = %d, handoff closes = %d", old.Len(), old.Stats().Closes[pool.CloseHandoff])
+	}
+
+	// 经unix socket交给新进程
+	a, b := unixPair(t)
+	defer a.Close()
+	defer b.Close()
+	go pool.SendFiles(a, files)
+	received, err := pool.ReceiveFiles(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conns, err := pool.ImportConns(received)
+	if err != nil || len(conns) != 2 {
+		t.Fatalf("ImportConns() = %d conns, %v", len(conns), err)
+	}
+
+	dials := 0
+	p := pool.New(&pool.PoolConfig{
+		MaxCap:     2,
+		InitialCap: len(conns),
+		Factory: pool.InheritFactory(conns, func() (interface{}, error) {
+			dials++
+			return net.Dial("tcp", l.Addr().String())
+		}),
+		Close: pool.CloseNetConn,
+	})
+	defer p.Release()
+	if p.Len() != 2 || dials != 0 {
+		t.Fatalf("new pool Len() = %d, dials = %d, want 2 inherited conns", p.Len(), dials)
+	}
+
+	v, _ := p.Get()
+	v.(net.Conn).Write([]byte("hello\n"))
+	if line := <-lines; line != "hello\n" {
+		t.Errorf("server read %q through inherited conn", line)
+	}
+	p.Put(v)
+}
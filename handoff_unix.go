@@ -0,0 +1,91 @@
+//go:build unix
+
+package pool
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"os"
+	"syscall"
+)
+
+// maxFilesPerMsg 单条消息携带的文件描述符数，低于Linux的SCM_MAX_FD(253)
+const maxFilesPerMsg = 200
+
+// SendFiles 通过unix socket以SCM_RIGHTS发送files，全部发送后关闭files
+// 每条消息的数据部分为本条携带的描述符数，最后发送一条数量为0的消息表示结束
+func SendFiles(uc *net.UnixConn, files []*os.File) error {
+	defer func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}()
+	for rest := files; ; {
+		n := len(rest)
+		if n > maxFilesPerMsg {
+			n = maxFilesPerMsg
+		}
+		fds := make([]int, n)
+		for i, f := range rest[:n] {
+			fds[i] = int(f.Fd())
+		}
+		var head [4]byte
+		binary.BigEndian.PutUint32(head[:], uint32(n))
+		var oob []byte
+		if n > 0 {
+			oob = syscall.UnixRights(fds...)
+		}
+		if _, _, err := uc.WriteMsgUnix(head[:], oob, nil); err != nil {
+			return err
+		}
+		if n == 0 {
+			return nil
+		}
+		rest = rest[n:]
+	}
+}
+
+// ReceiveFiles 接收SendFiles发送的文件
+func ReceiveFiles(uc *net.UnixConn) ([]*os.File, error) {
+	var files []*os.File
+	fail := func(err error) ([]*os.File, error) {
+		for _, f := range files {
+			f.Close()
+		}
+		return nil, err
+	}
+	oob := make([]byte, syscall.CmsgSpace(maxFilesPerMsg*4))
+	for {
+		var head [4]byte
+		n, oobn, _, _, err := uc.ReadMsgUnix(head[:], oob)
+		if err != nil {
+			return fail(err)
+		}
+		if n != len(head) {
+			return fail(errors.New("pool: short handoff message"))
+		}
+		count := int(binary.BigEndian.Uint32(head[:]))
+		if count == 0 {
+			return files, nil
+		}
+		msgs, err := syscall.ParseSocketControlMessage(oob[:oobn])
+		if err != nil {
+			return fail(err)
+		}
+		var fds []int
+		for i := range msgs {
+			rights, err := syscall.ParseUnixRights(&msgs[i])
+			if err != nil {
+				return fail(err)
+			}
+			fds = append(fds, rights...)
+		}
+		for _, fd := range fds {
+			files = append(files, os.NewFile(uintptr(fd), "pool-handoff"))
+		}
+		if len(fds) != count {
+			return fail(errors.New("pool: handoff message lost file descriptors"))
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package pool
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// HealthThresholds Healthy判断连接池是否健康的阈值，为0的项不检查
+type HealthThresholds struct {
+	//拨号失败率上限，取值0~1，按相邻两次Healthy调用之间的拨号计算，看门狗按相邻两次检查之间的拨号单独计算
+	MaxDialErrorRate float64
+	//等待连接的goroutine数上限
+	MaxWaiters int
+	//连接数持续处于上限的最长时间
+	MaxSaturation time.Duration
+}
+
+// dialWindow 计算拨号失败率的窗口，记录上次检查时的累计拨号数，各检查方分别持有，互不重置
+type dialWindow struct {
+	mu       sync.Mutex
+	attempts uint64
+	failures uint64
+}
+
+// advance 返回上次advance以来的拨号数及失败数
+func (w *dialWindow) advance(attempts, failures uint64) (uint64, uint64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	da, df := attempts-w.attempts, failures-w.failures
+	w.attempts, w.failures = attempts, failures
+	return da, df
+}
+
+// Healthy 按PoolConfig.Health的阈值及连接池状态、新建连接错误缓存判断连接池是否健康，
+// 不健康时返回原因，可直接用于就绪探针
+func (c *ChannelPool) Healthy() (bool, []string) {
+	return c.healthy(&c.healthWindow)
+}
+
+// healthy 同Healthy，拨号失败率按window上次检查以来的拨号计算
+func (c *ChannelPool) healthy(window *dialWindow) (bool, []string) {
+	var reasons []string
+	if state := c.State(); state != StateOpen {
+		reasons = append(reasons, "pool is "+state.String())
+	}
+
+	now := c.now()
+	c.mu.Lock()
+	if c.dialErr != nil && now.Before(c.dialErrUntil) {
+		reasons = append(reasons, fmt.Sprintf("dial circuit open until %s: %v", c.dialErrUntil.Format(time.RFC3339), c.dialErr))
+	}
+	c.mu.Unlock()
+
+	h := c.health
+	// 先读失败数，避免两次读取之间的拨号使失败数大于拨号数
+	failures := atomic.LoadUint64(&c.dialFailures)
+	attempts := atomic.LoadUint64(&c.dialAttempts)
+	attempts, failures = window.advance(attempts, failures)
+	if h.MaxDialErrorRate > 0 && attempts > 0 {
+		if rate := float64(failures) / float64(attempts); rate > h.MaxDialErrorRate {
+			reasons = append(reasons, fmt.Sprintf("dial error rate %.2f exceeds %.2f", rate, h.MaxDialErrorRate))
+		}
+	}
+	if waiters := int(atomic.LoadInt32(&c.waiters)); h.MaxWaiters > 0 && waiters > h.MaxWaiters {
+		reasons = append(reasons, fmt.Sprintf("%d waiters exceeds %d", waiters, h.MaxWaiters))
+	}
+	// 仍有空闲连接或名额时不再饱和
+	if limit := c.limit(); limit == 0 || atomic.LoadInt32(&c.numOpen) < limit || c.Len() > 0 {
+		c.clearSaturated()
+	}
+	if since := atomic.LoadInt64(&c.saturatedAt); h.MaxSaturation > 0 && since != 0 {
+		if d := now.Sub(time.Unix(0, since)); d > h.MaxSaturation {
+			reasons = append(reasons, fmt.Sprintf("saturated for %v exceeds %v", d.Round(time.Millisecond), h.MaxSaturation))
+		}
+	}
+	return len(reasons) == 0, reasons
+}
+
+// markSaturated 记录连接数达到上限的起始时间
+func (c *ChannelPool) markSaturated() {
+	if atomic.LoadInt64(&c.saturatedAt) == 0 {
+		atomic.CompareAndSwapInt64(&c.saturatedAt, 0, c.now().UnixNano())
+	}
+}
+
+// clearSaturated 无需等待即取到连接，连接池不再饱和
+func (c *ChannelPool) clearSaturated() {
+	if atomic.LoadInt64(&c.saturatedAt) != 0 {
+		atomic.StoreInt64(&c.saturatedAt, 0)
+	}
+}
@@ -0,0 +1,73 @@
+package pool
+
+import "context"
+
+// Op 可被拦截的连接池操作
+type Op int
+
+const (
+	// OpGet 取出连接，包括GetContext、GetFor、GetQoS等阻塞取连接的方法，conn为nil
+	OpGet Op = iota
+	// OpPut 放回连接
+	OpPut
+	// OpDial 调用工厂方法新建连接，conn为nil
+	OpDial
+	// OpClose 调用方关闭连接
+	OpClose
+)
+
+var opNames = [...]string{
+	OpGet:   "get",
+	OpPut:   "put",
+	OpDial:  "dial",
+	OpClose: "close",
+}
+
+func (op Op) String() string {
+	if op < 0 || int(op) >= len(opNames) {
+		return "unknown"
+	}
+	return opNames[op]
+}
+
+// Invoker 执行被拦截的操作，返回取出或新建的连接，OpPut和OpClose只返回错误
+type Invoker func(ctx context.Context, conn interface{}) (interface{}, error)
+
+// InterceptorFunc 拦截连接池操作的中间件，调用next继续执行，可修改传给next的ctx和conn或直接返回
+type InterceptorFunc func(ctx context.Context, op Op, conn interface{}, next Invoker) (interface{}, error)
+
+// Use 追加拦截器，先追加的在外层，可在运行时调用
+// 使用poolnostats构建时拦截器不会被调用
+func (c *ChannelPool) Use(interceptor InterceptorFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	chain, _ := c.interceptors.Load().([]InterceptorFunc)
+	next := make([]InterceptorFunc, len(chain), len(chain)+1)
+	copy(next, chain)
+	c.interceptors.Store(append(next, interceptor))
+}
+
+// intercepted 是否有拦截器，Get/Put据此跳过创建闭包；使用poolnostats构建时总是false
+func (c *ChannelPool) intercepted() bool {
+	if !instrumented {
+		return false
+	}
+	chain, _ := c.interceptors.Load().([]InterceptorFunc)
+	return len(chain) > 0
+}
+
+// intercept 经过拦截器链执行final，没有拦截器时直接执行
+func (c *ChannelPool) intercept(ctx context.Context, op Op, conn interface{}, final Invoker) (interface{}, error) {
+	chain, _ := c.interceptors.Load().([]InterceptorFunc)
+	if len(chain) == 0 {
+		return final(ctx, conn)
+	}
+	next := final
+	for i := len(chain) - 1; i >= 0; i-- {
+		interceptor, inner := chain[i], next
+		next = func(ctx context.Context, conn interface{}) (interface{}, error) {
+			return interceptor(ctx, op, conn, inner)
+		}
+	}
+	return next(ctx, conn)
+}
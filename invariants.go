@@ -0,0 +1,122 @@
+package pool
+
+import (
+	"fmt"
+	"sync"
+)
+
+// invariants 开启CheckInvariants时维护空闲及借出连接的集合，在每次存取后校验连接池的内部不变式
+type invariants struct {
+	mu sync.Mutex
+	// 空闲连接队列中的连接
+	idle map[*idleConn]bool
+	// 借出的连接，无法追踪的连接不在其中
+	busy   map[interface{}]bool
+	report func(error)
+}
+
+func newInvariants(report func(error)) *invariants {
+	return &invariants{
+		idle:   make(map[*idleConn]bool),
+		busy:   make(map[interface{}]bool),
+		report: report,
+	}
+}
+
+// violated 报告违反的不变式，未配置回调时panic
+func (inv *invariants) violated(err error) {
+	if inv.report != nil {
+		inv.report(err)
+		return
+	}
+	panic(err)
+}
+
+// checkedQueue 在存取空闲连接时同步更新invariants的idleQueue
+type checkedQueue struct {
+	idleQueue
+	c *ChannelPool
+}
+
+func (q checkedQueue) push(wrapConn *idleConn) bool {
+	inv := q.c.inv
+	inv.mu.Lock()
+	var err error
+	switch {
+	case inv.idle[wrapConn]:
+		err = fmt.Errorf("%w: conn %d pushed to the idle queue twice", ErrInvariantViolated, wrapConn.id)
+	case wrapConn.shard != nil && inv.busy[trackKey(wrapConn.conn)]:
+		err = fmt.Errorf("%w: conn %d pushed to the idle queue while checked out", ErrInvariantViolated, wrapConn.id)
+	}
+	ok := q.idleQueue.push(wrapConn)
+	if ok {
+		inv.idle[wrapConn] = true
+		if err == nil {
+			err = q.c.checkCountLocked()
+		}
+	}
+	inv.mu.Unlock()
+	if err != nil {
+		inv.violated(err)
+	}
+	return ok
+}
+
+func (q checkedQueue) pop() *idleConn {
+	inv := q.c.inv
+	inv.mu.Lock()
+	wrapConn := q.idleQueue.pop()
+	delete(inv.idle, wrapConn)
+	inv.mu.Unlock()
+	return wrapConn
+}
+
+// checkLend 借出前校验连接不在空闲队列中且未被借出
+func (c *ChannelPool) checkLend(wrapConn *idleConn) {
+	inv := c.inv
+	if inv == nil || wrapConn.shard == nil {
+		return
+	}
+	inv.mu.Lock()
+	var err error
+	key := trackKey(wrapConn.conn)
+	switch {
+	case inv.busy[key]:
+		err = fmt.Errorf("%w: conn %d handed out twice", ErrInvariantViolated, wrapConn.id)
+	case inv.idle[wrapConn]:
+		err = fmt.Errorf("%w: conn %d handed out while in the idle queue", ErrInvariantViolated, wrapConn.id)
+	}
+	inv.busy[key] = true
+	if err == nil {
+		err = c.checkCountLocked()
+	}
+	inv.mu.Unlock()
+	if err != nil {
+		inv.violated(err)
+	}
+}
+
+// checkGiveBack 归还时从借出集合中移除
+func (c *ChannelPool) checkGiveBack(conn interface{}) {
+	if inv := c.inv; inv != nil {
+		inv.mu.Lock()
+		delete(inv.busy, trackKey(conn))
+		inv.mu.Unlock()
+	}
+}
+
+// checkClosedGet 连接池在Get开始前已关闭却借出了连接时报告
+func (c *ChannelPool) checkClosedGet(closedBefore bool, conn interface{}) {
+	if c.inv != nil && closedBefore {
+		c.inv.violated(fmt.Errorf("%w: conn %v handed out by a closed pool", ErrInvariantViolated, conn))
+	}
+}
+
+// checkCountLocked 校验空闲加借出的连接数不超过上限，调用方持有inv.mu
+func (c *ChannelPool) checkCountLocked() error {
+	inv := c.inv
+	if limit := int(c.limit()); limit > 0 && len(inv.idle)+len(inv.busy) > limit {
+		return fmt.Errorf("%w: %d idle + %d busy conns exceed the limit %d", ErrInvariantViolated, len(inv.idle), len(inv.busy), limit)
+	}
+	return nil
+}
@@ -0,0 +1,14 @@
+package pool
+
+import "time"
+
+// keepAliveLoop 每隔interval对空闲时间超过interval的连接调用probe，探测失败的连接被关闭
+// 探测逐条进行，被探测的连接暂时取出，其余空闲连接仍可借出；连接池Release后自动停止
+func (c *ChannelPool) keepAliveLoop(probe func(interface{}) error, interval time.Duration) {
+	c.every(interval, func() {
+		now := c.now()
+		c.filterIdle(func(conn interface{}, info ConnInfo) bool {
+			return now.Sub(info.LastUsed) >= interval && probe(conn) != nil
+		}, CloseKeepAlive)
+	})
+}
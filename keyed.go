@@ -0,0 +1,242 @@
+package pool
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// KeyedPool 按key划分的连接池，如每个后端地址一个key，各key使用独立的连接池，
+// 首次Get某个key时按newConfig(key)创建，统计信息既可汇总也可按key查看
+// 连接需可作为map的key，以便放回时找到所属的连接池
+type KeyedPool struct {
+	newConfig func(key string) *PoolConfig
+
+	mu     sync.RWMutex
+	pools  map[string]*ChannelPool
+	closed bool
+
+	ownersMu sync.Mutex
+	//连接到所属key的映射，Get时记录，连接关闭时删除，使Put及Close不必逐个key查找
+	owners map[interface{}]string
+}
+
+// NewKeyedPool 初始化按key划分的连接池，newConfig返回各key的连接池配置
+func NewKeyedPool(newConfig func(key string) *PoolConfig) *KeyedPool {
+	return &KeyedPool{
+		newConfig: newConfig,
+		pools:     make(map[string]*ChannelPool),
+		owners:    make(map[interface{}]string),
+	}
+}
+
+// pool 返回key对应的连接池，不存在时创建
+func (p *KeyedPool) pool(key string) (*ChannelPool, error) {
+	p.mu.RLock()
+	kp, ok := p.pools[key]
+	closed := p.closed
+	p.mu.RUnlock()
+	if ok {
+		return kp, nil
+	}
+	if closed {
+		return nil, ErrClosed
+	}
+
+	// 创建连接池可能同步预建连接，不持有锁
+	created := newPool(p.config(key), newChanQueue)
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		created.Release()
+		return nil, ErrClosed
+	}
+	if kp, ok = p.pools[key]; !ok {
+		kp = created
+		p.pools[key] = kp
+	}
+	p.mu.Unlock()
+	if kp != created {
+		created.Release()
+	}
+	return kp, nil
+}
+
+// config 返回key对应的连接池配置，连接关闭时同时删除其所属key的记录
+func (p *KeyedPool) config(key string) *PoolConfig {
+	poolConfig := *p.newConfig(key)
+	onClose := poolConfig.OnClose
+	poolConfig.OnClose = func(conn interface{}, reason CloseReason) {
+		p.forget(conn, key)
+		if onClose != nil {
+			onClose(conn, reason)
+		}
+	}
+	return &poolConfig
+}
+
+// Get 从key对应的连接池取一个连接
+func (p *KeyedPool) Get(ctx context.Context, key string) (interface{}, error) {
+	kp, err := p.pool(key)
+	if err != nil {
+		return nil, getErr(err)
+	}
+	conn, err := kp.GetContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	p.remember(conn, key)
+	return conn, nil
+}
+
+// remember 记录连接所属的key
+func (p *KeyedPool) remember(conn interface{}, key string) {
+	if !trackable(conn) {
+		return
+	}
+	ck := trackKey(conn)
+	p.ownersMu.Lock()
+	p.owners[ck] = key
+	p.ownersMu.Unlock()
+}
+
+// forget 连接关闭时删除其记录，记录已指向其他key时保留
+func (p *KeyedPool) forget(conn interface{}, key string) {
+	if !trackable(conn) {
+		return
+	}
+	ck := trackKey(conn)
+	p.ownersMu.Lock()
+	if p.owners[ck] == key {
+		delete(p.owners, ck)
+	}
+	p.ownersMu.Unlock()
+}
+
+// owner 返回连接所属的连接池
+func (p *KeyedPool) owner(conn interface{}) *ChannelPool {
+	_, kp := p.ownerKey(conn)
+	return kp
+}
+
+// ownerKey 返回连接所属的key及连接池，不属于任何key时返回nil
+// 优先按Get时的记录查找，未记录的连接（如经Pool(key)直接取出）才逐个key查找
+func (p *KeyedPool) ownerKey(conn interface{}) (string, *ChannelPool) {
+	if !trackable(conn) {
+		return "", nil
+	}
+	ck := trackKey(conn)
+	p.ownersMu.Lock()
+	key, ok := p.owners[ck]
+	p.ownersMu.Unlock()
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if ok {
+		if kp := p.pools[key]; kp != nil && kp.owns(conn) {
+			return key, kp
+		}
+	}
+	for key, kp := range p.pools {
+		if kp.owns(conn) {
+			p.remember(conn, key)
+			return key, kp
+		}
+	}
+	return "", nil
+}
+
+// Put 将连接放回其所属的连接池
+func (p *KeyedPool) Put(conn interface{}) error {
+	kp := p.owner(conn)
+	if kp == nil {
+		return ErrUnknownConn
+	}
+	return kp.Put(conn)
+}
+
+// Close 关闭连接并从其所属的连接池中移除
+func (p *KeyedPool) Close(conn interface{}) error {
+	kp := p.owner(conn)
+	if kp == nil {
+		return ErrUnknownConn
+	}
+	return kp.Close(conn)
+}
+
+// Keys 返回已创建连接池的key，按字典序排列
+func (p *KeyedPool) Keys() []string {
+	p.mu.RLock()
+	keys := make([]string, 0, len(p.pools))
+	for key := range p.pools {
+		keys = append(keys, key)
+	}
+	p.mu.RUnlock()
+	sort.Strings(keys)
+	return keys
+}
+
+// Pool 返回key对应的连接池，尚未创建时返回nil
+func (p *KeyedPool) Pool(key string) *ChannelPool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if kp, ok := p.pools[key]; ok {
+		return kp
+	}
+	return nil
+}
+
+// Stats 返回所有key汇总的统计信息，高低水位为各key之和
+func (p *KeyedPool) Stats() *Stats {
+	stats := &Stats{}
+	for _, s := range p.KeyStats() {
+		stats.merge(s)
+	}
+	return stats
+}
+
+// KeyStats 按key返回各连接池的统计信息，用于诊断后端之间的倾斜
+func (p *KeyedPool) KeyStats() map[string]*Stats {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	stats := make(map[string]*Stats, len(p.pools))
+	for key, kp := range p.pools {
+		stats[key] = kp.Stats()
+	}
+	return stats
+}
+
+// DrainKey 排空key对应的连接池：不再为该key发放连接（Get返回ErrDraining），关闭其空闲连接，
+// 等待借出的连接全部放回或关闭后移除并释放该连接池，用于滚动维护单个后端而不影响其他key
+// ctx结束时返回ctx.Err()，该key保持排空状态，可再次调用DrainKey继续等待；移除后再次Get该key时重新创建连接池
+func (p *KeyedPool) DrainKey(ctx context.Context, key string) error {
+	p.mu.RLock()
+	kp, ok := p.pools[key]
+	p.mu.RUnlock()
+	if !ok {
+		return nil
+	}
+
+	kp.Drain()
+	if err := kp.Wait(ctx); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	if p.pools[key] == kp {
+		delete(p.pools, key)
+	}
+	p.mu.Unlock()
+	kp.Release()
+	return nil
+}
+
+// Release 释放所有key的连接池，之后的Get返回ErrClosed
+func (p *KeyedPool) Release() {
+	p.mu.Lock()
+	p.closed = true
+	pools := p.pools
+	p.mu.Unlock()
+	for _, kp := range pools {
+		kp.Release()
+	}
+}
@@ -0,0 +1,93 @@
+package pool_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/hms58/pool"
+)
+
+func TestKeyedPoolDrainKey(t *testing.T) {
+	p := pool.NewKeyedPool(func(string) *pool.PoolConfig {
+		return &pool.PoolConfig{
+			MaxCap:  2,
+			Factory: func() (interface{}, error) { return &testConn{}, nil },
+			Close: func(v interface{}) error {
+				v.(*testConn).closed = true
+				return nil
+			},
+		}
+	})
+	defer p.Release()
+	ctx := context.Background()
+
+	a, _ := p.Get(ctx, "a")
+	b, _ := p.Get(ctx, "b")
+	p.Put(b)
+
+	short, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err := p.DrainKey(short, "a"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("DrainKey() with busy conn err = %v, want DeadlineExceeded", err)
+	}
+	if _, err := p.Get(ctx, "a"); !errors.Is(err, pool.ErrDraining) {
+		t.Errorf("Get() on draining key err = %v, want ErrDraining", err)
+	}
+	if c, err := p.Get(ctx, "b"); err != nil || c != b {
+		t.Errorf("Get(b) during drain of a = %v, %v, want idle b conn", c, err)
+	}
+
+	p.Put(a)
+	if !a.(*testConn).closed {
+		t.Errorf("conn returned to draining key was not closed")
+	}
+	if err := p.DrainKey(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if p.Pool("a") != nil || !reflect.DeepEqual(p.Keys(), []string{"b"}) {
+		t.Errorf("Keys() after DrainKey = %v, want [b]", p.Keys())
+	}
+}
+
+func TestKeyedPoolOwners(t *testing.T) {
+	var closed []string
+	p := pool.NewKeyedPool(func(key string) *pool.PoolConfig {
+		return &pool.PoolConfig{
+			MaxCap:  2,
+			Factory: func() (interface{}, error) { return &testConn{}, nil },
+			OnClose: func(conn interface{}, reason pool.CloseReason) {
+				closed = append(closed, key)
+			},
+		}
+	})
+	defer p.Release()
+	ctx := context.Background()
+
+	a, _ := p.Get(ctx, "a")
+	b, _ := p.Get(ctx, "b")
+	p.Get(ctx, "c")
+	// 绕过KeyedPool.Get取出的连接没有记录，仍可放回
+	c, _ := p.Pool("c").Get()
+	for _, conn := range []interface{}{a, b, c} {
+		if err := p.Put(conn); err != nil {
+			t.Errorf("Put() err = %v", err)
+		}
+	}
+	if p.Pool("a").Len() != 1 || p.Pool("b").Len() != 1 || p.Pool("c").Len() != 1 {
+		t.Errorf("conns not returned to their own key's pool")
+	}
+
+	a, _ = p.Get(ctx, "a")
+	if err := p.Close(a); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(closed, []string{"a"}) {
+		t.Errorf("OnClose calls = %v, want [a]", closed)
+	}
+	if err := p.Put(a); err != pool.ErrUnknownConn {
+		t.Errorf("Put() of closed conn err = %v, want ErrUnknownConn", err)
+	}
+}
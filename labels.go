@@ -0,0 +1,62 @@
+package pool
+
+import (
+	"log/slog"
+	"sort"
+	"strings"
+)
+
+// labelAttrs 连接池名称及标签属性，标签按key排序
+func (c *ChannelPool) labelAttrs() []any {
+	var attrs []any
+	if c.name != "" {
+		attrs = append(attrs, slog.String("pool", c.name))
+	}
+	if len(c.labels) > 0 {
+		keys := sortedKeys(c.labels)
+		labels := make([]any, 0, len(keys))
+		for _, k := range keys {
+			labels = append(labels, slog.String(k, c.labels[k]))
+		}
+		attrs = append(attrs, slog.Group("labels", labels...))
+	}
+	return attrs
+}
+
+// labelPrefix logf输出的前缀，如 "[cache region=us] "，未配置名称及标签时为空，%已转义
+func (c *ChannelPool) labelPrefix() string {
+	if c.name == "" && len(c.labels) == 0 {
+		return ""
+	}
+	parts := make([]string, 0, len(c.labels)+1)
+	if c.name != "" {
+		parts = append(parts, c.name)
+	}
+	for _, k := range sortedKeys(c.labels) {
+		parts = append(parts, k+"="+c.labels[k])
+	}
+	prefix := "[" + strings.Join(parts, " ") + "] "
+	return strings.Replace(prefix, "%", "%%", -1)
+}
+
+// sortedKeys 返回按字典序排列的key
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// telemetryLabels 连接池标签及名称，名称的标签名为pool
+func (c *ChannelPool) telemetryLabels() map[string]string {
+	labels := make(map[string]string, len(c.labels)+1)
+	for k, v := range c.labels {
+		labels[k] = v
+	}
+	if c.name != "" {
+		labels["pool"] = c.name
+	}
+	return labels
+}
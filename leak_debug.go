@@ -0,0 +1,67 @@
+//go:build pooldebug
+
+package pool
+
+import (
+	"log/slog"
+	"reflect"
+	"runtime"
+	"runtime/debug"
+)
+
+// trackKey 调试模式下指针类型的连接以地址作为key，借出期间连接池不持有连接的强引用，
+// 连接未归还就被回收时由finalizer报告泄漏
+func trackKey(conn interface{}) interface{} {
+	if v := reflect.ValueOf(conn); v.Kind() == reflect.Ptr {
+		return v.Pointer()
+	}
+	return conn
+}
+
+// onLend 记录借出时的调用栈，并在连接被回收时报告泄漏
+func (c *ChannelPool) onLend(wrapConn *idleConn) {
+	conn := wrapConn.conn
+	if reflect.ValueOf(conn).Kind() != reflect.Ptr {
+		return
+	}
+	key := trackKey(conn)
+	stack := string(debug.Stack())
+	wrapConn.conn = nil
+	runtime.SetFinalizer(conn, func(interface{}) {
+		c.leaked(key, stack)
+	})
+}
+
+// onGiveBack 连接归还时取消finalizer，恢复连接池对连接的引用
+func (c *ChannelPool) onGiveBack(wrapConn *idleConn, conn interface{}) {
+	if wrapConn.conn == nil {
+		runtime.SetFinalizer(conn, nil)
+		wrapConn.conn = conn
+	}
+}
+
+// leaked 借出的连接未归还就被回收，停止追踪并打印借出时的调用栈
+func (c *ChannelPool) leaked(key interface{}, stack string) {
+	s := c.tracked.shard(key)
+	s.mu.Lock()
+	wrapConn, ok := s.conns[key]
+	if ok {
+		delete(s.conns, key)
+	}
+	s.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	if owner := wrapConn.owner; owner != "" {
+		c.releaseOwner(owner, false)
+	}
+	if wrapConn.children != nil {
+		wrapConn.children.release()
+	}
+	attrs := append(c.connAttrs(wrapConn), slog.String("owner", wrapConn.owner), slog.String("stack", stack))
+	c.pushBusy(wrapConn)
+	c.releaseBusy()
+	c.unreserve()
+	c.logAttrs(slog.LevelError, "connection garbage collected without Put or Close", attrs...)
+}
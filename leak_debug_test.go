@@ -0,0 +1,45 @@
+//go:build pooldebug
+
+package pool_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hms58/pool"
+)
+
+func TestLeakDetection(t *testing.T) {
+	var buf syncBuffer
+	p := pool.New(&pool.PoolConfig{
+		MaxCap:  2,
+		Slog:    slog.New(slog.NewJSONHandler(&buf, nil)),
+		Factory: func() (interface{}, error) { return &testConn{}, nil },
+	})
+	defer p.Release()
+
+	func() {
+		p.Get()
+	}()
+	var leak struct {
+		Stack string `json:"stack"`
+	}
+	for i := 0; i < 10 && leak.Stack == ""; i++ {
+		runtime.GC()
+		time.Sleep(10 * time.Millisecond)
+		for _, line := range bytes.Split([]byte(buf.String()), []byte("\n")) {
+			if json.Unmarshal(line, &leak) == nil && leak.Stack != "" {
+				break
+			}
+		}
+	}
+
+	if !strings.Contains(leak.Stack, "TestLeakDetection") {
+		t.Errorf("leak not reported with checkout stack, log: %q", buf.String())
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !pooldebug
+
+package pool
+
+// trackKey 返回追踪连接时使用的key
+func trackKey(conn interface{}) interface{} {
+	return conn
+}
+
+// onLend 仅在pooldebug构建下记录借出调用栈
+func (c *ChannelPool) onLend(wrapConn *idleConn) {}
+
+// onGiveBack 仅在pooldebug构建下恢复连接引用
+func (c *ChannelPool) onGiveBack(wrapConn *idleConn, conn interface{}) {}
@@ -0,0 +1,102 @@
+package pool
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Lease 带有效期的连接租约，到期前未续约或归还时连接池强制关闭连接并回收名额
+type Lease struct {
+	p    *ChannelPool
+	conn interface{}
+
+	mu   sync.Mutex
+	stop func()
+	//每次续约加一，到期回调据此忽略已被续约取代的定时器
+	gen uint64
+	//已归还或已回收
+	done bool
+}
+
+// GetLease 取出一个连接并返回有效期为ttl的租约
+func (c *ChannelPool) GetLease(ctx context.Context, ttl time.Duration) (*Lease, error) {
+	conn, err := c.GetContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	l := &Lease{p: c, conn: conn}
+	l.mu.Lock()
+	l.schedule(ttl)
+	l.mu.Unlock()
+	return l, nil
+}
+
+// schedule 按连接池的时钟在ttl后回收租约，取消此前的定时器，需持有mu
+func (l *Lease) schedule(ttl time.Duration) {
+	if l.stop != nil {
+		l.stop()
+	}
+	l.gen++
+	gen := l.gen
+	l.stop = l.p.timeSource.AfterFunc(ttl, func() { l.revoke(gen) })
+}
+
+// Conn 返回租约对应的连接，租约失效后不可再使用
+func (l *Lease) Conn() interface{} {
+	return l.conn
+}
+
+// Renew 将租约的有效期重置为ttl，租约已失效时返回ErrLeaseExpired
+func (l *Lease) Renew(ttl time.Duration) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.done {
+		return ErrLeaseExpired
+	}
+	l.schedule(ttl)
+	return nil
+}
+
+// Put 归还连接并结束租约
+func (l *Lease) Put() error {
+	if !l.finish() {
+		return ErrLeaseExpired
+	}
+	return l.p.Put(l.conn)
+}
+
+// Close 关闭连接并结束租约
+func (l *Lease) Close() error {
+	if !l.finish() {
+		return ErrLeaseExpired
+	}
+	return l.p.Close(l.conn)
+}
+
+// finish 结束租约，租约已失效时返回false
+func (l *Lease) finish() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.done {
+		return false
+	}
+	l.done = true
+	l.stop()
+	return true
+}
+
+// revoke 租约到期时强制关闭连接，gen为定时器设置时的续约次数，
+// 已触发的定时器等待mu期间租约被续约时不回收
+func (l *Lease) revoke(gen uint64) {
+	l.mu.Lock()
+	if l.done || l.gen != gen {
+		l.mu.Unlock()
+		return
+	}
+	l.done = true
+	l.mu.Unlock()
+	l.p.logAttrs(slog.LevelWarn, "lease expired, force closing connection", slog.Uint64("conn_id", l.p.ConnID(l.conn)))
+	l.p.closeWith(l.conn, CloseLeaseExpired)
+}
@@ -0,0 +1,102 @@
+package pool
+
+import (
+	"log/slog"
+	"sync/atomic"
+)
+
+// markUsable 能清除不可用标记的连接，BrokenLinger探测成功后放回空闲连接
+type markUsable interface {
+	MarkUsable()
+}
+
+// lingerable 判断放回的不可用连接能否搁置等待探测，连接需能清除不可用标记
+func (c *ChannelPool) lingerable(conn interface{}) bool {
+	if c.brokenProbe == nil {
+		return false
+	}
+	_, ok := conn.(markUsable)
+	return ok
+}
+
+// linger 搁置放回的不可用连接，BrokenLinger到期后探测一次，成功则放回空闲连接，否则以CloseBroken关闭
+// 搁置期间连接仍占用名额，EvictWhere、ForceCloseAll及Drain、Release会一并关闭搁置的连接
+func (c *ChannelPool) linger(wrapConn *idleConn) {
+	// 先登记再启动定时器，保证到期回调总能认领该连接
+	c.lingerMu.Lock()
+	if c.lingering == nil {
+		c.lingering = make(map[*idleConn]func())
+	}
+	c.lingering[wrapConn] = nil
+	c.lingerMu.Unlock()
+
+	stop := c.timeSource.AfterFunc(c.brokenLinger, func() {
+		c.probeLingering(wrapConn)
+	})
+	c.lingerMu.Lock()
+	if _, ok := c.lingering[wrapConn]; ok {
+		c.lingering[wrapConn] = stop
+	}
+	c.lingerMu.Unlock()
+
+	// 登记期间连接池被排空或释放，不再等待探测
+	if c.getConns() == nil {
+		c.closeLingering(func(interface{}, ConnInfo) bool { return true }, CloseBroken)
+	}
+}
+
+// claimLingering 从搁置的连接中取出wrapConn，已被取出时返回false
+func (c *ChannelPool) claimLingering(wrapConn *idleConn) bool {
+	c.lingerMu.Lock()
+	defer c.lingerMu.Unlock()
+	_, ok := c.lingering[wrapConn]
+	if ok {
+		delete(c.lingering, wrapConn)
+	}
+	return ok
+}
+
+// probeLingering BrokenLinger到期后清除不可用标记并探测，成功则放回空闲连接
+func (c *ChannelPool) probeLingering(wrapConn *idleConn) {
+	if !c.claimLingering(wrapConn) {
+		return
+	}
+	if c.getConns() == nil {
+		c.discard(wrapConn, CloseBroken)
+		return
+	}
+
+	conn := wrapConn.conn
+	conn.(markUsable).MarkUsable()
+	if err := c.brokenProbe(conn); err != nil || isUnusable(conn) {
+		c.discard(wrapConn, CloseBroken)
+		return
+	}
+	if instrumented {
+		atomic.AddUint64(&c.counters.shard().recovered, 1)
+	}
+	c.logAttrs(slog.LevelInfo, "broken connection recovered", slog.Uint64("conn_id", wrapConn.id), slog.Duration("linger", c.brokenLinger))
+	c.putIdle(wrapConn)
+}
+
+// closeLingering 以reason关闭满足pred的搁置连接，返回关闭的连接数
+func (c *ChannelPool) closeLingering(pred func(conn interface{}, info ConnInfo) bool, reason CloseReason) int {
+	var victims []*idleConn
+	c.lingerMu.Lock()
+	for wrapConn, stop := range c.lingering {
+		if !pred(wrapConn.conn, wrapConn.lockedInfo()) {
+			continue
+		}
+		delete(c.lingering, wrapConn)
+		if stop != nil {
+			stop()
+		}
+		victims = append(victims, wrapConn)
+	}
+	c.lingerMu.Unlock()
+
+	for _, wrapConn := range victims {
+		c.discard(wrapConn, reason)
+	}
+	return len(victims)
+}
@@ -0,0 +1,132 @@
+package pool
+
+import (
+	"context"
+	"sync"
+)
+
+// MuxPool 多路复用连接池，一条连接可同时借给最多MaxStreams个使用方，
+// 适用于HTTP/2、gRPC等在单条连接上复用多个流的协议，所有连接的流都占满时新建连接
+type MuxPool struct {
+	p          *ChannelPool
+	maxStreams int
+
+	mu     sync.Mutex
+	active []*muxConn
+}
+
+// muxConn 正在被使用的连接及其流数
+type muxConn struct {
+	conn    interface{}
+	streams int
+	//已被标记为不可用，最后一个流归还时关闭
+	broken bool
+}
+
+// MuxConnStats 单条连接的流统计
+type MuxConnStats struct {
+	Conn    interface{}
+	Streams int
+}
+
+// NewMuxPool 初始化多路复用连接池，maxStreams为每条连接的最大并发流数，默认100
+func NewMuxPool(poolConfig *PoolConfig, maxStreams int) *MuxPool {
+	if maxStreams <= 0 {
+		maxStreams = 100
+	}
+	return &MuxPool{
+		p:          newPool(poolConfig, newChanQueue),
+		maxStreams: maxStreams,
+	}
+}
+
+// Get 取一条连接并占用一个流，优先选择流最少的连接
+func (m *MuxPool) Get(ctx context.Context) (interface{}, error) {
+	m.mu.Lock()
+	var best *muxConn
+	for _, mc := range m.active {
+		if !mc.broken && mc.streams < m.maxStreams && (best == nil || mc.streams < best.streams) {
+			best = mc
+		}
+	}
+	if best != nil {
+		best.streams++
+		m.mu.Unlock()
+		return best.conn, nil
+	}
+	m.mu.Unlock()
+
+	conn, err := m.p.GetContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	m.active = append(m.active, &muxConn{conn: conn, streams: 1})
+	m.mu.Unlock()
+	return conn, nil
+}
+
+// Put 归还一个流，连接上的流全部归还后放回连接池
+func (m *MuxPool) Put(conn interface{}) error {
+	return m.release(conn, false)
+}
+
+// Close 将连接标记为不可用，不再分配新的流，最后一个流归还时关闭
+func (m *MuxPool) Close(conn interface{}) error {
+	return m.release(conn, true)
+}
+
+// release 归还conn上的一个流
+func (m *MuxPool) release(conn interface{}, broken bool) error {
+	m.mu.Lock()
+	i := m.find(conn)
+	if i < 0 {
+		m.mu.Unlock()
+		return ErrUnknownConn
+	}
+	mc := m.active[i]
+	mc.broken = mc.broken || broken
+	mc.streams--
+	if mc.streams > 0 {
+		m.mu.Unlock()
+		return nil
+	}
+	m.active = append(m.active[:i], m.active[i+1:]...)
+	m.mu.Unlock()
+
+	if mc.broken {
+		return m.p.Close(conn)
+	}
+	return m.p.Put(conn)
+}
+
+// find 返回conn在active中的下标，不存在时返回-1
+func (m *MuxPool) find(conn interface{}) int {
+	for i, mc := range m.active {
+		if mc.conn == conn {
+			return i
+		}
+	}
+	return -1
+}
+
+// ConnStats 返回正在使用的连接及其流数
+func (m *MuxPool) ConnStats() []MuxConnStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stats := make([]MuxConnStats, len(m.active))
+	for i, mc := range m.active {
+		stats[i] = MuxConnStats{Conn: mc.conn, Streams: mc.streams}
+	}
+	return stats
+}
+
+// Stats 返回底层连接池的统计信息
+func (m *MuxPool) Stats() *Stats {
+	return m.p.Stats()
+}
+
+// Release 释放底层连接池，正在使用的连接在所有流归还时关闭
+func (m *MuxPool) Release() {
+	m.p.Release()
+}
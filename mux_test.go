@@ -0,0 +1,51 @@
+//go:build !poolnostats
+
+package pool_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hms58/pool"
+)
+
+func TestMuxPool(t *testing.T) {
+	var dialed int
+	m := pool.NewMuxPool(&pool.PoolConfig{
+		MaxCap: 4,
+		Factory: func() (interface{}, error) {
+			dialed++
+			return &testConn{id: dialed}, nil
+		},
+	}, 2)
+	defer m.Release()
+
+	var streams []interface{}
+	for i := 0; i < 3; i++ {
+		cn, err := m.Get(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		streams = append(streams, cn)
+	}
+	if dialed != 2 {
+		t.Fatalf("dialed %d conns for 3 streams with MaxStreams 2, want 2", dialed)
+	}
+	if streams[0] != streams[1] || streams[2] == streams[0] {
+		t.Error("streams not packed onto the first conn before spilling over")
+	}
+
+	m.Put(streams[0])
+	if err := m.Close(streams[1]); err != nil {
+		t.Fatal(err)
+	}
+	if got := m.Stats().Closes[pool.CloseBroken]; got != 1 {
+		t.Errorf("Closes[CloseBroken] = %d, want 1 after last stream of broken conn", got)
+	}
+	if n := len(m.ConnStats()); n != 1 {
+		t.Errorf("ConnStats() has %d conns, want 1", n)
+	}
+	if err := m.Put(streams[1]); err != pool.ErrUnknownConn {
+		t.Errorf("Put() of released conn err = %v, want ErrUnknownConn", err)
+	}
+}
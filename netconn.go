@@ -0,0 +1,99 @@
+package pool
+
+import (
+	"errors"
+	"net"
+	"sync/atomic"
+	"time"
+)
+
+// unusable 能报告自身已不可用的连接，放回时将被关闭
+type unusable interface {
+	Unusable() bool
+}
+
+// isUnusable 判断连接是否已标记为不可用
+func isUnusable(conn interface{}) bool {
+	u, ok := conn.(unusable)
+	return ok && u.Unusable()
+}
+
+// DeadlineConn 配置IOTimeout或DiscardOnNetError时Factory生成的net.Conn会被包装为DeadlineConn，
+// 每次读写前设置超时时间，读写出错后标记为不可用，放回时关闭
+// 标记为不可用后的读写直接返回ErrConnUnusable
+type DeadlineConn struct {
+	net.Conn
+	ioTimeout time.Duration
+	//为true时超时不标记为不可用，只有连接重置等永久错误才标记
+	keepOnTimeout bool
+	broken        int32
+}
+
+// newDeadlineConn 包装conn
+func newDeadlineConn(conn net.Conn, ioTimeout time.Duration, keepOnTimeout bool) *DeadlineConn {
+	return &DeadlineConn{Conn: conn, ioTimeout: ioTimeout, keepOnTimeout: keepOnTimeout}
+}
+
+func (c *DeadlineConn) Read(b []byte) (int, error) {
+	if c.Unusable() {
+		return 0, ErrConnUnusable
+	}
+	if c.ioTimeout > 0 {
+		if err := c.Conn.SetReadDeadline(time.Now().Add(c.ioTimeout)); err != nil {
+			c.MarkUnusable()
+			return 0, err
+		}
+	}
+	n, err := c.Conn.Read(b)
+	c.checkErr(err)
+	return n, err
+}
+
+func (c *DeadlineConn) Write(b []byte) (int, error) {
+	if c.Unusable() {
+		return 0, ErrConnUnusable
+	}
+	if c.ioTimeout > 0 {
+		if err := c.Conn.SetWriteDeadline(time.Now().Add(c.ioTimeout)); err != nil {
+			c.MarkUnusable()
+			return 0, err
+		}
+	}
+	n, err := c.Conn.Write(b)
+	c.checkErr(err)
+	return n, err
+}
+
+// checkErr 读写出错时标记为不可用，keepOnTimeout时忽略超时错误
+func (c *DeadlineConn) checkErr(err error) {
+	if err == nil || (c.keepOnTimeout && isTimeout(err)) {
+		return
+	}
+	c.MarkUnusable()
+}
+
+// isTimeout 判断是否为超时错误，超时后连接仍可继续使用
+func isTimeout(err error) bool {
+	var ne net.Error
+	return errors.As(err, &ne) && ne.Timeout()
+}
+
+// MarkUnusable 标记连接不可用，之后的读写返回ErrConnUnusable，放回连接池时将被关闭
+func (c *DeadlineConn) MarkUnusable() {
+	atomic.StoreInt32(&c.broken, 1)
+}
+
+// MarkUsable 清除不可用标记，BrokenLinger探测成功后调用
+func (c *DeadlineConn) MarkUsable() {
+	atomic.StoreInt32(&c.broken, 0)
+}
+
+// Unusable 连接是否已标记为不可用
+func (c *DeadlineConn) Unusable() bool {
+	return atomic.LoadInt32(&c.broken) != 0
+}
+
+// Unwrap 返回被包装的连接
+func (c *DeadlineConn) Unwrap() net.Conn {
+	return c.Conn
+}
@@ -0,0 +1,75 @@
+package pool_test
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/hms58/pool"
+)
+
+func TestDiscardOnNetError(t *testing.T) {
+	var servers []net.Conn
+	p := pool.New(&pool.PoolConfig{
+		MaxCap: 1,
+		Factory: func() (interface{}, error) {
+			client, server := net.Pipe()
+			servers = append(servers, server)
+			return client, nil
+		},
+		Close:             pool.CloseNetConn,
+		IOTimeout:         10 * time.Millisecond,
+		DiscardOnNetError: true,
+	})
+	defer p.Release()
+
+	cn, _ := p.Get()
+	conn := cn.(*pool.DeadlineConn)
+	if _, err := conn.Read(make([]byte, 1)); err == nil {
+		t.Fatal("Read() from silent pipe did not time out")
+	}
+	if conn.Unusable() {
+		t.Error("timeout marked conn unusable with DiscardOnNetError")
+	}
+
+	servers[0].Close()
+	if _, err := conn.Read(make([]byte, 1)); err == nil {
+		t.Fatal("Read() from closed pipe succeeded")
+	}
+	if !conn.Unusable() {
+		t.Error("EOF did not mark conn unusable")
+	}
+	p.Put(conn)
+	if p.Len() != 0 {
+		t.Errorf("Len() = %d, want broken conn discarded", p.Len())
+	}
+}
+
+func TestDeadlineConnMarkUnusable(t *testing.T) {
+	p := pool.New(&pool.PoolConfig{
+		MaxCap: 1,
+		Factory: func() (interface{}, error) {
+			client, server := net.Pipe()
+			go func() {
+				server.Write([]byte("x"))
+				server.Close()
+			}()
+			return client, nil
+		},
+		Close:     pool.CloseNetConn,
+		IOTimeout: time.Second,
+	})
+	defer p.Release()
+
+	cn, _ := p.Get()
+	conn := cn.(*pool.DeadlineConn)
+	conn.MarkUnusable()
+	if _, err := conn.Read(make([]byte, 1)); !errors.Is(err, pool.ErrConnUnusable) {
+		t.Errorf("Read() after MarkUnusable err = %v, want ErrConnUnusable", err)
+	}
+	if _, err := conn.Write([]byte("y")); !errors.Is(err, pool.ErrConnUnusable) {
+		t.Errorf("Write() after MarkUnusable err = %v, want ErrConnUnusable", err)
+	}
+	p.Put(conn)
+}
@@ -0,0 +1,38 @@
+//go:build poolnostats
+
+package pool_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hms58/pool"
+)
+
+// skipWithoutInterceptors 使用poolnostats构建时拦截器不会被调用，跳过依赖拦截器的测试
+func skipWithoutInterceptors(t *testing.T) {
+	t.Skip("interceptors are compiled out by poolnostats")
+}
+
+func TestNoStats(t *testing.T) {
+	p, _ := newTestPool(&pool.PoolConfig{MaxCap: 2})
+	defer p.Release()
+
+	if _, ok := <-p.Events(); ok {
+		t.Error("Events() received an event without instrumentation")
+	}
+
+	ops := make(map[pool.Op]int)
+	p.Use(func(ctx context.Context, op pool.Op, conn interface{}, next pool.Invoker) (interface{}, error) {
+		ops[op]++
+		return next(ctx, conn)
+	})
+	c, err := p.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Put(c)
+	if len(ops) != 0 {
+		t.Errorf("interceptor calls = %v, want none without instrumentation", ops)
+	}
+}
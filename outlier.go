@@ -0,0 +1,141 @@
+package pool
+
+import (
+	"sort"
+	"time"
+)
+
+// OutlierConfig BalancedPool摘除异常后端的配置，Consecutive大于0时启用
+type OutlierConfig struct {
+	//后端连续失败达到该次数后摘除，失败包括新建连接失败、Ping/OnBorrow校验失败、保活失败及读写出错被关闭，
+	//连接正常放回视为成功并清零计数
+	Consecutive int
+	//摘除的时长，到期后的下一次Get在选择后端前先对其进行探测新建，默认30秒
+	EjectFor time.Duration
+	//探测新建的连接数，全部成功后恢复该后端，任一失败则再次摘除，默认1
+	Canaries int
+	//同时摘除的后端数占总数的比例上限，默认0.5，至少保留一个后端
+	MaxEjectedRatio float64
+}
+
+// defaultEjectFor 摘除后端的默认时长
+const defaultEjectFor = 30 * time.Second
+
+func (o *OutlierConfig) setDefaults() {
+	if o.EjectFor <= 0 {
+		o.EjectFor = defaultEjectFor
+	}
+	if o.Canaries <= 0 {
+		o.Canaries = 1
+	}
+	if o.MaxEjectedRatio <= 0 {
+		o.MaxEjectedRatio = 0.5
+	}
+}
+
+// outlierReason 判断连接池关闭连接的原因是否说明后端异常
+func outlierReason(reason CloseReason) bool {
+	switch reason {
+	case CloseValidation, CloseBroken, CloseKeepAlive:
+		return true
+	}
+	return false
+}
+
+// record 记录后端的一次成功或失败
+func (p *BalancedPool) record(b *backend, ok bool) {
+	p.mu.Lock()
+	p.recordLocked(b, ok)
+	p.mu.Unlock()
+}
+
+// recordLocked 同record，需持有mu，摘除期间的结果不计入
+func (p *BalancedPool) recordLocked(b *backend, ok bool) {
+	if p.outlier.Consecutive <= 0 || b.ejected {
+		return
+	}
+	if ok {
+		b.failures = 0
+		return
+	}
+	if b.failures++; b.failures >= p.outlier.Consecutive && p.canEjectLocked() {
+		b.ejected = true
+		b.ejectedUntil = p.clock.Now().Add(p.outlier.EjectFor)
+		b.failures = 0
+	}
+}
+
+// canEjectLocked 判断是否还能再摘除一个后端，需持有mu
+func (p *BalancedPool) canEjectLocked() bool {
+	max := int(p.outlier.MaxEjectedRatio * float64(len(p.backends)))
+	if max >= len(p.backends) {
+		max = len(p.backends) - 1
+	}
+	ejected := 0
+	for _, b := range p.backends {
+		if b.ejected {
+			ejected++
+		}
+	}
+	return ejected < max
+}
+
+// readmit 对摘除到期的后端进行探测新建，全部成功后恢复，否则再次摘除
+func (p *BalancedPool) readmit() {
+	if p.outlier.Consecutive <= 0 {
+		return
+	}
+	now := p.clock.Now()
+	var due []*backend
+	p.mu.Lock()
+	for _, b := range p.backends {
+		if b.ejected && !b.drained && !b.probing && !now.Before(b.ejectedUntil) {
+			b.probing = true
+			due = append(due, b)
+		}
+	}
+	p.mu.Unlock()
+
+	for _, b := range due {
+		ok := p.canary(b)
+		p.mu.Lock()
+		b.probing = false
+		if ok {
+			b.ejected = false
+			b.current = 0
+		} else {
+			b.ejectedUntil = p.clock.Now().Add(p.outlier.EjectFor)
+		}
+		p.mu.Unlock()
+	}
+}
+
+// canary 为后端新建Canaries条连接并放入其连接池，全部成功时返回true
+func (p *BalancedPool) canary(b *backend) bool {
+	kp, err := p.keyed.pool(b.Name)
+	if err != nil {
+		return false
+	}
+	for i := 0; i < p.outlier.Canaries; i++ {
+		conn, err := kp.Dial()
+		if err != nil {
+			return false
+		}
+		kp.Put(conn)
+	}
+	return true
+}
+
+// Ejected 返回当前被摘除的后端，按名称排列
+func (p *BalancedPool) Ejected() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var names []string
+	for _, b := range p.backends {
+		if b.ejected {
+			names = append(names, b.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
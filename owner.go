@@ -0,0 +1,88 @@
+package pool
+
+import "context"
+
+// OwnerStats 单个使用方的借出统计
+type OwnerStats struct {
+	Busy     int    // number of connections currently checked out by the owner
+	MaxBusy  int    // high watermark of Busy
+	Gets     uint64 // number of successful GetFor calls
+	Rejected uint64 // number of GetFor calls rejected by the owner's quota
+}
+
+// ownerQuota 返回owner最多可同时借出的连接数，为0时不限制
+func (c *ChannelPool) ownerQuota(owner string) int {
+	if n, ok := c.ownerQuotas[owner]; ok {
+		return n
+	}
+	return c.defaultOwnerQuota
+}
+
+// GetFor 以owner的名义取一个连接，owner借出的连接数达到配额时返回ErrOwnerQuota
+// 连接放回或关闭时归还owner的配额，无法追踪的连接取出后立即归还配额
+func (c *ChannelPool) GetFor(ctx context.Context, owner string) (interface{}, error) {
+	c.ownersMu.Lock()
+	st, ok := c.owners[owner]
+	if !ok {
+		st = &OwnerStats{}
+		c.owners[owner] = st
+	}
+	if quota := c.ownerQuota(owner); quota > 0 && st.Busy >= quota {
+		st.Rejected++
+		c.ownersMu.Unlock()
+		return nil, getErr(ErrOwnerQuota)
+	}
+	st.Busy++
+	if st.Busy > st.MaxBusy {
+		st.MaxBusy = st.Busy
+	}
+	c.ownersMu.Unlock()
+
+	conn, err := c.GetContext(ctx)
+	if err != nil {
+		c.releaseOwner(owner, false)
+		return nil, err
+	}
+
+	owned := false
+	if trackable(conn) {
+		c.tracked.lookup(trackKey(conn), func(wrapConn *idleConn) {
+			if wrapConn != nil && wrapConn.lent {
+				wrapConn.owner = owner
+				owned = true
+			}
+		})
+	}
+	if owned {
+		c.ownersMu.Lock()
+		st.Gets++
+		c.ownersMu.Unlock()
+		return conn, nil
+	}
+	c.releaseOwner(owner, true)
+	return conn, nil
+}
+
+// releaseOwner 归还owner的一个配额，got为true时计入成功借出次数
+func (c *ChannelPool) releaseOwner(owner string, got bool) {
+	c.ownersMu.Lock()
+	if st, ok := c.owners[owner]; ok {
+		st.Busy--
+		if got {
+			st.Gets++
+		}
+	}
+	c.ownersMu.Unlock()
+}
+
+// OwnerStats 返回各使用方的借出统计
+func (c *ChannelPool) OwnerStats() map[string]OwnerStats {
+	c.ownersMu.Lock()
+	defer c.ownersMu.Unlock()
+
+	stats := make(map[string]OwnerStats, len(c.owners))
+	for owner, st := range c.owners {
+		stats[owner] = *st
+	}
+	return stats
+}
@@ -0,0 +1,131 @@
+package pool
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// PinnedConn 固定在调用方的借出连接，Unpin之前Put不会将其放回连接池，而是交还给PinnedConn，
+// 可在之后的作用域中通过Get再次取出，用于必须在同一连接上完成的多步事务
+type PinnedConn struct {
+	p    *ChannelPool
+	conn interface{}
+
+	mu sync.Mutex
+	//已通过Put交还，等待Get再次取出
+	parked bool
+	//已Unpin或连接已被关闭
+	released bool
+}
+
+// Pin 固定已借出的conn，之后的Put将其交还给返回的PinnedConn，直到调用Unpin
+// 对已固定的连接返回同一个PinnedConn，连接需可作为map的key
+func (c *ChannelPool) Pin(conn interface{}) (*PinnedConn, error) {
+	if conn == nil || !trackable(conn) {
+		return nil, ErrUnknownConn
+	}
+	key := trackKey(conn)
+	s := c.tracked.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	wrapConn, ok := s.conns[key]
+	if !ok {
+		return nil, ErrUnknownConn
+	}
+	if wrapConn.pin != nil {
+		return wrapConn.pin, nil
+	}
+	if !wrapConn.lent {
+		return nil, ErrDoublePut
+	}
+	wrapConn.pin = &PinnedConn{p: c, conn: conn}
+	atomic.AddInt32(&c.numPinned, 1)
+	return wrapConn.pin, nil
+}
+
+// Conn 返回固定的连接
+func (pc *PinnedConn) Conn() interface{} {
+	return pc.conn
+}
+
+// Get 再次取出已通过Put交还的连接，连接仍在使用中时返回ErrPinnedInUse，
+// 已Unpin或连接已被关闭时返回ErrNotPinned
+func (pc *PinnedConn) Get() (interface{}, error) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	if pc.released {
+		return nil, ErrNotPinned
+	}
+	if !pc.parked {
+		return nil, ErrPinnedInUse
+	}
+	pc.parked = false
+	return pc.conn, nil
+}
+
+// Unpin 解除固定，连接已通过Put交还时放回连接池，仍在使用中时之后的Put照常放回连接池
+func (pc *PinnedConn) Unpin() error {
+	pc.mu.Lock()
+	if pc.released {
+		pc.mu.Unlock()
+		return ErrNotPinned
+	}
+	pc.released = true
+	parked := pc.parked
+	pc.parked = false
+	pc.mu.Unlock()
+
+	c := pc.p
+	c.tracked.lookup(trackKey(pc.conn), func(wrapConn *idleConn) {
+		if wrapConn != nil && wrapConn.pin == pc {
+			wrapConn.pin = nil
+			atomic.AddInt32(&c.numPinned, -1)
+		}
+	})
+	if parked {
+		return c.put(pc.conn)
+	}
+	return nil
+}
+
+// park Put固定的连接时交还给PinnedConn
+func (pc *PinnedConn) park() error {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	if pc.parked {
+		return ErrDoublePut
+	}
+	pc.parked = true
+	return nil
+}
+
+// parkPinned conn已被固定时交还给其PinnedConn而不放回连接池，返回是否已交还
+func (c *ChannelPool) parkPinned(conn interface{}) (bool, error) {
+	if atomic.LoadInt32(&c.numPinned) == 0 || !trackable(conn) {
+		return false, nil
+	}
+	var pin *PinnedConn
+	c.tracked.lookup(trackKey(conn), func(wrapConn *idleConn) {
+		if wrapConn != nil {
+			pin = wrapConn.pin
+		}
+	})
+	if pin == nil {
+		return false, nil
+	}
+	return true, pin.park()
+}
+
+// unpinLocked 连接关闭时解除其固定，需持有连接所在分片的锁
+func (c *ChannelPool) unpinLocked(wrapConn *idleConn) {
+	pin := wrapConn.pin
+	if pin == nil {
+		return
+	}
+	wrapConn.pin = nil
+	atomic.AddInt32(&c.numPinned, -1)
+	pin.mu.Lock()
+	pin.released = true
+	pin.parked = false
+	pin.mu.Unlock()
+}
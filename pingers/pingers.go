@@ -0,0 +1,100 @@
+// Package pingers 提供常见协议的连接健康检查方法，可直接作为PoolConfig.Ping使用，
+// 连接类型需为net.Conn，timeout为整个检查的超时时间
+package pingers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// maxLineLen 读取响应行的最大长度
+const maxLineLen = 512
+
+// ErrUnexpectedData 空闲连接上出现了未读取的数据
+var ErrUnexpectedData = errors.New("pingers: unexpected data on idle conn")
+
+// Redis 发送PING，期望响应+PONG
+func Redis(timeout time.Duration) func(interface{}) error {
+	return command(timeout, "PING\r\n", "+PONG")
+}
+
+// Memcached 发送version，期望响应VERSION
+func Memcached(timeout time.Duration) func(interface{}) error {
+	return command(timeout, "version\r\n", "VERSION ")
+}
+
+// SMTP 发送NOOP，期望响应250
+func SMTP(timeout time.Duration) func(interface{}) error {
+	return command(timeout, "NOOP\r\n", "250")
+}
+
+// TCP 不发送数据，在timeout内尝试读取：超时说明连接正常，对端关闭或出错时返回错误，
+// 读到数据时返回ErrUnexpectedData，适用于只由客户端发起请求的协议
+func TCP(timeout time.Duration) func(interface{}) error {
+	return func(v interface{}) error {
+		conn := v.(net.Conn)
+		if err := conn.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+			return err
+		}
+		defer conn.SetReadDeadline(time.Time{})
+
+		var b [1]byte
+		n, err := conn.Read(b[:])
+		var netErr net.Error
+		switch {
+		case n > 0:
+			return ErrUnexpectedData
+		case errors.As(err, &netErr) && netErr.Timeout():
+			return nil
+		case err == nil:
+			return io.ErrNoProgress
+		default:
+			return err
+		}
+	}
+}
+
+// command 发送req并检查响应行是否以prefix开头
+func command(timeout time.Duration, req, prefix string) func(interface{}) error {
+	return func(v interface{}) error {
+		conn := v.(net.Conn)
+		if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+			return err
+		}
+		defer conn.SetDeadline(time.Time{})
+
+		if _, err := io.WriteString(conn, req); err != nil {
+			return err
+		}
+		line, err := readLine(conn)
+		if err != nil {
+			return err
+		}
+		if !strings.HasPrefix(line, prefix) {
+			return fmt.Errorf("pingers: unexpected reply %q", line)
+		}
+		return nil
+	}
+}
+
+// readLine 逐字节读取一行，避免多读后续数据
+func readLine(r io.Reader) (string, error) {
+	var (
+		buf []byte
+		b   [1]byte
+	)
+	for len(buf) < maxLineLen {
+		if _, err := io.ReadFull(r, b[:]); err != nil {
+			return "", err
+		}
+		if b[0] == '\n' {
+			return strings.TrimSuffix(string(buf), "\r"), nil
+		}
+		buf = append(buf, b[0])
+	}
+	return "", errors.New("pingers: reply line too long")
+}
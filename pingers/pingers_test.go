@@ -0,0 +1,65 @@
+package pingers_test
+
+import (
+	"bufio"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/hms58/pool/pingers"
+)
+
+// serve 读取一行请求后回复reply
+func serve(reply string) net.Conn {
+	client, server := net.Pipe()
+	go func() {
+		defer server.Close()
+		if _, err := bufio.NewReader(server).ReadString('\n'); err != nil {
+			return
+		}
+		server.Write([]byte(reply))
+	}()
+	return client
+}
+
+func TestCommandPingers(t *testing.T) {
+	tests := []struct {
+		name  string
+		ping  func(interface{}) error
+		reply string
+		ok    bool
+	}{
+		{"redis", pingers.Redis(time.Second), "+PONG\r\n", true},
+		{"redis error", pingers.Redis(time.Second), "-NOAUTH Authentication required.\r\n", false},
+		{"memcached", pingers.Memcached(time.Second), "VERSION 1.6.21\r\n", true},
+		{"smtp", pingers.SMTP(time.Second), "250 2.0.0 OK\r\n", true},
+		{"smtp error", pingers.SMTP(time.Second), "421 closing\r\n", false},
+		{"no reply", pingers.Redis(50 * time.Millisecond), "", false},
+	}
+	for _, tt := range tests {
+		conn := serve(tt.reply)
+		if err := tt.ping(conn); (err == nil) != tt.ok {
+			t.Errorf("%s: err = %v, want ok=%v", tt.name, err, tt.ok)
+		}
+		conn.Close()
+	}
+}
+
+func TestTCP(t *testing.T) {
+	ping := pingers.TCP(10 * time.Millisecond)
+
+	client, server := net.Pipe()
+	if err := ping(client); err != nil {
+		t.Errorf("idle conn err = %v", err)
+	}
+
+	go server.Write([]byte("x"))
+	if err := ping(client); err != pingers.ErrUnexpectedData {
+		t.Errorf("conn with pending data err = %v, want ErrUnexpectedData", err)
+	}
+
+	server.Close()
+	if err := ping(client); err == nil {
+		t.Error("closed conn passed ping")
+	}
+}
@@ -1,13 +1,55 @@
 package pool
 
-import "errors"
+import (
+	"context"
+	"errors"
+)
 
 var (
 	//ErrClosed 连接池已经关闭Error
 	ErrClosed = errors.New("pool is closed")
+	//ErrDraining 连接池正在排空Error
+	ErrDraining = errors.New("pool is draining")
+	//ErrPoolExhausted 连接数已达MaxCap+MaxOverflow上限Error
+	ErrPoolExhausted = errors.New("pool is exhausted")
+	//ErrNoIdleConn 没有可用的空闲连接Error
+	ErrNoIdleConn = errors.New("pool: no idle connection available")
+	//ErrLeaseExpired 租约已到期被回收或已归还Error
+	ErrLeaseExpired = errors.New("pool: lease expired")
+	//ErrOwnerQuota 使用方借出的连接数已达配额Error
+	ErrOwnerQuota = errors.New("pool: owner quota exceeded")
+	//ErrNoChildFactory 未配置ChildFactory Error
+	ErrNoChildFactory = errors.New("pool: ChildFactory is not configured")
+	//ErrReservationUsed 名额已经Dial或Cancel Error
+	ErrReservationUsed = errors.New("pool: reservation already used")
+	//ErrQueueFull 任务队列已满Error
+	ErrQueueFull = errors.New("pool: task queue is full")
+	//ErrGetTimeout 等待连接超过WaitTimeout Error
+	ErrGetTimeout = errors.New("pool: timed out waiting for a connection")
+	//ErrCloseTimeout 关闭连接超时Error
+	ErrCloseTimeout = errors.New("pool: close timed out")
+	//ErrDoublePut 连接已放回连接池，重复放回或关闭Error
+	ErrDoublePut = errors.New("pool: connection already returned to the pool")
+	//ErrUnknownConn 连接不是由该连接池创建Error
+	ErrUnknownConn = errors.New("pool: connection does not belong to the pool")
+	//ErrInvariantViolated 违反连接池内部不变式Error，参见PoolConfig.CheckInvariants
+	ErrInvariantViolated = errors.New("pool: invariant violated")
+	//ErrNoMatchingConn 没有标签匹配选择器的连接Error
+	ErrNoMatchingConn = errors.New("pool: no connection matches the selector")
+	//ErrPinnedInUse 固定的连接仍在使用中，尚未通过Put交还Error
+	ErrPinnedInUse = errors.New("pool: pinned connection is in use")
+	//ErrNotPinned 连接已解除固定或已被关闭Error
+	ErrNotPinned = errors.New("pool: connection is not pinned")
+	//ErrRateLimited 超过GetRateLimit且无法在截止时间前取得令牌Error
+	ErrRateLimited = errors.New("pool: get rate limit exceeded")
+	//ErrConnUnusable 连接已被标记为不可用，不再读写Error
+	ErrConnUnusable = errors.New("pool: connection is marked unusable")
 )
 
-//Pool 基本方法
+// Factory 生成连接的方法
+type Factory func() (interface{}, error)
+
+// Pool 基本方法，取连接的其他方式及排空、观测等扩展能力由*ChannelPool提供
 type Pooler interface {
 	Get() (interface{}, error)
 
@@ -22,3 +64,9 @@ type Pooler interface {
 	Stats() *Stats
 	ShowStats()
 }
+
+// ContextGetter Pooler的可选扩展，支持ctx取消及截止时间的Get，*ChannelPool实现
+// 只接受Pooler的调用方可通过类型断言使用，未实现时退回Get
+type ContextGetter interface {
+	GetContext(ctx context.Context) (interface{}, error)
+}
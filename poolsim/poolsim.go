@@ -0,0 +1,246 @@
+// Package poolsim 提供虚拟时钟及按脚本返回延迟和错误的Factory/Close，
+// 用于在毫秒内确定性地测试空闲超时、存活时间、错误缓存及后台定时任务，无需sleep
+package poolsim
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hms58/pool"
+)
+
+// ErrClosedTwice 同一条连接被关闭两次
+var ErrClosedTwice = errors.New("poolsim: conn closed twice")
+
+// Clock 只在Advance时前进的虚拟时钟，实现pool.Clock
+type Clock struct {
+	mu     sync.Mutex
+	now    time.Time
+	seq    int
+	timers []*timer
+}
+
+// timer Every及AfterFunc注册的定时任务
+type timer struct {
+	seq int
+	//执行间隔，0表示只执行一次
+	every   time.Duration
+	next    time.Time
+	f       func()
+	stopped bool
+}
+
+// NewClock 创建从start开始的虚拟时钟
+func NewClock(start time.Time) *Clock {
+	return &Clock{now: start}
+}
+
+// Now 返回虚拟时钟的当前时间
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Every 注册每隔d执行一次的定时任务，f只在Advance中同步调用
+func (c *Clock) Every(d time.Duration, f func()) func() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.seq++
+	t := &timer{seq: c.seq, every: d, next: c.now.Add(d), f: f}
+	c.timers = append(c.timers, t)
+	return func() {
+		c.mu.Lock()
+		t.stopped = true
+		c.mu.Unlock()
+	}
+}
+
+// AfterFunc 注册d之后执行一次的定时任务，f只在Advance中同步调用
+func (c *Clock) AfterFunc(d time.Duration, f func()) func() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.seq++
+	t := &timer{seq: c.seq, next: c.now.Add(d), f: f}
+	c.timers = append(c.timers, t)
+	return func() {
+		c.mu.Lock()
+		t.stopped = true
+		c.mu.Unlock()
+	}
+}
+
+// Advance 将时钟前进d，按到期时间顺序依次同步执行到期的定时任务，返回时所有任务已执行完毕
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	end := c.now.Add(d)
+	for {
+		t := c.nextDue(end)
+		if t == nil {
+			break
+		}
+		c.now = t.next
+		if t.every > 0 {
+			t.next = t.next.Add(t.every)
+		} else {
+			t.stopped = true
+		}
+		c.mu.Unlock()
+		t.f()
+		c.mu.Lock()
+	}
+	c.now = end
+	c.mu.Unlock()
+}
+
+// Sleep 将时钟前进d但不执行定时任务，到期的任务在下次Advance时执行
+// 用于在Factory、Close等可能持有连接池锁的回调中模拟耗时
+func (c *Clock) Sleep(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+}
+
+// nextDue 返回不晚于end的最早到期任务，同时到期的按注册顺序，需持有mu
+func (c *Clock) nextDue(end time.Time) *timer {
+	live := c.timers[:0]
+	for _, t := range c.timers {
+		if !t.stopped {
+			live = append(live, t)
+		}
+	}
+	c.timers = live
+	sort.SliceStable(live, func(i, j int) bool {
+		if !live[i].next.Equal(live[j].next) {
+			return live[i].next.Before(live[j].next)
+		}
+		return live[i].seq < live[j].seq
+	})
+	if len(live) == 0 || live[0].next.After(end) {
+		return nil
+	}
+	return live[0]
+}
+
+// Step 脚本中的一步
+type Step struct {
+	// 虚拟时钟前进的时长
+	Latency time.Duration
+	// 返回的错误，Factory返回错误时不创建连接
+	Err error
+}
+
+// Conn Sim创建的连接
+type Conn struct {
+	// 创建顺序，从1开始
+	ID int
+	// 创建时的虚拟时间
+	Created time.Time
+}
+
+// Sim 由虚拟时钟驱动的模拟后端，Factory和Close按脚本依次返回延迟和错误，
+// 脚本用完后立即成功
+type Sim struct {
+	Clock *Clock
+
+	mu     sync.Mutex
+	dials  []Step
+	closes []Step
+	nextID int
+	open   map[*Conn]bool
+	closed int
+}
+
+// New 创建虚拟时间从start开始的模拟后端
+func New(start time.Time) *Sim {
+	return &Sim{Clock: NewClock(start), open: make(map[*Conn]bool)}
+}
+
+// ScriptDials 追加之后Factory调用的脚本
+func (s *Sim) ScriptDials(steps ...Step) {
+	s.mu.Lock()
+	s.dials = append(s.dials, steps...)
+	s.mu.Unlock()
+}
+
+// ScriptCloses 追加之后Close调用的脚本
+func (s *Sim) ScriptCloses(steps ...Step) {
+	s.mu.Lock()
+	s.closes = append(s.closes, steps...)
+	s.mu.Unlock()
+}
+
+// Config 在cfg中填入Clock、Factory及Close并返回cfg
+func (s *Sim) Config(cfg *pool.PoolConfig) *pool.PoolConfig {
+	cfg.Clock = s.Clock
+	cfg.Factory = s.Factory
+	cfg.Close = s.Close
+	return cfg
+}
+
+// Factory 按脚本推进时钟并新建连接或返回错误
+func (s *Sim) Factory() (interface{}, error) {
+	s.mu.Lock()
+	step := pop(&s.dials)
+	s.mu.Unlock()
+
+	s.Clock.Sleep(step.Latency)
+	if step.Err != nil {
+		return nil, step.Err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	conn := &Conn{ID: s.nextID, Created: s.Clock.Now()}
+	s.open[conn] = true
+	return conn, nil
+}
+
+// Close 按脚本推进时钟并关闭连接，脚本中的错误在连接关闭后返回
+func (s *Sim) Close(v interface{}) error {
+	s.mu.Lock()
+	step := pop(&s.closes)
+	conn := v.(*Conn)
+	if !s.open[conn] {
+		s.mu.Unlock()
+		return ErrClosedTwice
+	}
+	delete(s.open, conn)
+	s.closed++
+	s.mu.Unlock()
+
+	s.Clock.Sleep(step.Latency)
+	return step.Err
+}
+
+// Open 返回尚未关闭的连接数
+func (s *Sim) Open() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.open)
+}
+
+// Dialed 返回成功新建的连接数
+func (s *Sim) Dialed() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.nextID
+}
+
+// Closed 返回已关闭的连接数
+func (s *Sim) Closed() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+func pop(steps *[]Step) Step {
+	if len(*steps) == 0 {
+		return Step{}
+	}
+	step := (*steps)[0]
+	*steps = (*steps)[1:]
+	return step
+}
@@ -0,0 +1,107 @@
+package poolsim_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hms58/pool"
+	"github.com/hms58/pool/poolsim"
+)
+
+var epoch = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func newSimPool(t *testing.T, sim *poolsim.Sim, cfg pool.PoolConfig) *pool.ChannelPool {
+	t.Helper()
+	if cfg.MaxCap == 0 {
+		cfg.MaxCap = 4
+	}
+	p := pool.New(sim.Config(&cfg))
+	t.Cleanup(p.Release)
+	return p
+}
+
+func TestIdleTimeout(t *testing.T) {
+	sim := poolsim.New(epoch)
+	p := newSimPool(t, sim, pool.PoolConfig{IdleTimeout: time.Minute})
+
+	v, _ := p.Get()
+	p.Put(v)
+	sim.Clock.Advance(59 * time.Second)
+	if v2, _ := p.Get(); v2 != v {
+		t.Fatalf("conn reused before IdleTimeout: got %v, want %v", v2, v)
+	}
+	p.Put(v)
+
+	sim.Clock.Advance(61 * time.Second)
+	v2, err := p.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v2 == v || sim.Closed() != 1 {
+		t.Fatalf("expired conn not replaced: got id %d, closed %d", v2.(*poolsim.Conn).ID, sim.Closed())
+	}
+}
+
+func TestMaxLifetime(t *testing.T) {
+	sim := poolsim.New(epoch)
+	p := newSimPool(t, sim, pool.PoolConfig{MaxLifetime: time.Hour})
+
+	v, _ := p.Get()
+	for i := 0; i < 3; i++ {
+		p.Put(v)
+		sim.Clock.Advance(25 * time.Minute)
+		v, _ = p.Get()
+	}
+	if id := v.(*poolsim.Conn).ID; id != 2 {
+		t.Fatalf("conn id = %d after lifetime, want 2", id)
+	}
+}
+
+func TestKeepAlive(t *testing.T) {
+	sim := poolsim.New(epoch)
+	var probes int
+	p := newSimPool(t, sim, pool.PoolConfig{
+		KeepAliveInterval: 10 * time.Second,
+		KeepAlive: func(interface{}) error {
+			probes++
+			return errors.New("no pong")
+		},
+	})
+
+	v, _ := p.Get()
+	sim.Clock.Advance(5 * time.Second)
+	p.Put(v)
+	sim.Clock.Advance(5 * time.Second)
+	if probes != 0 || sim.Open() != 1 {
+		t.Fatalf("probed conn idle for less than interval: probes %d, open %d", probes, sim.Open())
+	}
+	sim.Clock.Advance(10 * time.Second)
+	if probes != 1 || sim.Open() != 0 || p.Len() != 0 {
+		t.Fatalf("after probe failure: probes %d, open %d, len %d", probes, sim.Open(), p.Len())
+	}
+}
+
+func TestClockOrder(t *testing.T) {
+	clk := poolsim.NewClock(epoch)
+	var got []string
+	clk.Every(2*time.Second, func() { got = append(got, "a@"+clk.Now().Sub(epoch).String()) })
+	stop := clk.Every(3*time.Second, func() { got = append(got, "b@"+clk.Now().Sub(epoch).String()) })
+	clk.AfterFunc(5*time.Second, func() { got = append(got, "c@"+clk.Now().Sub(epoch).String()) })
+	clk.Advance(6 * time.Second)
+	stop()
+	clk.Advance(2 * time.Second)
+
+	want := []string{"a@2s", "b@3s", "a@4s", "c@5s", "a@6s", "b@6s", "a@8s"}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+	if !clk.Now().Equal(epoch.Add(8 * time.Second)) {
+		t.Fatalf("Now = %v", clk.Now())
+	}
+}
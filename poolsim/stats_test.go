@@ -0,0 +1,40 @@
+//go:build !poolnostats
+
+package poolsim_test
+
+// 依赖统计计数器的测试，使用poolnostats构建时跳过
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hms58/pool"
+	"github.com/hms58/pool/poolsim"
+)
+
+func TestDialErrorCache(t *testing.T) {
+	boom := errors.New("connection refused")
+	sim := poolsim.New(epoch)
+	sim.ScriptDials(poolsim.Step{Latency: 3 * time.Second, Err: boom})
+	p := newSimPool(t, sim, pool.PoolConfig{DialErrorTTL: 5 * time.Second})
+
+	_, err := p.Get()
+	var ge *pool.GetError
+	if !errors.As(err, &ge) || ge.Kind != pool.KindDialFailed {
+		t.Fatalf("first Get err = %v, want dial failure", err)
+	}
+	sim.Clock.Advance(4 * time.Second)
+	if _, err := p.Get(); !errors.As(err, &ge) || ge.Kind != pool.KindCircuitOpen {
+		t.Fatalf("cached Get err = %v, want circuit open", err)
+	}
+	sim.Clock.Advance(2 * time.Second)
+	if _, err := p.Get(); err != nil {
+		t.Fatalf("Get after TTL: %v", err)
+	}
+
+	stats := p.Stats()
+	if stats.DialDuration != 3*time.Second || stats.MaxDialDuration != 3*time.Second {
+		t.Fatalf("DialDuration = %v, MaxDialDuration = %v, want 3s", stats.DialDuration, stats.MaxDialDuration)
+	}
+}
@@ -0,0 +1,189 @@
+// Package pooltest 提供对任意pool.Pooler实现的并发正确性测试，
+// 可供其他连接池实现及用户的包装层共用同一套检查
+package pooltest
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hms58/pool"
+)
+
+// TortureOpts Torture的参数，零值使用默认值
+type TortureOpts struct {
+	// 并发的goroutine数，默认8
+	Goroutines int
+	// 总运行时长，前一半正常使用，之后调用Release并继续运行，默认200毫秒
+	Duration time.Duration
+	// 借出的连接改为Close关闭的比例，默认0.1
+	CloseRatio float64
+	// 借出后持有连接的最长时间，默认不持有
+	MaxHold time.Duration
+	// 单次Get的超时时间，默认1秒
+	GetTimeout time.Duration
+	// 持有连接期间调用，返回错误视为连接不可用
+	Use func(conn interface{}) error
+}
+
+func (o *TortureOpts) setDefaults() {
+	if o.Goroutines <= 0 {
+		o.Goroutines = 8
+	}
+	if o.Duration <= 0 {
+		o.Duration = 200 * time.Millisecond
+	}
+	if o.CloseRatio == 0 {
+		o.CloseRatio = 0.1
+	}
+	if o.GetTimeout <= 0 {
+		o.GetTimeout = time.Second
+	}
+}
+
+// Torture 并发地对p执行Get、Put、Close，运行一半时间后调用Release，检查：
+// 同一连接不会同时借给两个调用者，已Close的连接不会再次借出，
+// Release之后开始的Get不会成功，Stats中IdleConns+BusyConns+DialingConns始终不超过TotalConns
+// 连接不可作为map的key时跳过与连接身份相关的检查，Torture返回时p已被释放
+func Torture(t testing.TB, p pool.Pooler, opts TortureOpts) {
+	t.Helper()
+	opts.setDefaults()
+
+	tr := &tracker{
+		t:      t,
+		lent:   make(map[interface{}]bool),
+		closed: make(map[interface{}]bool),
+	}
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < opts.Goroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				tr.round(p, &opts)
+			}
+		}()
+	}
+
+	half := time.After(opts.Duration / 2)
+	end := time.After(opts.Duration)
+	released := false
+	for done := false; !done; {
+		select {
+		case <-half:
+			tr.release(p)
+			released = true
+		case <-end:
+			done = true
+		default:
+			if s := p.Stats(); s.IdleConns+s.BusyConns+s.DialingConns > s.TotalConns {
+				t.Errorf("Stats: IdleConns %d + BusyConns %d + DialingConns %d > TotalConns %d", s.IdleConns, s.BusyConns, s.DialingConns, s.TotalConns)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	if !released {
+		tr.release(p)
+	}
+	close(stop)
+	wg.Wait()
+
+	if n := p.Len(); n != 0 {
+		t.Errorf("Len() after Release = %d, want 0", n)
+	}
+	if _, err := p.Get(); err == nil {
+		t.Error("Get() after Release succeeded")
+	}
+}
+
+// tracker 记录借出及关闭的连接
+type tracker struct {
+	t testing.TB
+	// Release开始及返回后分别置1
+	releasing int32
+	released  int32
+
+	mu     sync.Mutex
+	lent   map[interface{}]bool
+	closed map[interface{}]bool
+}
+
+// release 释放p并记录释放的开始及结束
+func (tr *tracker) release(p pool.Pooler) {
+	atomic.StoreInt32(&tr.releasing, 1)
+	p.Release()
+	atomic.StoreInt32(&tr.released, 1)
+}
+
+// round 执行一次Get，持有后放回或关闭
+func (tr *tracker) round(p pool.Pooler, opts *TortureOpts) {
+	afterRelease := atomic.LoadInt32(&tr.released) == 1
+	ctx, cancel := context.WithTimeout(context.Background(), opts.GetTimeout)
+	var (
+		conn interface{}
+		err  error
+	)
+	if g, ok := p.(pool.ContextGetter); ok {
+		conn, err = g.GetContext(ctx)
+	} else {
+		conn, err = p.Get()
+	}
+	cancel()
+	if err != nil {
+		if atomic.LoadInt32(&tr.releasing) == 0 && errors.Is(err, pool.ErrClosed) {
+			tr.t.Errorf("Get() err = %v before Release", err)
+		}
+		return
+	}
+	if afterRelease {
+		tr.t.Errorf("Get() after Release returned conn %v", conn)
+	}
+
+	comparable := reflect.TypeOf(conn).Comparable()
+	if comparable {
+		tr.mu.Lock()
+		if tr.lent[conn] {
+			tr.t.Errorf("conn %v handed out twice", conn)
+		}
+		if tr.closed[conn] {
+			tr.t.Errorf("closed conn %v handed out", conn)
+		}
+		tr.lent[conn] = true
+		tr.mu.Unlock()
+	}
+
+	if opts.MaxHold > 0 {
+		time.Sleep(rand.N(opts.MaxHold))
+	}
+	broken := false
+	if opts.Use != nil {
+		broken = opts.Use(conn) != nil
+	}
+	closing := broken || rand.Float64() < opts.CloseRatio
+
+	// 先取消登记再放回，避免放回后立即被其他调用者取出时误报
+	if comparable {
+		tr.mu.Lock()
+		delete(tr.lent, conn)
+		if closing {
+			tr.closed[conn] = true
+		}
+		tr.mu.Unlock()
+	}
+	if closing {
+		p.Close(conn)
+	} else {
+		p.Put(conn)
+	}
+}
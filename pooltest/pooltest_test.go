@@ -0,0 +1,46 @@
+package pooltest_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hms58/pool"
+	"github.com/hms58/pool/pooltest"
+)
+
+func newConfig() *pool.PoolConfig {
+	return &pool.PoolConfig{
+		MaxCap:  4,
+		Wait:    true,
+		Factory: func() (interface{}, error) { return new(int), nil },
+	}
+}
+
+func TestTorture(t *testing.T) {
+	tests := []struct {
+		name string
+		new  func() pool.Pooler
+	}{
+		{"channel", func() pool.Pooler { return pool.NewChannelPool(newConfig()) }},
+		{"ring", func() pool.Pooler { return pool.NewRingPool(newConfig()) }},
+		{"sticky", func() pool.Pooler {
+			cfg := newConfig()
+			cfg.StickyStash = true
+			return pool.NewChannelPool(cfg)
+		}},
+		{"invariants", func() pool.Pooler {
+			cfg := newConfig()
+			cfg.CheckInvariants = true
+			return pool.NewChannelPool(cfg)
+		}},
+		// 只实现Pooler基本方法的连接池
+		{"core", func() pool.Pooler {
+			return struct{ pool.Pooler }{pool.NewChannelPool(newConfig())}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pooltest.Torture(t, tt.new(), pooltest.TortureOpts{MaxHold: 100 * time.Microsecond})
+		})
+	}
+}
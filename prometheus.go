@@ -0,0 +1,110 @@
+package pool
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// promMetric 输出到Prometheus的一项统计信息
+type promMetric struct {
+	name  string
+	kind  string
+	help  string
+	value func(*Stats) float64
+}
+
+var promMetrics = []promMetric{
+	{"pool_total_conns", "gauge", "Open connections, including ones being dialed or closed.", func(s *Stats) float64 { return float64(s.TotalConns) }},
+	{"pool_idle_conns", "gauge", "Idle connections.", func(s *Stats) float64 { return float64(s.IdleConns) }},
+	{"pool_busy_conns", "gauge", "Connections checked out.", func(s *Stats) float64 { return float64(s.BusyConns) }},
+	{"pool_dialing_conns", "gauge", "Connections being dialed.", func(s *Stats) float64 { return float64(s.DialingConns) }},
+	{"pool_waiters", "gauge", "Goroutines waiting in Get.", func(s *Stats) float64 { return float64(s.Waiters) }},
+	{"pool_hits_total", "counter", "Gets served by an idle connection.", func(s *Stats) float64 { return float64(s.Hits) }},
+	{"pool_misses_total", "counter", "Gets that found no idle connection.", func(s *Stats) float64 { return float64(s.Misses) }},
+	{"pool_stale_skips_total", "counter", "Stale or invalid idle connections discarded by Get.", func(s *Stats) float64 { return float64(s.StaleSkips) }},
+	{"pool_overflows_total", "counter", "Overflow connections created beyond MaxCap.", func(s *Stats) float64 { return float64(s.Overflows) }},
+	{"pool_rate_limited_total", "counter", "Gets delayed or rejected by GetRateLimit.", func(s *Stats) float64 { return float64(s.RateLimited) }},
+	{"pool_wait_count_total", "counter", "Gets that blocked waiting for a connection.", func(s *Stats) float64 { return float64(s.WaitCount) }},
+	{"pool_wait_seconds_total", "counter", "Time blocked waiting for a connection.", func(s *Stats) float64 { return s.WaitDuration.Seconds() }},
+	{"pool_dial_count_total", "counter", "Factory calls.", func(s *Stats) float64 { return float64(s.DialCount) }},
+	{"pool_dial_errors_total", "counter", "Factory calls that returned an error.", func(s *Stats) float64 { return float64(s.DialErrors) }},
+	{"pool_dial_seconds_total", "counter", "Time spent in factory calls.", func(s *Stats) float64 { return s.DialDuration.Seconds() }},
+}
+
+// WritePrometheus 以Prometheus文本格式输出各连接池的统计信息，
+// 连接池的Name(标签名pool)及Labels作为样本的标签，用于区分同一进程中的多个连接池
+func WritePrometheus(w io.Writer, pools ...Pooler) error {
+	labels := make([]string, len(pools))
+	stats := make([]*Stats, len(pools))
+	for i, p := range pools {
+		if l, ok := p.(labeler); ok {
+			labels[i] = promLabels(l.telemetryLabels())
+		}
+		stats[i] = p.Stats()
+	}
+
+	bw := bufio.NewWriter(w)
+	for _, m := range promMetrics {
+		fmt.Fprintf(bw, "# HELP %s %s\n# TYPE %s %s\n", m.name, m.help, m.name, m.kind)
+		for i, s := range stats {
+			fmt.Fprintf(bw, "%s%s %g\n", m.name, promBraces(labels[i]), m.value(s))
+		}
+	}
+	fmt.Fprintf(bw, "# HELP pool_closes_total Connections closed by the pool, by reason.\n# TYPE pool_closes_total counter\n")
+	for i, s := range stats {
+		for reason, n := range s.Closes {
+			reasonLabel := `reason="` + promEscape(CloseReason(reason).String()) + `"`
+			if labels[i] != "" {
+				reasonLabel = labels[i] + "," + reasonLabel
+			}
+			fmt.Fprintf(bw, "pool_closes_total{%s} %d\n", reasonLabel, n)
+		}
+	}
+	return bw.Flush()
+}
+
+// PrometheusHandler 返回以Prometheus文本格式输出各连接池统计信息的http.Handler，可挂载到 /metrics
+func PrometheusHandler(pools ...Pooler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		WritePrometheus(w, pools...)
+	})
+}
+
+// promLabels 将标签格式化为 k1="v1",k2="v2"，按key排序，key中的非法字符替换为下划线
+func promLabels(labels map[string]string) string {
+	parts := make([]string, 0, len(labels))
+	for _, k := range sortedKeys(labels) {
+		parts = append(parts, promLabelName(k)+`="`+promEscape(labels[k])+`"`)
+	}
+	return strings.Join(parts, ",")
+}
+
+func promBraces(labels string) string {
+	if labels == "" {
+		return ""
+	}
+	return "{" + labels + "}"
+}
+
+// promLabelName 标签名只能包含字母、数字及下划线，且不能以数字开头
+func promLabelName(s string) string {
+	b := []byte(s)
+	for i, c := range b {
+		if c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || i > 0 && '0' <= c && c <= '9' {
+			continue
+		}
+		b[i] = '_'
+	}
+	return string(b)
+}
+
+var promEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// promEscape 转义标签值中的反斜杠、双引号及换行
+func promEscape(s string) string {
+	return promEscaper.Replace(s)
+}
@@ -0,0 +1,184 @@
+package pool
+
+import (
+	"bufio"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// ErrProxyHandshake 代理握手失败Error
+var ErrProxyHandshake = errors.New("pool: proxy handshake failed")
+
+// ProxyFactory 返回经由代理连接addr的工厂方法，生成的连接类型为net.Conn
+// proxyURL支持socks5://和http://两种协议，包含用户信息时进行代理认证，
+// WithHandshakeTimeout同时限制代理握手的时间
+func ProxyFactory(proxyURL *url.URL, addr string, opts ...TCPOption) Factory {
+	o := newTCPOptions(opts)
+	return func() (interface{}, error) {
+		var handshake func(net.Conn, *url.URL, string) (net.Conn, error)
+		switch proxyURL.Scheme {
+		case "socks5", "socks5h":
+			handshake = socks5Handshake
+		case "http":
+			handshake = connectHandshake
+		default:
+			return nil, fmt.Errorf("pool: unsupported proxy scheme %q", proxyURL.Scheme)
+		}
+
+		conn, err := o.dial(proxyURL.Host)
+		if err != nil {
+			return nil, err
+		}
+		if o.handshakeTimeout > 0 {
+			conn.SetDeadline(time.Now().Add(o.handshakeTimeout))
+		}
+		tunnel, err := handshake(conn, proxyURL, addr)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		if o.handshakeTimeout > 0 {
+			conn.SetDeadline(time.Time{})
+		}
+		return tunnel, nil
+	}
+}
+
+// socks5Handshake 按RFC 1928/1929完成SOCKS5认证及CONNECT
+func socks5Handshake(conn net.Conn, proxyURL *url.URL, addr string) (net.Conn, error) {
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return nil, err
+	}
+
+	methods := []byte{0x00}
+	if proxyURL.User != nil {
+		methods = []byte{0x00, 0x02}
+	}
+	if _, err := conn.Write(append([]byte{0x05, byte(len(methods))}, methods...)); err != nil {
+		return nil, err
+	}
+	var reply [2]byte
+	if _, err := io.ReadFull(conn, reply[:]); err != nil {
+		return nil, err
+	}
+	switch {
+	case reply[0] != 0x05:
+		return nil, ErrProxyHandshake
+	case reply[1] == 0x02 && proxyURL.User != nil:
+		user := proxyURL.User.Username()
+		pass, _ := proxyURL.User.Password()
+		req := []byte{0x01, byte(len(user))}
+		req = append(req, user...)
+		req = append(req, byte(len(pass)))
+		req = append(req, pass...)
+		if _, err := conn.Write(req); err != nil {
+			return nil, err
+		}
+		if _, err := io.ReadFull(conn, reply[:]); err != nil {
+			return nil, err
+		}
+		if reply[1] != 0x00 {
+			return nil, fmt.Errorf("%w: socks5 authentication rejected", ErrProxyHandshake)
+		}
+	case reply[1] != 0x00:
+		return nil, fmt.Errorf("%w: no acceptable socks5 auth method", ErrProxyHandshake)
+	}
+
+	req := []byte{0x05, 0x01, 0x00}
+	if ip := net.ParseIP(host); ip == nil {
+		req = append(req, 0x03, byte(len(host)))
+		req = append(req, host...)
+	} else if ip4 := ip.To4(); ip4 != nil {
+		req = append(req, 0x01)
+		req = append(req, ip4...)
+	} else {
+		req = append(req, 0x04)
+		req = append(req, ip.To16()...)
+	}
+	req = append(req, byte(port>>8), byte(port))
+	if _, err := conn.Write(req); err != nil {
+		return nil, err
+	}
+
+	var head [4]byte
+	if _, err := io.ReadFull(conn, head[:]); err != nil {
+		return nil, err
+	}
+	if head[0] != 0x05 || head[1] != 0x00 {
+		return nil, fmt.Errorf("%w: socks5 connect failed with code %d", ErrProxyHandshake, head[1])
+	}
+	var skip int
+	switch head[3] {
+	case 0x01:
+		skip = net.IPv4len
+	case 0x04:
+		skip = net.IPv6len
+	case 0x03:
+		var n [1]byte
+		if _, err := io.ReadFull(conn, n[:]); err != nil {
+			return nil, err
+		}
+		skip = int(n[0])
+	default:
+		return nil, ErrProxyHandshake
+	}
+	// 跳过绑定地址及端口
+	if _, err := io.ReadFull(conn, make([]byte, skip+2)); err != nil {
+		return nil, err
+	}
+	return conn, nil
+}
+
+// connectHandshake 通过HTTP CONNECT建立隧道
+func connectHandshake(conn net.Conn, proxyURL *url.URL, addr string) (net.Conn, error) {
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+	if proxyURL.User != nil {
+		pass, _ := proxyURL.User.Password()
+		auth := proxyURL.User.Username() + ":" + pass
+		req.Header.Set("Proxy-Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(auth)))
+	}
+	if err := req.Write(conn); err != nil {
+		return nil, err
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: proxy returned %s", ErrProxyHandshake, resp.Status)
+	}
+	if br.Buffered() > 0 {
+		return &bufferedConn{Conn: conn, r: br}, nil
+	}
+	return conn, nil
+}
+
+// bufferedConn 读取代理响应时多读的数据先从r中返回
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
@@ -0,0 +1,88 @@
+package pool_test
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/hms58/pool"
+)
+
+// startEcho 启动回显服务
+func startEcho(t *testing.T) net.Listener {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				io.Copy(conn, conn)
+				conn.Close()
+			}()
+		}
+	}()
+	return ln
+}
+
+func TestProxyFactoryConnect(t *testing.T) {
+	echo := startEcho(t)
+	defer echo.Close()
+
+	gotAuth := make(chan string, 1)
+	proxy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.Close()
+	go func() {
+		conn, err := proxy.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		req, err := http.ReadRequest(bufio.NewReader(conn))
+		if err != nil {
+			return
+		}
+		gotAuth <- req.Header.Get("Proxy-Authorization")
+		target, err := net.Dial("tcp", req.Host)
+		if err != nil {
+			return
+		}
+		defer target.Close()
+		io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n")
+		go io.Copy(target, conn)
+		io.Copy(conn, target)
+	}()
+
+	proxyURL := &url.URL{Scheme: "http", Host: proxy.Addr().String(), User: url.UserPassword("u", "p")}
+	cn, err := pool.ProxyFactory(proxyURL, echo.Addr().String())()
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn := cn.(net.Conn)
+	defer conn.Close()
+
+	io.WriteString(conn, "ping")
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(conn, buf); err != nil || string(buf) != "ping" {
+		t.Errorf("echo through proxy = %q, %v", buf, err)
+	}
+	if auth := <-gotAuth; auth != "Basic dTpw" {
+		t.Errorf("Proxy-Authorization = %q, want Basic dTpw", auth)
+	}
+}
+
+func TestProxyFactoryUnsupported(t *testing.T) {
+	if _, err := pool.ProxyFactory(&url.URL{Scheme: "ftp", Host: "x:1"}, "y:2")(); err == nil {
+		t.Error("ProxyFactory() with ftp scheme succeeded")
+	}
+}
@@ -0,0 +1,22 @@
+package pool
+
+import "context"
+
+// qosLimit 返回class最多可同时借出的连接数，未配置QoSReserved时返回0表示不限制
+// 未预留容量的class只能使用总容量减去所有预留后的部分，预留了容量的class额外可使用自己的预留
+func (c *ChannelPool) qosLimit(class string) int32 {
+	if c.qosReservedTotal == 0 {
+		return 0
+	}
+	capacity := c.limit()
+	if capacity == 0 {
+		capacity = int32(c.maxCap)
+	}
+	return capacity - c.qosReservedTotal + int32(c.qosReserved[class])
+}
+
+// GetQoS 以QoS等级class取一个连接，借出连接数达到该等级的上限时与连接池已满的处理相同
+// 开启Wait时预留容量是尽力而为的，等待者之间不区分等级
+func (c *ChannelPool) GetQoS(ctx context.Context, class string) (interface{}, error) {
+	return c.get(ctx, c.qosLimit(class), nil)
+}
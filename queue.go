@@ -0,0 +1,44 @@
+package pool
+
+// idleQueue 存放空闲连接的容器，所有操作均不阻塞
+type idleQueue interface {
+	// push 放入一条空闲连接，已满时返回false
+	push(*idleConn) bool
+	// pop 取出一条空闲连接，为空时返回nil
+	pop() *idleConn
+	len() int
+	cap() int
+}
+
+// chanQueue 基于channel实现的idleQueue
+type chanQueue chan *idleConn
+
+func newChanQueue(capacity int) idleQueue {
+	return make(chanQueue, capacity)
+}
+
+func (q chanQueue) push(wrapConn *idleConn) bool {
+	select {
+	case q <- wrapConn:
+		return true
+	default:
+		return false
+	}
+}
+
+func (q chanQueue) pop() *idleConn {
+	select {
+	case wrapConn := <-q:
+		return wrapConn
+	default:
+		return nil
+	}
+}
+
+func (q chanQueue) len() int {
+	return len(q)
+}
+
+func (q chanQueue) cap() int {
+	return cap(q)
+}
@@ -0,0 +1,109 @@
+package pool
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// tokenBucket 令牌桶，每秒补充rate个令牌，最多积累burst个
+// 令牌数可以为负，表示已被预约、等待补充的令牌
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// newTokenBucket 创建初始时装满的令牌桶，burst小于1时取rate，至少为1
+func newTokenBucket(rate float64, burst int, now time.Time) *tokenBucket {
+	b := float64(burst)
+	if b < 1 {
+		b = rate
+	}
+	if b < 1 {
+		b = 1
+	}
+	return &tokenBucket{rate: rate, burst: b, tokens: b, last: now}
+}
+
+// reserve 预约一个令牌，返回需要等待的时长；需要等待超过maxWait时不预约并返回false，maxWait小于0时不限
+func (tb *tokenBucket) reserve(now time.Time, maxWait time.Duration) (time.Duration, bool) {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	if elapsed := now.Sub(tb.last); elapsed > 0 {
+		tb.tokens += elapsed.Seconds() * tb.rate
+		if tb.tokens > tb.burst {
+			tb.tokens = tb.burst
+		}
+		tb.last = now
+	}
+	var wait time.Duration
+	if tb.tokens < 1 {
+		wait = time.Duration((1 - tb.tokens) / tb.rate * float64(time.Second))
+	}
+	if maxWait >= 0 && wait > maxWait {
+		return 0, false
+	}
+	tb.tokens--
+	return wait, true
+}
+
+// cancel 归还预约后未使用的令牌
+func (tb *tokenBucket) cancel() {
+	tb.mu.Lock()
+	if tb.tokens++; tb.tokens > tb.burst {
+		tb.tokens = tb.burst
+	}
+	tb.mu.Unlock()
+}
+
+// waitToken 按GetRateLimit取一个令牌，需要时阻塞等待；ctx截止或WaitTimeout之前
+// 无法取得时直接返回ErrRateLimited
+func (c *ChannelPool) waitToken(ctx context.Context) error {
+	maxWait := time.Duration(-1)
+	if deadline, ok := ctx.Deadline(); ok {
+		maxWait = time.Until(deadline)
+	}
+	if wt := c.loadWaitTimeout(); c.wait && wt > 0 && (maxWait < 0 || wt < maxWait) {
+		maxWait = wt
+	}
+	delay, ok := c.limiter.reserve(c.now(), maxWait)
+	if ok && delay <= 0 {
+		return nil
+	}
+	if instrumented {
+		atomic.AddUint64(&c.counters.shard().rateLimited, 1)
+	}
+	if !ok {
+		return ErrRateLimited
+	}
+
+	// 令牌桶按c.now()计时，等待也使用同一个时钟
+	ready := make(chan struct{})
+	stop := c.timeSource.AfterFunc(delay, func() { close(ready) })
+	defer stop()
+	select {
+	case <-ready:
+		return nil
+	case <-ctx.Done():
+		c.limiter.cancel()
+		return ctx.Err()
+	case <-c.done:
+		c.limiter.cancel()
+		return ErrClosed
+	}
+}
+
+// takeToken 不等待地取一个令牌，用于TryGet、GetIdle等非阻塞的取连接方法
+func (c *ChannelPool) takeToken() error {
+	if _, ok := c.limiter.reserve(c.now(), 0); !ok {
+		if instrumented {
+			atomic.AddUint64(&c.counters.shard().rateLimited, 1)
+		}
+		return ErrRateLimited
+	}
+	return nil
+}
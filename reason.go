@@ -0,0 +1,70 @@
+package pool
+
+// CloseReason 连接被连接池关闭的原因
+type CloseReason int
+
+const (
+	// CloseIdleTimeout 空闲时间超过IdleTimeout
+	CloseIdleTimeout CloseReason = iota
+	// CloseLifetime 存活时间超过MaxLifetime
+	CloseLifetime
+	// ClosePoolFull 放回时空闲连接已达上限
+	ClosePoolFull
+	// CloseOverflow 放回的是超出MaxCap的溢出连接
+	CloseOverflow
+	// CloseValidation 取出时Ping校验或OnBorrow失败
+	CloseValidation
+	// CloseRelease 连接池已释放
+	CloseRelease
+	// CloseBroken 调用方通过Close或连接自身读写出错标记为不可用
+	CloseBroken
+	// CloseEvicted 调用方通过EvictWhere主动淘汰，或GetWithOptions为新建足够新的连接腾出名额
+	CloseEvicted
+	// CloseDrained 连接池处于排空状态，或生成该连接的工厂方法已被SetFactory替换
+	CloseDrained
+	// CloseReset 放回时Reset失败
+	CloseReset
+	// CloseKeepAlive 空闲时保活探测失败
+	CloseKeepAlive
+	// CloseLeaseExpired 租约到期未续约或归还，被强制回收
+	CloseLeaseExpired
+	// CloseForced 通过ForceClose强制关闭
+	CloseForced
+	// CloseWatchdog 连接池持续不健康，看门狗重建时关闭
+	CloseWatchdog
+	// CloseHandoff 通过ExportIdle交给其他进程后关闭本进程中的副本
+	CloseHandoff
+	// CloseRotated 通过Rotate轮换淘汰
+	CloseRotated
+	// CloseFlushed 短时间内失败过多，按FlushOnErrors清空空闲连接
+	CloseFlushed
+
+	closeReasonMax
+)
+
+var closeReasonNames = [closeReasonMax]string{
+	CloseIdleTimeout:  "idle timeout",
+	CloseLifetime:     "lifetime",
+	ClosePoolFull:     "pool full",
+	CloseOverflow:     "overflow",
+	CloseValidation:   "validation failed",
+	CloseRelease:      "release",
+	CloseBroken:       "broken",
+	CloseEvicted:      "evicted",
+	CloseDrained:      "drained",
+	CloseReset:        "reset failed",
+	CloseKeepAlive:    "keepalive failed",
+	CloseLeaseExpired: "lease expired",
+	CloseForced:       "forced",
+	CloseWatchdog:     "watchdog",
+	CloseHandoff:      "handoff",
+	CloseRotated:      "rotated",
+	CloseFlushed:      "flushed",
+}
+
+func (r CloseReason) String() string {
+	if r < 0 || r >= closeReasonMax {
+		return "unknown"
+	}
+	return closeReasonNames[r]
+}
@@ -0,0 +1,15 @@
+package pool
+
+import "time"
+
+// StartStatsReporter 每隔interval获取一次统计信息并调用fn，fn为空时输出到日志
+// 每次回调后IdleLow/IdleHigh重置为当前值，反映的是该区间内的高低水位，连接池Release后自动停止
+func (p *ChannelPool) StartStatsReporter(interval time.Duration, fn func(*Stats)) {
+	if fn == nil {
+		fn = p.logStats
+	}
+	p.every(interval, func() {
+		fn(p.Stats())
+		p.resetIdleDepth()
+	})
+}
@@ -0,0 +1,82 @@
+package pool
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// Reservation Reserve占用的新建连接名额，需调用Dial或Cancel之一
+type Reservation struct {
+	p    *ChannelPool
+	n    int32
+	used int32
+}
+
+// Reserve 占用一个新建连接的名额，调用方可在完成准备工作后再Dial，放弃时Cancel
+// 名额已满时的处理与Get相同：未开启Wait时返回ErrPoolExhausted，否则等待
+func (c *ChannelPool) Reserve(ctx context.Context) (*Reservation, error) {
+	if c.getConns() == nil {
+		return nil, c.stateErr()
+	}
+
+	var timeout <-chan time.Time
+	waiting := false
+	defer func() {
+		if waiting {
+			atomic.AddInt32(&c.waiters, -1)
+		}
+	}()
+
+	for {
+		if n, ok := c.reserve(); ok {
+			if waiting {
+				c.notifyWaiter()
+			}
+			return &Reservation{p: c, n: n}, nil
+		}
+		if !c.wait {
+			c.exhausted()
+			return nil, ErrPoolExhausted
+		}
+
+		if !waiting {
+			waiting = true
+			updateMax(&c.maxWaiters, atomic.AddInt32(&c.waiters, 1))
+			c.exhausted()
+			continue
+		}
+		if waitTimeout := c.loadWaitTimeout(); timeout == nil && waitTimeout > 0 {
+			timer := time.NewTimer(waitTimeout)
+			defer timer.Stop()
+			timeout = timer.C
+		}
+		if err := c.awaitAvail(ctx, timeout); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Dial 使用占用的名额新建连接，创建失败时释放名额并返回*GetError，
+// 重复调用返回ErrReservationUsed
+func (r *Reservation) Dial() (interface{}, error) {
+	if !atomic.CompareAndSwapInt32(&r.used, 0, 1) {
+		return nil, ErrReservationUsed
+	}
+	if r.p.getConns() == nil {
+		r.p.unreserve()
+		return nil, r.p.stateErr()
+	}
+	conn, err := r.p.dial(r.n)
+	if err != nil {
+		return nil, getErr(err)
+	}
+	return conn, nil
+}
+
+// Cancel 释放占用的名额，Dial之后调用无效
+func (r *Reservation) Cancel() {
+	if atomic.CompareAndSwapInt32(&r.used, 0, 1) {
+		r.p.unreserve()
+	}
+}
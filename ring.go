@@ -0,0 +1,94 @@
+package pool
+
+import "sync/atomic"
+
+// NewRingPool 初始化基于无锁环形队列的连接池，适用于极高频率的Get/Put
+func NewRingPool(poolConfig *PoolConfig) Pooler {
+	return newPool(poolConfig, newRingQueue)
+}
+
+// ringQueue 有界多生产者多消费者无锁队列（Dmitry Vyukov算法）
+type ringQueue struct {
+	_     [56]byte
+	head  uint64
+	_     [56]byte
+	tail  uint64
+	_     [56]byte
+	mask  uint64
+	size  int
+	cells []ringCell
+}
+
+type ringCell struct {
+	seq      uint64
+	wrapConn *idleConn
+}
+
+func newRingQueue(capacity int) idleQueue {
+	n := 1
+	for n < capacity {
+		n <<= 1
+	}
+	q := &ringQueue{
+		mask:  uint64(n - 1),
+		size:  capacity,
+		cells: make([]ringCell, n),
+	}
+	for i := range q.cells {
+		q.cells[i].seq = uint64(i)
+	}
+	return q
+}
+
+func (q *ringQueue) push(wrapConn *idleConn) bool {
+	pos := atomic.LoadUint64(&q.tail)
+	for {
+		cell := &q.cells[pos&q.mask]
+		seq := atomic.LoadUint64(&cell.seq)
+		switch dif := int64(seq - pos); {
+		case dif == 0:
+			if atomic.CompareAndSwapUint64(&q.tail, pos, pos+1) {
+				cell.wrapConn = wrapConn
+				atomic.StoreUint64(&cell.seq, pos+1)
+				return true
+			}
+		case dif < 0:
+			// 队列已满
+			return false
+		}
+		pos = atomic.LoadUint64(&q.tail)
+	}
+}
+
+func (q *ringQueue) pop() *idleConn {
+	pos := atomic.LoadUint64(&q.head)
+	for {
+		cell := &q.cells[pos&q.mask]
+		seq := atomic.LoadUint64(&cell.seq)
+		switch dif := int64(seq - (pos + 1)); {
+		case dif == 0:
+			if atomic.CompareAndSwapUint64(&q.head, pos, pos+1) {
+				wrapConn := cell.wrapConn
+				cell.wrapConn = nil
+				atomic.StoreUint64(&cell.seq, pos+q.mask+1)
+				return wrapConn
+			}
+		case dif < 0:
+			// 队列为空
+			return nil
+		}
+		pos = atomic.LoadUint64(&q.head)
+	}
+}
+
+func (q *ringQueue) len() int {
+	n := int64(atomic.LoadUint64(&q.tail) - atomic.LoadUint64(&q.head))
+	if n < 0 {
+		return 0
+	}
+	return int(n)
+}
+
+func (q *ringQueue) cap() int {
+	return q.size
+}
@@ -0,0 +1,60 @@
+package pool
+
+import (
+	"log/slog"
+	"sort"
+)
+
+// Rotate 以CloseRotated关闭至多n条创建最早的空闲连接，并在后台按WarmupRate新建同样数量的连接补充，
+// 用于证书轮换或服务端配置变更后逐步淘汰旧会话，返回关闭的连接数
+// 借出中的连接不受影响，可多次调用直到所有旧连接都被轮换
+func (c *ChannelPool) Rotate(n int) int {
+	conns := c.getConns()
+	if n <= 0 || conns == nil {
+		return 0
+	}
+	c.flushStash()
+
+	var candidates []*idleConn
+	for k := conns.len(); k > 0; k-- {
+		wrapConn := conns.pop()
+		if wrapConn == nil {
+			break
+		}
+		candidates = append(candidates, wrapConn)
+	}
+
+	oldest := make([]*idleConn, len(candidates))
+	copy(oldest, candidates)
+	sort.SliceStable(oldest, func(i, j int) bool {
+		return oldest[i].created.Before(oldest[j].created)
+	})
+	if len(oldest) > n {
+		oldest = oldest[:n]
+	}
+	retired := make(map[*idleConn]bool, len(oldest))
+	for _, wrapConn := range oldest {
+		retired[wrapConn] = true
+	}
+
+	rotated := 0
+	for _, wrapConn := range candidates {
+		if retired[wrapConn] {
+			c.discard(wrapConn, CloseRotated)
+			rotated++
+			continue
+		}
+		if c.idleLen(conns) < c.loadMaxIdle() && conns.push(wrapConn) {
+			continue
+		}
+		c.discard(wrapConn, ClosePoolFull)
+	}
+	c.drainIfClosed()
+	c.notifyWaiter()
+
+	if rotated > 0 {
+		c.logAttrs(slog.LevelInfo, "rotating connections", slog.Int("conns", rotated))
+		go c.warmup(rotated, c.warmupRate)
+	}
+	return rotated
+}
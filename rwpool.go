@@ -0,0 +1,67 @@
+package pool
+
+import "context"
+
+// RWPool 读写分离连接池，读连接来自只读副本，写连接来自主库，
+// 读连接池不可用时读请求回退到写连接池
+// 连接需可作为map的key，以便区分所属的连接池
+type RWPool struct {
+	read  *ChannelPool
+	write *ChannelPool
+}
+
+// NewRWPool 使用读、写两份配置初始化读写分离连接池
+func NewRWPool(readConfig, writeConfig *PoolConfig) *RWPool {
+	return &RWPool{
+		read:  newPool(readConfig, newChanQueue),
+		write: newPool(writeConfig, newChanQueue),
+	}
+}
+
+// GetRead 取一个读连接，读连接池创建连接失败、已满或超时时回退到写连接池
+func (p *RWPool) GetRead(ctx context.Context) (interface{}, error) {
+	conn, err := p.read.GetContext(ctx)
+	if err == nil || ctx.Err() != nil {
+		return conn, err
+	}
+	return p.write.GetContext(ctx)
+}
+
+// GetWrite 取一个写连接
+func (p *RWPool) GetWrite(ctx context.Context) (interface{}, error) {
+	return p.write.GetContext(ctx)
+}
+
+// owner 返回连接所属的连接池
+func (p *RWPool) owner(conn interface{}) *ChannelPool {
+	if p.read.owns(conn) {
+		return p.read
+	}
+	return p.write
+}
+
+// Put 将连接放回其所属的连接池
+func (p *RWPool) Put(conn interface{}) error {
+	return p.owner(conn).Put(conn)
+}
+
+// Close 关闭连接并从其所属的连接池中移除
+func (p *RWPool) Close(conn interface{}) error {
+	return p.owner(conn).Close(conn)
+}
+
+// Read 返回读连接池
+func (p *RWPool) Read() *ChannelPool {
+	return p.read
+}
+
+// Write 返回写连接池
+func (p *RWPool) Write() *ChannelPool {
+	return p.write
+}
+
+// Release 释放读写两个连接池
+func (p *RWPool) Release() {
+	p.read.Release()
+	p.write.Release()
+}
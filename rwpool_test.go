@@ -0,0 +1,48 @@
+package pool_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/hms58/pool"
+)
+
+func TestRWPool(t *testing.T) {
+	readUp := true
+	readCfg := &pool.PoolConfig{
+		MaxCap: 2,
+		Factory: func() (interface{}, error) {
+			if !readUp {
+				return nil, errors.New("replica down")
+			}
+			return &testConn{id: 1}, nil
+		},
+	}
+	writeCfg := &pool.PoolConfig{
+		MaxCap:  2,
+		Factory: func() (interface{}, error) { return &testConn{id: 2}, nil },
+	}
+	p := pool.NewRWPool(readCfg, writeCfg)
+	defer p.Release()
+
+	r, _ := p.GetRead(context.Background())
+	if r.(*testConn).id != 1 {
+		t.Errorf("GetRead() got conn from pool %d, want read pool", r.(*testConn).id)
+	}
+	if err := p.Close(r); err != nil {
+		t.Fatal(err)
+	}
+
+	readUp = false
+	r, err := p.GetRead(context.Background())
+	if err != nil || r.(*testConn).id != 2 {
+		t.Fatalf("GetRead() with replica down = %v, %v, want write conn", r, err)
+	}
+	if err := p.Put(r); err != nil {
+		t.Fatal(err)
+	}
+	if p.Write().Len() != 1 || p.Read().Len() != 0 {
+		t.Errorf("read Len=%d write Len=%d, want 0 1", p.Read().Len(), p.Write().Len())
+	}
+}
@@ -0,0 +1,84 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// LabeledFactory 生成连接并返回其标签的方法，标签描述连接的能力，如 tls=true、db=analytics
+type LabeledFactory func() (interface{}, map[string]string, error)
+
+// unlabeled 将Factory包装为不带标签的LabeledFactory
+func unlabeled(f Factory) LabeledFactory {
+	if f == nil {
+		return nil
+	}
+	return func() (interface{}, map[string]string, error) {
+		conn, err := f()
+		return conn, nil, err
+	}
+}
+
+// Selector 连接标签选择器，连接的标签包含其中所有键值对时匹配，空选择器匹配所有连接
+type Selector map[string]string
+
+// ParseSelector 解析 "tls=true,db=analytics" 形式的选择器
+func ParseSelector(s string) (Selector, error) {
+	sel := make(Selector)
+	for _, pair := range strings.Split(s, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(k) == "" {
+			return nil, fmt.Errorf("pool: invalid selector %q", pair)
+		}
+		sel[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	return sel, nil
+}
+
+// Matches 判断labels是否包含选择器的所有键值对
+func (s Selector) Matches(labels map[string]string) bool {
+	for k, v := range s {
+		if got, ok := labels[k]; !ok || got != v {
+			return false
+		}
+	}
+	return true
+}
+
+// GetMatching 返回标签匹配sel的连接：优先取匹配的空闲连接，没有时与Get相同地新建或等待，
+// 等待期间只接受匹配的空闲连接；新建的连接不匹配时放入空闲连接供其他调用方使用，并返回ErrNoMatchingConn
+// 标签由PoolConfig.LabeledFactory在新建时给出，连接需可作为map的key
+func (c *ChannelPool) GetMatching(ctx context.Context, sel Selector) (interface{}, error) {
+	conn, err := c.get(ctx, c.qosLimit(""), &idleFilter{
+		match: func(wrapConn *idleConn) bool {
+			return sel.Matches(wrapConn.labels)
+		},
+		strict: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !sel.Matches(c.connLabels(conn)) {
+		c.Put(conn)
+		return nil, getErr(ErrNoMatchingConn)
+	}
+	return conn, nil
+}
+
+// connLabels 返回借出的连接的标签
+func (c *ChannelPool) connLabels(conn interface{}) map[string]string {
+	if !trackable(conn) {
+		return nil
+	}
+	var labels map[string]string
+	c.tracked.lookup(trackKey(conn), func(wrapConn *idleConn) {
+		if wrapConn != nil {
+			labels = wrapConn.labels
+		}
+	})
+	return labels
+}
@@ -0,0 +1,131 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// logAttrs 输出结构化日志，配置了Slog时交由slog输出，否则格式化为key=value后交由logf输出
+func (c *ChannelPool) logAttrs(level slog.Level, msg string, attrs ...slog.Attr) {
+	if c.slog != nil {
+		c.slog.LogAttrs(context.Background(), level, msg, attrs...)
+		return
+	}
+	var b strings.Builder
+	b.WriteString("pool: ")
+	b.WriteString(msg)
+	for _, attr := range attrs {
+		fmt.Fprintf(&b, " %s=%v", attr.Key, attr.Value)
+	}
+	c.logf("%s", b.String())
+}
+
+// slogEnabled 配置了Slog且启用了level，连接关闭、拨号失败等高频记录只输出到slog
+func (c *ChannelPool) slogEnabled(level slog.Level) bool {
+	return c.slog != nil && c.slog.Enabled(context.Background(), level)
+}
+
+// connAttrs 连接的标识及时长属性
+func (c *ChannelPool) connAttrs(wrapConn *idleConn) []slog.Attr {
+	now := c.now()
+	return []slog.Attr{
+		slog.Uint64("conn_id", wrapConn.id),
+		slog.Duration("age", now.Sub(wrapConn.created)),
+		slog.Duration("idle", now.Sub(wrapConn.t)),
+	}
+}
+
+// ConnID 返回连接的编号，与ConnInfo.ID、Event.ConnID及日志中的conn_id一致，
+// 连接已关闭或无法追踪时返回0
+func (c *ChannelPool) ConnID(conn interface{}) uint64 {
+	if !trackable(conn) {
+		return 0
+	}
+	var id uint64
+	c.tracked.lookup(trackKey(conn), func(wrapConn *idleConn) {
+		if wrapConn != nil {
+			id = wrapConn.id
+		}
+	})
+	return id
+}
+
+// logCreated 记录新建的连接
+func (c *ChannelPool) logCreated(wrapConn *idleConn) {
+	if !c.slogEnabled(slog.LevelDebug) {
+		return
+	}
+	c.logAttrs(slog.LevelDebug, "connection created", slog.Uint64("conn_id", wrapConn.id))
+}
+
+// logClosed 记录连接池主动关闭的连接
+func (c *ChannelPool) logClosed(wrapConn *idleConn, reason CloseReason) {
+	if !c.slogEnabled(slog.LevelDebug) {
+		return
+	}
+	attrs := append(c.connAttrs(wrapConn), slog.String("reason", reason.String()))
+	c.logAttrs(slog.LevelDebug, "connection closed", attrs...)
+}
+
+// logDialFailed 记录工厂方法返回的错误
+func (c *ChannelPool) logDialFailed(err error, took time.Duration) {
+	if !c.slogEnabled(slog.LevelWarn) {
+		return
+	}
+	c.logAttrs(slog.LevelWarn, "dial failed", slog.Any("error", err), slog.Duration("took", took))
+}
+
+// statsAttrs 统计信息的结构化属性
+func statsAttrs(stats *Stats) []slog.Attr {
+	attrs := []slog.Attr{
+		slog.Uint64("total_conns", uint64(stats.TotalConns)),
+		slog.Uint64("idle_conns", uint64(stats.IdleConns)),
+		slog.Uint64("busy_conns", uint64(stats.BusyConns)),
+		slog.Uint64("dialing_conns", uint64(stats.DialingConns)),
+		slog.Uint64("hits", stats.Hits),
+		slog.Uint64("misses", stats.Misses),
+		slog.Uint64("stale_hits", stats.StaleHits),
+		slog.Uint64("stale_skips", stats.StaleSkips),
+		slog.Uint64("overflows", stats.Overflows),
+		slog.Uint64("dial_error_cache_hits", stats.DialErrorCacheHits),
+		slog.Uint64("shed_waiters", stats.ShedWaiters),
+		slog.Uint64("rate_limited", stats.RateLimited),
+		slog.Uint64("recovered", stats.Recovered),
+		slog.Uint64("wait_count", stats.WaitCount),
+		slog.Duration("wait_duration", stats.WaitDuration),
+		slog.Uint64("dial_count", stats.DialCount),
+		slog.Uint64("dial_errors", stats.DialErrors),
+		slog.Duration("dial_duration", stats.DialDuration),
+		slog.Duration("max_dial_duration", stats.MaxDialDuration),
+		slog.Uint64("waiters", uint64(stats.Waiters)),
+		slog.Uint64("max_busy", uint64(stats.MaxBusy)),
+		slog.Uint64("max_waiters", uint64(stats.MaxWaiters)),
+		slog.Uint64("idle_low", uint64(stats.IdleLow)),
+		slog.Uint64("idle_high", uint64(stats.IdleHigh)),
+		slog.Uint64("stale_conns", stats.StaleConns),
+		slog.Uint64("full_discards", stats.FullDiscards),
+	}
+	closes := make([]any, 0, len(stats.Closes))
+	for reason, n := range stats.Closes {
+		closes = append(closes, slog.Uint64(metricName(CloseReason(reason).String()), n))
+	}
+	dialDurations := make([]any, 0, dialBucketMax)
+	for i, name := range dialBucketNames {
+		dialDurations = append(dialDurations, slog.Uint64(name, stats.DialDurations[i]))
+	}
+	idleAges := make([]any, 0, ageBucketMax)
+	connAges := make([]any, 0, ageBucketMax)
+	for i, name := range ageBucketNames {
+		idleAges = append(idleAges, slog.Uint64(name, uint64(stats.IdleAges[i])))
+		connAges = append(connAges, slog.Uint64(name, uint64(stats.ConnAges[i])))
+	}
+	return append(attrs,
+		slog.Group("closes", closes...),
+		slog.Group("dial_durations", dialDurations...),
+		slog.Group("idle_ages", idleAges...),
+		slog.Group("conn_ages", connAges...),
+	)
+}
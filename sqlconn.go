@@ -0,0 +1,200 @@
+package pool
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// SQLConfig database/sql风格的连接池配置
+type SQLConfig struct {
+	// 最大打开连接数，达到上限后Connect阻塞等待，为0时不限制
+	MaxOpenConns int
+	// 保留的最大空闲连接数，为0时默认2，为负数时不保留空闲连接
+	MaxIdleConns int
+	// 连接最大存活时间，为0时不限制
+	ConnMaxLifetime time.Duration
+	// 连接最大空闲时间，为0时不限制
+	ConnMaxIdleTime time.Duration
+}
+
+// defaultMaxIdleConns 与database/sql一致的默认空闲连接数
+const defaultMaxIdleConns = 2
+
+// PoolConfig 将database/sql的语义映射为连接池配置
+func (s SQLConfig) PoolConfig(factory Factory, close func(interface{}) error) *PoolConfig {
+	maxIdle := s.MaxIdleConns
+	if maxIdle == 0 {
+		maxIdle = defaultMaxIdleConns
+	}
+	if maxIdle < 0 {
+		// 不保留空闲连接由Connector在放回时关闭，此处只需容纳一个连接
+		maxIdle = 1
+	}
+	cfg := &PoolConfig{
+		MaxCap:      maxIdle,
+		MaxIdle:     maxIdle,
+		Factory:     factory,
+		Close:       close,
+		IdleTimeout: s.ConnMaxIdleTime,
+		MaxLifetime: s.ConnMaxLifetime,
+	}
+	if s.MaxOpenConns > 0 {
+		cfg.MaxCap = s.MaxOpenConns
+		cfg.Wait = true
+	}
+	return cfg
+}
+
+// Connector 以连接池作为连接来源的driver.Connector，同时实现io.Closer，
+// 可直接传给sql.OpenDB，此时应调用db.SetMaxIdleConns(0)由连接池管理空闲连接
+type Connector struct {
+	pool      *ChannelPool
+	driver    driver.Driver
+	closeIdle bool
+}
+
+// NewConnector 以connector建立连接，按cfg创建连接池
+func NewConnector(connector driver.Connector, cfg SQLConfig) *Connector {
+	factory := func() (interface{}, error) {
+		return connector.Connect(context.Background())
+	}
+	closeConn := func(v interface{}) error {
+		return v.(driver.Conn).Close()
+	}
+	return &Connector{
+		pool:      New(cfg.PoolConfig(factory, closeConn)),
+		driver:    connector.Driver(),
+		closeIdle: cfg.MaxIdleConns < 0,
+	}
+}
+
+// Connect 从连接池取出连接，返回连接的Close将其放回连接池
+func (c *Connector) Connect(ctx context.Context) (driver.Conn, error) {
+	v, err := c.pool.GetContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlConn{Conn: v.(driver.Conn), c: c}, nil
+}
+
+// Driver 返回底层驱动
+func (c *Connector) Driver() driver.Driver {
+	return c.driver
+}
+
+// Close 释放连接池
+func (c *Connector) Close() error {
+	return c.pool.ReleaseErr()
+}
+
+// Pool 返回底层连接池
+func (c *Connector) Pool() *ChannelPool {
+	return c.pool
+}
+
+// sqlConn 借出的driver.Conn，转发database/sql使用的可选接口，
+// 返回driver.ErrBadConn或校验失败的连接在Close时关闭
+type sqlConn struct {
+	driver.Conn
+	c      *Connector
+	bad    int32
+	closed int32
+}
+
+// check 记录driver.ErrBadConn
+func (sc *sqlConn) check(err error) error {
+	if errors.Is(err, driver.ErrBadConn) {
+		atomic.StoreInt32(&sc.bad, 1)
+	}
+	return err
+}
+
+func (sc *sqlConn) Close() error {
+	if !atomic.CompareAndSwapInt32(&sc.closed, 0, 1) {
+		return driver.ErrBadConn
+	}
+	if sc.c.closeIdle || !sc.IsValid() {
+		return sc.c.pool.Close(sc.Conn)
+	}
+	err := sc.c.pool.Put(sc.Conn)
+	if errors.Is(err, ErrClosed) {
+		// 连接池已关闭时Put已关闭连接
+		return nil
+	}
+	return err
+}
+
+func (sc *sqlConn) Prepare(query string) (driver.Stmt, error) {
+	stmt, err := sc.Conn.Prepare(query)
+	return stmt, sc.check(err)
+}
+
+func (sc *sqlConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if p, ok := sc.Conn.(driver.ConnPrepareContext); ok {
+		stmt, err := p.PrepareContext(ctx, query)
+		return stmt, sc.check(err)
+	}
+	return sc.Prepare(query)
+}
+
+func (sc *sqlConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if b, ok := sc.Conn.(driver.ConnBeginTx); ok {
+		tx, err := b.BeginTx(ctx, opts)
+		return tx, sc.check(err)
+	}
+	if opts.ReadOnly || opts.Isolation != 0 {
+		return nil, errors.New("pool: driver does not support non-default transaction options")
+	}
+	tx, err := sc.Conn.Begin()
+	return tx, sc.check(err)
+}
+
+func (sc *sqlConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if e, ok := sc.Conn.(driver.ExecerContext); ok {
+		res, err := e.ExecContext(ctx, query, args)
+		return res, sc.check(err)
+	}
+	return nil, driver.ErrSkip
+}
+
+func (sc *sqlConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if q, ok := sc.Conn.(driver.QueryerContext); ok {
+		rows, err := q.QueryContext(ctx, query, args)
+		return rows, sc.check(err)
+	}
+	return nil, driver.ErrSkip
+}
+
+func (sc *sqlConn) Ping(ctx context.Context) error {
+	if p, ok := sc.Conn.(driver.Pinger); ok {
+		return sc.check(p.Ping(ctx))
+	}
+	return nil
+}
+
+func (sc *sqlConn) ResetSession(ctx context.Context) error {
+	if r, ok := sc.Conn.(driver.SessionResetter); ok {
+		return sc.check(r.ResetSession(ctx))
+	}
+	return nil
+}
+
+func (sc *sqlConn) IsValid() bool {
+	if atomic.LoadInt32(&sc.bad) == 1 {
+		return false
+	}
+	if v, ok := sc.Conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+func (sc *sqlConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if c, ok := sc.Conn.(driver.NamedValueChecker); ok {
+		return c.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
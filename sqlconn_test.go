@@ -0,0 +1,118 @@
+package pool_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/hms58/pool"
+)
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) { return nil, errors.New("not supported") }
+
+type fakeSQLConn struct {
+	closed *int32
+	err    error
+}
+
+func (c *fakeSQLConn) Prepare(string) (driver.Stmt, error) { return nil, c.err }
+func (c *fakeSQLConn) Begin() (driver.Tx, error)           { return nil, c.err }
+func (c *fakeSQLConn) Close() error {
+	atomic.AddInt32(c.closed, 1)
+	return nil
+}
+func (c *fakeSQLConn) Ping(context.Context) error { return c.err }
+
+type fakeConnector struct {
+	opened, closed int32
+	err            error
+}
+
+func (fc *fakeConnector) Connect(context.Context) (driver.Conn, error) {
+	atomic.AddInt32(&fc.opened, 1)
+	return &fakeSQLConn{closed: &fc.closed, err: fc.err}, nil
+}
+
+func (fc *fakeConnector) Driver() driver.Driver { return fakeDriver{} }
+
+func TestSQLConfigPoolConfig(t *testing.T) {
+	cfg := pool.SQLConfig{MaxOpenConns: 5}.PoolConfig(nil, nil)
+	if cfg.MaxCap != 5 || cfg.MaxIdle != 2 || !cfg.Wait {
+		t.Errorf("MaxOpenConns=5: MaxCap=%d MaxIdle=%d Wait=%v", cfg.MaxCap, cfg.MaxIdle, cfg.Wait)
+	}
+	cfg = pool.SQLConfig{MaxIdleConns: 4}.PoolConfig(nil, nil)
+	if cfg.MaxIdle != 4 || cfg.Wait {
+		t.Errorf("MaxIdleConns=4: MaxIdle=%d Wait=%v", cfg.MaxIdle, cfg.Wait)
+	}
+}
+
+func TestConnectorReuse(t *testing.T) {
+	fc := &fakeConnector{}
+	c := pool.NewConnector(fc, pool.SQLConfig{MaxOpenConns: 1})
+
+	for i := 0; i < 3; i++ {
+		conn, err := c.Connect(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn.Close()
+	}
+	if fc.opened != 1 {
+		t.Errorf("opened %d conns, want 1", fc.opened)
+	}
+
+	conn, _ := c.Connect(context.Background())
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.Connect(ctx); err == nil {
+		t.Error("Connect() beyond MaxOpenConns succeeded")
+	}
+	conn.Close()
+
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if fc.closed != 1 {
+		t.Errorf("closed %d conns, want 1", fc.closed)
+	}
+}
+
+func TestConnectorBadConn(t *testing.T) {
+	fc := &fakeConnector{err: driver.ErrBadConn}
+	c := pool.NewConnector(fc, pool.SQLConfig{})
+	defer c.Close()
+
+	conn, _ := c.Connect(context.Background())
+	if _, err := conn.Prepare("SELECT 1"); !errors.Is(err, driver.ErrBadConn) {
+		t.Fatalf("Prepare() err = %v", err)
+	}
+	conn.Close()
+	if fc.closed != 1 || c.Pool().Len() != 0 {
+		t.Errorf("bad conn closed=%d Len()=%d, want closed and not pooled", fc.closed, c.Pool().Len())
+	}
+}
+
+func TestConnectorOpenDB(t *testing.T) {
+	fc := &fakeConnector{}
+	c := pool.NewConnector(fc, pool.SQLConfig{MaxIdleConns: 1})
+	db := sql.OpenDB(c)
+	db.SetMaxIdleConns(0)
+
+	for i := 0; i < 3; i++ {
+		if err := db.Ping(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if fc.opened != 1 || c.Pool().Len() != 1 {
+		t.Errorf("opened=%d Len()=%d, want 1 and 1", fc.opened, c.Pool().Len())
+	}
+	db.Close()
+	if !c.Pool().IsClosed() {
+		t.Error("db.Close() did not release the pool")
+	}
+}
@@ -0,0 +1,72 @@
+package pool
+
+import "sync/atomic"
+
+// State 连接池的状态
+type State int32
+
+const (
+	// StateOpen 正常提供连接
+	StateOpen State = iota
+	// StateDraining 排空中，拒绝新的Get，放回的连接直接关闭
+	StateDraining
+	// StateClosed 已释放
+	StateClosed
+)
+
+var stateNames = [...]string{
+	StateOpen:     "open",
+	StateDraining: "draining",
+	StateClosed:   "closed",
+}
+
+func (s State) String() string {
+	if s < 0 || int(s) >= len(stateNames) {
+		return "unknown"
+	}
+	return stateNames[s]
+}
+
+// State 返回连接池当前状态
+func (c *ChannelPool) State() State {
+	return State(atomic.LoadInt32(&c.state))
+}
+
+// IsClosed 连接池是否已释放
+func (c *ChannelPool) IsClosed() bool {
+	return c.State() == StateClosed
+}
+
+// stateErr 返回当前状态下Get/Put应返回的错误，StateOpen时返回nil
+func (c *ChannelPool) stateErr() error {
+	switch c.State() {
+	case StateDraining:
+		return ErrDraining
+	case StateClosed:
+		return ErrClosed
+	}
+	return nil
+}
+
+// transition 将状态由from切换为to，切换成功时通知等待者并调用OnStateChange
+func (c *ChannelPool) transition(from, to State) bool {
+	if !atomic.CompareAndSwapInt32(&c.state, int32(from), int32(to)) {
+		return false
+	}
+	if from == StateOpen {
+		close(c.stopping)
+	}
+	if c.onStateChange != nil {
+		c.onStateChange(from, to)
+	}
+	return true
+}
+
+// Drain 将连接池切换为排空状态：关闭所有空闲连接，拒绝新的Get，
+// 借出的连接放回时关闭，之后仍需调用Release释放连接池
+func (c *ChannelPool) Drain() {
+	if !c.transition(StateOpen, StateDraining) {
+		return
+	}
+	c.drain(CloseDrained)
+}
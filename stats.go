@@ -0,0 +1,274 @@
+package pool
+
+import (
+	"math/rand/v2"
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+// dialBucketMax 工厂方法耗时分布的桶数
+const dialBucketMax = 5
+
+// DialBuckets Stats.DialDurations前几个桶的上界，最后一个桶为更慢的调用
+var DialBuckets = [dialBucketMax - 1]time.Duration{10 * time.Millisecond, 100 * time.Millisecond, time.Second, 10 * time.Second}
+
+// dialBucketNames 各桶的名称，用于日志及指标
+var dialBucketNames = [dialBucketMax]string{"10ms", "100ms", "1s", "10s", "inf"}
+
+// dialBucket 返回耗时d所在的桶
+func dialBucket(d time.Duration) int {
+	for i, bound := range DialBuckets {
+		if d < bound {
+			return i
+		}
+	}
+	return dialBucketMax - 1
+}
+
+// ageBucketMax 连接时长分布的桶数
+const ageBucketMax = 5
+
+// AgeBuckets Stats.IdleAges及Stats.ConnAges前几个桶的上界，最后一个桶为更久的连接
+var AgeBuckets = [ageBucketMax - 1]time.Duration{time.Second, 10 * time.Second, time.Minute, 10 * time.Minute}
+
+// ageBucketNames 各桶的名称，用于日志及指标
+var ageBucketNames = [ageBucketMax]string{"1s", "10s", "1m", "10m", "inf"}
+
+// ageBucket 返回时长d所在的桶
+func ageBucket(d time.Duration) int {
+	for i, bound := range AgeBuckets {
+		if d < bound {
+			return i
+		}
+	}
+	return ageBucketMax - 1
+}
+
+type Stats struct {
+	Hits   uint64 // number of times free connection was found in the pool
+	Misses uint64 // number of times free connection was NOT found in the pool
+
+	StaleHits  uint64 // number of Gets that found a free connection only after discarding stale ones
+	StaleSkips uint64 // number of stale or invalid idle connections discarded by Get
+
+	TotalConns   uint32 // number of open connections, including ones being dialed, lingering or being closed
+	IdleConns    uint32 // number of connections waiting in the idle queue or sticky stash
+	BusyConns    uint32 // number of connections checked out
+	DialingConns uint32 // number of connections being dialed
+
+	Overflows uint64 // number of overflow connections created beyond MaxCap
+
+	DialErrorCacheHits uint64 // number of dials failed fast with a cached factory error
+
+	ShedWaiters uint64 // number of waiters dropped because their context ended before being served
+
+	RateLimited uint64 // number of Gets delayed or rejected by GetRateLimit
+
+	Recovered uint64 // number of broken connections returned to the idle pool after a successful BrokenLinger probe
+
+	WaitCount    uint64        // number of Get calls that blocked waiting for a connection
+	WaitDuration time.Duration // total time blocked waiting for a connection
+
+	DialCount       uint64                // number of factory calls, successful or not
+	DialErrors      uint64                // number of factory calls that returned an error
+	DialDuration    time.Duration         // total time spent in factory calls
+	MaxDialDuration time.Duration         // longest single factory call since the last ResetStats
+	DialDurations   [dialBucketMax]uint64 // factory calls bucketed by duration, bounds in DialBuckets
+
+	Waiters    uint32 // number of goroutines currently waiting in Get
+	MaxBusy    uint32 // high watermark of connections checked out at once
+	MaxWaiters uint32 // high watermark of goroutines waiting in Get at once
+	IdleLow    uint32 // low watermark of idle connections since the last ResetStats or report
+	IdleHigh   uint32 // high watermark of idle connections since the last ResetStats or report
+
+	Closes [closeReasonMax]uint64 // number of connections closed by the pool, indexed by CloseReason
+
+	StaleConns   uint64 // number of connections closed for exceeding IdleTimeout or MaxLifetime
+	FullDiscards uint64 // number of connections closed because the idle pool was full, e.g. on Put
+
+	IdleAges [ageBucketMax]uint32 // idle connections bucketed by time since last returned, bounds in AgeBuckets
+	ConnAges [ageBucketMax]uint32 // idle connections bucketed by time since created, bounds in AgeBuckets
+}
+
+// Delta 返回相对prev的计数增量，用于计算区间速率；TotalConns等瞬时值保持当前值
+func (s *Stats) Delta(prev *Stats) *Stats {
+	delta := *s
+	if prev == nil {
+		return &delta
+	}
+	delta.Hits -= prev.Hits
+	delta.Misses -= prev.Misses
+	delta.StaleHits -= prev.StaleHits
+	delta.StaleSkips -= prev.StaleSkips
+	delta.Overflows -= prev.Overflows
+	delta.DialErrorCacheHits -= prev.DialErrorCacheHits
+	delta.ShedWaiters -= prev.ShedWaiters
+	delta.RateLimited -= prev.RateLimited
+	delta.Recovered -= prev.Recovered
+	delta.WaitCount -= prev.WaitCount
+	delta.WaitDuration -= prev.WaitDuration
+	delta.DialCount -= prev.DialCount
+	delta.DialErrors -= prev.DialErrors
+	delta.DialDuration -= prev.DialDuration
+	for i := range delta.DialDurations {
+		delta.DialDurations[i] -= prev.DialDurations[i]
+	}
+	for reason := range delta.Closes {
+		delta.Closes[reason] -= prev.Closes[reason]
+	}
+	delta.StaleConns -= prev.StaleConns
+	delta.FullDiscards -= prev.FullDiscards
+	return &delta
+}
+
+// addDial 记录一次工厂方法调用的耗时及是否失败
+func (s *statsShard) addDial(took time.Duration, failed bool) {
+	atomic.AddUint64(&s.dials, 1)
+	if failed {
+		atomic.AddUint64(&s.dialErrs, 1)
+	}
+	atomic.AddUint64(&s.dialNanos, uint64(took))
+	atomic.AddUint64(&s.dialHist[dialBucket(took)], 1)
+}
+
+// merge 将o累加到s，用于汇总多个连接池的统计信息，
+// 瞬时值及高低水位相加，MaxDialDuration取最大值
+func (s *Stats) merge(o *Stats) {
+	s.Hits += o.Hits
+	s.Misses += o.Misses
+	s.StaleHits += o.StaleHits
+	s.StaleSkips += o.StaleSkips
+	s.TotalConns += o.TotalConns
+	s.IdleConns += o.IdleConns
+	s.BusyConns += o.BusyConns
+	s.DialingConns += o.DialingConns
+	s.Overflows += o.Overflows
+	s.DialErrorCacheHits += o.DialErrorCacheHits
+	s.ShedWaiters += o.ShedWaiters
+	s.RateLimited += o.RateLimited
+	s.Recovered += o.Recovered
+	s.WaitCount += o.WaitCount
+	s.WaitDuration += o.WaitDuration
+	s.DialCount += o.DialCount
+	s.DialErrors += o.DialErrors
+	s.DialDuration += o.DialDuration
+	if o.MaxDialDuration > s.MaxDialDuration {
+		s.MaxDialDuration = o.MaxDialDuration
+	}
+	for i := range s.DialDurations {
+		s.DialDurations[i] += o.DialDurations[i]
+	}
+	s.Waiters += o.Waiters
+	s.MaxBusy += o.MaxBusy
+	s.MaxWaiters += o.MaxWaiters
+	s.IdleLow += o.IdleLow
+	s.IdleHigh += o.IdleHigh
+	for i := range s.Closes {
+		s.Closes[i] += o.Closes[i]
+	}
+	s.StaleConns += o.StaleConns
+	s.FullDiscards += o.FullDiscards
+	for i := range s.IdleAges {
+		s.IdleAges[i] += o.IdleAges[i]
+		s.ConnAges[i] += o.ConnAges[i]
+	}
+}
+
+// statsCounters 按CPU分片的计数器，减少并发累加时的缓存行争用，读取时再汇总
+type statsCounters struct {
+	shards []statsShard
+	mask   uint32
+}
+
+type statsShard struct {
+	hits        uint64
+	misses      uint64
+	staleHits   uint64
+	staleSkips  uint64
+	overflows   uint64
+	dialErrHits uint64
+	shed        uint64
+	rateLimited uint64
+	recovered   uint64
+	waits       uint64
+	waitNanos   uint64
+	dials       uint64
+	dialErrs    uint64
+	dialNanos   uint64
+	dialHist    [dialBucketMax]uint64
+	closes      [closeReasonMax]uint64
+	_           [64]byte
+}
+
+func newStatsCounters() statsCounters {
+	n := 1
+	for n < runtime.GOMAXPROCS(0) {
+		n <<= 1
+	}
+	return statsCounters{
+		shards: make([]statsShard, n),
+		mask:   uint32(n - 1),
+	}
+}
+
+// shard 随机选取一个分片用于累加
+func (s *statsCounters) shard() *statsShard {
+	return &s.shards[rand.Uint32()&s.mask]
+}
+
+// load 汇总所有分片的计数
+func (s *statsCounters) load(stats *Stats) {
+	for i := range s.shards {
+		shard := &s.shards[i]
+		stats.Hits += atomic.LoadUint64(&shard.hits)
+		stats.Misses += atomic.LoadUint64(&shard.misses)
+		stats.StaleHits += atomic.LoadUint64(&shard.staleHits)
+		stats.StaleSkips += atomic.LoadUint64(&shard.staleSkips)
+		stats.Overflows += atomic.LoadUint64(&shard.overflows)
+		stats.DialErrorCacheHits += atomic.LoadUint64(&shard.dialErrHits)
+		stats.ShedWaiters += atomic.LoadUint64(&shard.shed)
+		stats.RateLimited += atomic.LoadUint64(&shard.rateLimited)
+		stats.Recovered += atomic.LoadUint64(&shard.recovered)
+		stats.WaitCount += atomic.LoadUint64(&shard.waits)
+		stats.WaitDuration += time.Duration(atomic.LoadUint64(&shard.waitNanos))
+		stats.DialCount += atomic.LoadUint64(&shard.dials)
+		stats.DialErrors += atomic.LoadUint64(&shard.dialErrs)
+		stats.DialDuration += time.Duration(atomic.LoadUint64(&shard.dialNanos))
+		for i := range stats.DialDurations {
+			stats.DialDurations[i] += atomic.LoadUint64(&shard.dialHist[i])
+		}
+		for reason := range stats.Closes {
+			stats.Closes[reason] += atomic.LoadUint64(&shard.closes[reason])
+		}
+	}
+	stats.StaleConns = stats.Closes[CloseIdleTimeout] + stats.Closes[CloseLifetime]
+	stats.FullDiscards = stats.Closes[ClosePoolFull]
+}
+
+// reset 将所有分片的计数清零
+func (s *statsCounters) reset() {
+	for i := range s.shards {
+		shard := &s.shards[i]
+		atomic.StoreUint64(&shard.hits, 0)
+		atomic.StoreUint64(&shard.misses, 0)
+		atomic.StoreUint64(&shard.staleHits, 0)
+		atomic.StoreUint64(&shard.staleSkips, 0)
+		atomic.StoreUint64(&shard.overflows, 0)
+		atomic.StoreUint64(&shard.dialErrHits, 0)
+		atomic.StoreUint64(&shard.shed, 0)
+		atomic.StoreUint64(&shard.rateLimited, 0)
+		atomic.StoreUint64(&shard.recovered, 0)
+		atomic.StoreUint64(&shard.waits, 0)
+		atomic.StoreUint64(&shard.waitNanos, 0)
+		atomic.StoreUint64(&shard.dials, 0)
+		atomic.StoreUint64(&shard.dialErrs, 0)
+		atomic.StoreUint64(&shard.dialNanos, 0)
+		for i := range shard.dialHist {
+			atomic.StoreUint64(&shard.dialHist[i], 0)
+		}
+		for reason := range shard.closes {
+			atomic.StoreUint64(&shard.closes[reason], 0)
+		}
+	}
+}
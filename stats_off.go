@@ -0,0 +1,7 @@
+//go:build poolnostats
+
+package pool
+
+// instrumented 使用-tags poolnostats构建时去掉统计计数器、拦截器及事件，
+// Stats中的计数保持为0，Use追加的拦截器不会被调用，Events返回已关闭的通道
+const instrumented = false
@@ -0,0 +1,6 @@
+//go:build !poolnostats
+
+package pool
+
+// instrumented 默认构建统计计数器、拦截器及事件
+const instrumented = true
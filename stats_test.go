@@ -0,0 +1,1163 @@
+//go:build !poolnostats
+
+package pool_test
+
+// 依赖统计计数器或事件的测试，使用poolnostats构建时跳过
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"net"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hms58/pool"
+	"github.com/hms58/pool/poolsim"
+)
+
+// skipWithoutInterceptors 默认构建包含拦截器，不跳过
+func skipWithoutInterceptors(t *testing.T) {}
+
+func TestMaxOverflow(t *testing.T) {
+	p, dialed := newTestPool(&pool.PoolConfig{MaxCap: 2, MaxOverflow: 1})
+	defer p.Release()
+
+	var conns []interface{}
+	for i := 0; i < 3; i++ {
+		cn, err := p.Get()
+		if err != nil {
+			t.Fatal(err)
+		}
+		conns = append(conns, cn)
+	}
+	if _, err := p.Get(); !errors.Is(err, pool.ErrPoolExhausted) {
+		t.Fatalf("Get() err = %v, want ErrPoolExhausted", err)
+	}
+	if n := p.Stats().Overflows; n != 1 {
+		t.Errorf("Overflows = %d, want 1", n)
+	}
+
+	for _, cn := range conns {
+		if err := p.Put(cn); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !(*dialed)[0].closed || (*dialed)[1].closed || (*dialed)[2].closed {
+		t.Errorf("expected only the first returned conn to be closed as overflow")
+	}
+	if p.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", p.Len())
+	}
+}
+
+func TestCloseReason(t *testing.T) {
+	var reasons []pool.CloseReason
+	p, _ := newTestPool(&pool.PoolConfig{
+		MaxCap:      1,
+		MaxLifetime: time.Hour,
+		Ping: func(v interface{}) error {
+			if v.(*testConn).id == 0 {
+				return errors.New("ping failed")
+			}
+			return nil
+		},
+		OnClose: func(conn interface{}, reason pool.CloseReason) {
+			reasons = append(reasons, reason)
+		},
+	})
+
+	a, _ := p.Get()
+	b, _ := p.Get()
+	p.Put(a)
+	p.Put(b)
+	c, _ := p.Get() // a 校验失败被关闭，新建 c
+	p.Close(c)
+	p.Release()
+
+	want := []pool.CloseReason{pool.ClosePoolFull, pool.CloseValidation, pool.CloseBroken}
+	if fmt.Sprint(reasons) != fmt.Sprint(want) {
+		t.Errorf("close reasons = %v, want %v", reasons, want)
+	}
+	stats := p.Stats()
+	for _, r := range want {
+		if stats.Closes[r] != 1 {
+			t.Errorf("Closes[%v] = %d, want 1", r, stats.Closes[r])
+		}
+	}
+}
+
+func TestStatsDelta(t *testing.T) {
+	p, _ := newTestPool(&pool.PoolConfig{MaxCap: 2})
+	defer p.Release()
+
+	cn, _ := p.Get()
+	p.Put(cn)
+	prev := p.Stats()
+	cn, _ = p.Get()
+	p.Put(cn)
+
+	delta := p.Stats().Delta(prev)
+	if delta.Hits != 1 || delta.Misses != 0 {
+		t.Errorf("delta Hits=%d Misses=%d, want 1 0", delta.Hits, delta.Misses)
+	}
+
+	p.ResetStats()
+	if stats := p.Stats(); stats.Hits != 0 || stats.Misses != 0 || stats.TotalConns != 1 {
+		t.Errorf("after ResetStats got %+v", stats)
+	}
+}
+
+func TestWait(t *testing.T) {
+	p, _ := newTestPool(&pool.PoolConfig{MaxCap: 1, Wait: true})
+	defer p.Release()
+
+	a, _ := p.Get()
+	got := make(chan interface{})
+	go func() {
+		cn, err := p.Get()
+		if err != nil {
+			t.Error(err)
+		}
+		got <- cn
+	}()
+
+	for p.Stats().Waiters != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	p.Put(a)
+	if cn := <-got; cn != a {
+		t.Errorf("waiter got %v, want the returned conn", cn)
+	}
+
+	stats := p.Stats()
+	if stats.Waiters != 0 || stats.MaxWaiters != 1 || stats.MaxBusy != 1 {
+		t.Errorf("Waiters=%d MaxWaiters=%d MaxBusy=%d, want 0 1 1", stats.Waiters, stats.MaxWaiters, stats.MaxBusy)
+	}
+}
+
+func TestEvictWhere(t *testing.T) {
+	p, dialed := newTestPool(&pool.PoolConfig{MaxCap: 4})
+	defer p.Release()
+
+	var conns []interface{}
+	for i := 0; i < 4; i++ {
+		cn, _ := p.Get()
+		conns = append(conns, cn)
+	}
+	for _, cn := range conns {
+		p.Put(cn)
+	}
+
+	idle := 0
+	p.ForEachIdle(func(conn interface{}, info pool.ConnInfo) bool {
+		idle++
+		return true
+	})
+	if idle != 4 {
+		t.Errorf("ForEachIdle visited %d conns, want 4", idle)
+	}
+
+	n := p.EvictWhere(func(conn interface{}, info pool.ConnInfo) bool {
+		return conn.(*testConn).id%2 == 0
+	})
+	if n != 2 || p.Len() != 2 {
+		t.Errorf("EvictWhere() = %d, Len() = %d, want 2 2", n, p.Len())
+	}
+	for i, cn := range *dialed {
+		if cn.closed != (i%2 == 0) {
+			t.Errorf("conn %d closed=%v", i, cn.closed)
+		}
+	}
+	if got := p.Stats().Closes[pool.CloseEvicted]; got != 2 {
+		t.Errorf("Closes[CloseEvicted] = %d, want 2", got)
+	}
+}
+
+func TestSetFactory(t *testing.T) {
+	p, dialed := newTestPool(&pool.PoolConfig{MaxCap: 2})
+	defer p.Release()
+
+	a, _ := p.Get()
+	b, _ := p.Get()
+	p.Put(a)
+
+	p.SetFactory(func() (interface{}, error) { return &testConn{id: 100}, nil }, true)
+
+	// a 在取出时关闭，b 在放回时关闭
+	cn, _ := p.Get()
+	if cn.(*testConn).id != 100 {
+		t.Errorf("Get() returned conn %d, want one from the new factory", cn.(*testConn).id)
+	}
+	p.Put(b)
+	for i, old := range *dialed {
+		if !old.closed {
+			t.Errorf("old conn %d not drained", i)
+		}
+	}
+	if got := p.Stats().Closes[pool.CloseDrained]; got != 2 {
+		t.Errorf("Closes[CloseDrained] = %d, want 2", got)
+	}
+}
+
+func TestUpdateConfig(t *testing.T) {
+	p, _ := newTestPool(&pool.PoolConfig{MaxCap: 4})
+	defer p.Release()
+
+	var conns []interface{}
+	for i := 0; i < 4; i++ {
+		cn, _ := p.Get()
+		conns = append(conns, cn)
+	}
+	for _, cn := range conns {
+		p.Put(cn)
+	}
+
+	maxIdle := 1
+	idleTimeout := time.Nanosecond
+	p.UpdateConfig(pool.PoolUpdate{MaxIdle: &maxIdle})
+	if p.Len() != 1 {
+		t.Errorf("Len() = %d after shrinking MaxIdle, want 1", p.Len())
+	}
+
+	p.UpdateConfig(pool.PoolUpdate{IdleTimeout: &idleTimeout})
+	time.Sleep(time.Millisecond)
+	cn, _ := p.Get()
+	if cn.(*testConn).id != 4 {
+		t.Errorf("Get() returned conn %d, want a new conn after idle timeout", cn.(*testConn).id)
+	}
+	if closed := p.Stats().Closes[pool.CloseIdleTimeout]; closed != 1 {
+		t.Errorf("Closes[CloseIdleTimeout] = %d, want 1", closed)
+	}
+}
+
+func TestReset(t *testing.T) {
+	p, dialed := newTestPool(&pool.PoolConfig{
+		MaxCap: 2,
+		Reset: func(v interface{}) error {
+			if v.(*testConn).id == 1 {
+				return errors.New("dirty")
+			}
+			return nil
+		},
+	})
+	defer p.Release()
+
+	a, _ := p.Get()
+	b, _ := p.Get()
+	p.Put(a)
+	p.Put(b)
+	if p.Len() != 1 {
+		t.Errorf("Len() = %d, want 1", p.Len())
+	}
+	if (*dialed)[0].closed || !(*dialed)[1].closed {
+		t.Error("only the conn failing Reset should be closed")
+	}
+	if got := p.Stats().Closes[pool.CloseReset]; got != 1 {
+		t.Errorf("Closes[CloseReset] = %d, want 1", got)
+	}
+}
+
+func TestLease(t *testing.T) {
+	p, _ := newTestPool(&pool.PoolConfig{MaxCap: 1, Wait: true, Logger: log.New(io.Discard, "", 0)})
+	defer p.Release()
+
+	l, err := p.GetLease(context.Background(), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Renew(time.Hour); err != nil {
+		t.Errorf("Renew() err = %v", err)
+	}
+	if err := l.Put(); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Put(); err != pool.ErrLeaseExpired {
+		t.Errorf("second Put() err = %v, want ErrLeaseExpired", err)
+	}
+
+	l, _ = p.GetLease(context.Background(), 5*time.Millisecond)
+	// 租约到期回收名额后才能取到连接
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	cn, err := p.GetContext(ctx)
+	if err != nil {
+		t.Fatalf("Get() after lease expiry err = %v", err)
+	}
+	p.Put(cn)
+	if err := l.Renew(time.Hour); err != pool.ErrLeaseExpired {
+		t.Errorf("Renew() after expiry err = %v, want ErrLeaseExpired", err)
+	}
+	if got := p.Stats().Closes[pool.CloseLeaseExpired]; got != 1 {
+		t.Errorf("Closes[CloseLeaseExpired] = %d, want 1", got)
+	}
+}
+
+func TestEvents(t *testing.T) {
+	p, _ := newTestPool(&pool.PoolConfig{MaxCap: 1, EventBuffer: 4})
+	defer p.Release()
+	events := p.Events()
+
+	cn, _ := p.Get()
+	p.Close(cn)
+	maxIdle := 1
+	p.UpdateConfig(pool.PoolUpdate{MaxIdle: &maxIdle})
+
+	want := []pool.EventType{pool.EventConnCreated, pool.EventConnClosed, pool.EventResized}
+	for _, typ := range want {
+		ev := <-events
+		if ev.Type != typ {
+			t.Errorf("event %v, want %v", ev.Type, typ)
+		}
+		if typ == pool.EventConnClosed && ev.Reason != pool.CloseBroken {
+			t.Errorf("close event reason = %v, want broken", ev.Reason)
+		}
+	}
+
+	for i := 0; i < 6; i++ {
+		cn, _ := p.Get()
+		p.Close(cn)
+	}
+	if p.DroppedEvents() == 0 {
+		t.Error("DroppedEvents() = 0 after overflowing the buffer")
+	}
+}
+
+func TestDialErrorTTL(t *testing.T) {
+	var calls int32
+	down := errors.New("backend down")
+	p := pool.New(&pool.PoolConfig{
+		MaxCap: 2,
+		Factory: func() (interface{}, error) {
+			atomic.AddInt32(&calls, 1)
+			return nil, down
+		},
+		DialErrorTTL: time.Hour,
+	})
+	defer p.Release()
+	events := p.Events()
+
+	for i := 0; i < 5; i++ {
+		if _, err := p.Get(); !errors.Is(err, down) {
+			t.Fatalf("Get() err = %v, want cached factory error", err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("factory called %d times, want 1", calls)
+	}
+	if hits := p.Stats().DialErrorCacheHits; hits != 4 {
+		t.Errorf("DialErrorCacheHits = %d, want 4", hits)
+	}
+	if ev := <-events; ev.Type != pool.EventCircuitOpened {
+		t.Errorf("event %v, want circuit opened", ev.Type)
+	}
+
+	p.SetFactory(func() (interface{}, error) { return &testConn{}, nil }, false)
+	if _, err := p.Get(); err != nil {
+		t.Errorf("Get() after SetFactory err = %v", err)
+	}
+}
+
+func TestShedWaiters(t *testing.T) {
+	p := pool.New(&pool.PoolConfig{
+		MaxCap:  1,
+		Wait:    true,
+		Factory: func() (interface{}, error) { return new(int), nil },
+	})
+	defer p.Release()
+
+	v, _ := p.Get()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := p.GetContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("GetContext() err = %v, want DeadlineExceeded", err)
+	}
+	if shed := p.Stats().ShedWaiters; shed != 1 {
+		t.Errorf("ShedWaiters = %d, want 1", shed)
+	}
+
+	// 放弃的等待者不占用放回的连接
+	p.Put(v)
+	if p.Len() != 1 {
+		t.Errorf("Len() = %d, want 1", p.Len())
+	}
+}
+
+func TestIdleDepthWatermarks(t *testing.T) {
+	p := pool.New(&pool.PoolConfig{
+		MaxCap:  3,
+		Factory: func() (interface{}, error) { return new(int), nil },
+	})
+	defer p.Release()
+
+	conns, _ := p.GetN(context.Background(), 3)
+	p.PutAll(conns)
+	p.ResetStats()
+	if stats := p.Stats(); stats.IdleLow != 3 || stats.IdleHigh != 3 {
+		t.Errorf("after reset IdleLow = %d, IdleHigh = %d, want 3 and 3", stats.IdleLow, stats.IdleHigh)
+	}
+
+	// 突发借出使连接池短暂清空
+	conns, _ = p.GetN(context.Background(), 3)
+	p.PutAll(conns)
+	if stats := p.Stats(); stats.IdleLow != 0 || stats.IdleHigh != 3 {
+		t.Errorf("after burst IdleLow = %d, IdleHigh = %d, want 0 and 3", stats.IdleLow, stats.IdleHigh)
+	}
+}
+
+func TestPoolLabels(t *testing.T) {
+	var buf syncBuffer
+	p := pool.New(&pool.PoolConfig{
+		MaxCap:  1,
+		Name:    "cache",
+		Labels:  map[string]string{"region": "us"},
+		Slog:    slog.New(slog.NewJSONHandler(&buf, nil)),
+		Factory: func() (interface{}, error) { return new(int), nil },
+	})
+	defer p.Release()
+	events := p.Events()
+
+	p.Get()
+	if ev := <-events; ev.Pool != "cache" || ev.Labels["region"] != "us" {
+		t.Errorf("event Pool = %q, Labels = %v", ev.Pool, ev.Labels)
+	}
+	if state := p.DumpState(); state.Name != "cache" || state.Labels["region"] != "us" {
+		t.Errorf("DumpState Name = %q, Labels = %v", state.Name, state.Labels)
+	}
+	p.ShowStats()
+	if want := `"pool":"cache","labels":{"region":"us"}`; !strings.Contains(buf.String(), want) {
+		t.Errorf("log output missing %s:\n%s", want, buf.String())
+	}
+}
+
+func TestPoolLabelsSinks(t *testing.T) {
+	var buf syncBuffer
+	p := pool.New(&pool.PoolConfig{
+		MaxCap:  1,
+		Name:    "cache",
+		Labels:  map[string]string{"region": "us"},
+		Logger:  log.New(&buf, "", 0),
+		Factory: func() (interface{}, error) { return new(int), nil },
+	})
+	defer p.Release()
+
+	cn, _ := p.Get()
+	p.Put(cn)
+
+	p.ShowStats()
+	if want := "[cache region=us] TotalConns: 1"; !strings.Contains(buf.String(), want) {
+		t.Errorf("log output missing %q:\n%s", want, buf.String())
+	}
+
+	vars := pool.ExpvarFunc(p).String()
+	for _, want := range []string{`"labels":{"pool":"cache","region":"us"}`, `"Misses":1`} {
+		if !strings.Contains(vars, want) {
+			t.Errorf("expvar output missing %s:\n%s", want, vars)
+		}
+	}
+
+	var metrics strings.Builder
+	if err := pool.WritePrometheus(&metrics, p); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"# TYPE pool_misses_total counter\n",
+		`pool_misses_total{pool="cache",region="us"} 1` + "\n",
+		`pool_idle_conns{pool="cache",region="us"} 1` + "\n",
+	} {
+		if !strings.Contains(metrics.String(), want) {
+			t.Errorf("prometheus output missing %q:\n%s", want, metrics.String())
+		}
+	}
+}
+
+func TestLeaseRenewClock(t *testing.T) {
+	sim := poolsim.New(time.Now())
+	p := pool.New(sim.Config(&pool.PoolConfig{MaxCap: 1, Logger: log.New(io.Discard, "", 0)}))
+	defer p.Release()
+
+	l, err := p.GetLease(context.Background(), time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sim.Clock.Advance(45 * time.Second)
+	if err := l.Renew(time.Minute); err != nil {
+		t.Fatalf("Renew() err = %v", err)
+	}
+	// 续约后原定时器不再回收租约
+	sim.Clock.Advance(30 * time.Second)
+	if sim.Closed() != 0 {
+		t.Fatal("lease revoked by the timer replaced by Renew")
+	}
+	sim.Clock.Advance(30 * time.Second)
+	if sim.Closed() != 1 || p.Stats().Closes[pool.CloseLeaseExpired] != 1 {
+		t.Errorf("closed = %d, want the lease revoked on the pool clock", sim.Closed())
+	}
+	if err := l.Put(); err != pool.ErrLeaseExpired {
+		t.Errorf("Put() after expiry err = %v, want ErrLeaseExpired", err)
+	}
+}
+
+func TestWatchdogRebuild(t *testing.T) {
+	var fail int32
+	p := pool.New(&pool.PoolConfig{
+		MaxCap:       3,
+		DialErrorTTL: time.Hour,
+		Watchdog:     pool.WatchdogConfig{Interval: 5 * time.Millisecond, FailFor: 10 * time.Millisecond, Rewarm: 2},
+		Logger:       log.New(io.Discard, "", 0),
+		Factory: func() (interface{}, error) {
+			if atomic.LoadInt32(&fail) == 1 {
+				return nil, errors.New("refused")
+			}
+			return new(int), nil
+		},
+	})
+	defer p.Release()
+	events := p.Events()
+
+	v, _ := p.Get()
+	p.Put(v)
+	// 后端故障使新建连接错误被缓存，连接池持续不健康
+	atomic.StoreInt32(&fail, 1)
+	p.Dial()
+	atomic.StoreInt32(&fail, 0)
+
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case ev := <-events:
+			if ev.Type != pool.EventWatchdogRebuild {
+				continue
+			}
+			if ev.Size != 1 {
+				t.Errorf("rebuild closed %d idle conns, want 1", ev.Size)
+			}
+			time.Sleep(10 * time.Millisecond)
+			if p.Len() != 2 {
+				t.Errorf("Len() after rebuild = %d, want 2", p.Len())
+			}
+			if ok, reasons := p.Healthy(); !ok {
+				t.Errorf("Healthy() after rebuild = %v", reasons)
+			}
+			return
+		case <-timeout:
+			t.Fatal("watchdog did not rebuild the pool")
+		}
+	}
+}
+
+func TestWatchdogIgnoresHealthyCalls(t *testing.T) {
+	p := pool.New(&pool.PoolConfig{
+		MaxCap:   2,
+		Health:   pool.HealthThresholds{MaxDialErrorRate: 0.5},
+		Watchdog: pool.WatchdogConfig{Interval: 5 * time.Millisecond, FailFor: 10 * time.Millisecond},
+		Logger:   log.New(io.Discard, "", 0),
+		Factory:  func() (interface{}, error) { return nil, errors.New("refused") },
+	})
+	defer p.Release()
+	events := p.Events()
+
+	// 调用方频繁调用Healthy不会清空看门狗的拨号窗口
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				p.Dial()
+				p.Healthy()
+				time.Sleep(time.Millisecond)
+			}
+		}
+	}()
+
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case ev := <-events:
+			if ev.Type == pool.EventWatchdogRebuild {
+				return
+			}
+		case <-timeout:
+			t.Fatal("watchdog did not rebuild while the caller polled Healthy")
+		}
+	}
+}
+
+func TestConnID(t *testing.T) {
+	var buf syncBuffer
+	p := pool.New(&pool.PoolConfig{
+		MaxCap:  2,
+		Slog:    slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})),
+		Factory: func() (interface{}, error) { return new(int), nil },
+	})
+	defer p.Release()
+	events := p.Events()
+
+	a, _ := p.Get()
+	b, _ := p.Get()
+	if p.ConnID(a) != 1 || p.ConnID(b) != 2 {
+		t.Errorf("ConnID() = %d, %d, want 1, 2", p.ConnID(a), p.ConnID(b))
+	}
+	if ev := <-events; ev.Type != pool.EventConnCreated || ev.ConnID != 1 {
+		t.Errorf("first event = %v with ConnID %d", ev.Type, ev.ConnID)
+	}
+	<-events
+
+	p.Close(b)
+	if ev := <-events; ev.Type != pool.EventConnClosed || ev.ConnID != 2 {
+		t.Errorf("close event = %v with ConnID %d, want 2", ev.Type, ev.ConnID)
+	}
+	if p.ConnID(b) != 0 {
+		t.Errorf("ConnID() of closed conn = %d, want 0", p.ConnID(b))
+	}
+	for _, want := range []string{
+		`"msg":"connection created","conn_id":2`,
+		`"msg":"connection closed","conn_id":2`,
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("log output missing %s:\n%s", want, buf.String())
+		}
+	}
+	p.Put(a)
+}
+
+func TestPrepareError(t *testing.T) {
+	errAuth := errors.New("auth failed")
+	var closed int32
+	p := pool.New(&pool.PoolConfig{
+		MaxCap:  1,
+		Wait:    true,
+		Factory: func() (interface{}, error) { return new(int), nil },
+		Close: func(interface{}) error {
+			atomic.AddInt32(&closed, 1)
+			return nil
+		},
+		Prepare: func(interface{}) error { return errAuth },
+	})
+	defer p.Release()
+
+	if _, err := p.Get(); !errors.Is(err, errAuth) {
+		t.Fatalf("Get() err = %v, want %v", err, errAuth)
+	}
+	if atomic.LoadInt32(&closed) != 1 {
+		t.Error("conn that failed Prepare was not closed")
+	}
+	if got := p.Stats().Closes[pool.CloseValidation]; got != 1 {
+		t.Errorf("Closes[CloseValidation] = %d, want 1", got)
+	}
+}
+
+func TestAdvise(t *testing.T) {
+	p, _ := newTestPool(&pool.PoolConfig{MaxCap: 2, Wait: true})
+	defer p.Release()
+
+	v1, _ := p.Get()
+	v2, _ := p.Get()
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		p.Put(v1)
+	}()
+	v3, err := p.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Put(v2)
+	p.Put(v3)
+
+	r := p.Advise()
+	if r.WaitCount != 1 || r.AvgWait < 10*time.Millisecond {
+		t.Errorf("WaitCount, AvgWait = %d, %v, want 1 wait of about 20ms", r.WaitCount, r.AvgWait)
+	}
+	if r.PeakDemand != 3 {
+		t.Errorf("PeakDemand = %d, want 3", r.PeakDemand)
+	}
+	if r.RecommendedMaxCap <= 2 {
+		t.Errorf("RecommendedMaxCap = %d, want more than the contended MaxCap 2", r.RecommendedMaxCap)
+	}
+	if r.RecommendedInitialCap != 2 {
+		t.Errorf("RecommendedInitialCap = %d, want the peak busy conns within MaxIdle", r.RecommendedInitialCap)
+	}
+	if len(r.Reasons) == 0 {
+		t.Error("no reasons given for the recommendation")
+	}
+	if s := p.Stats(); s.WaitCount != 1 || s.WaitDuration < 10*time.Millisecond {
+		t.Errorf("Stats WaitCount, WaitDuration = %d, %v", s.WaitCount, s.WaitDuration)
+	}
+
+	p.ResetStats()
+	if r := p.Advise(); r.WaitCount != 0 || r.RecommendedMaxCap >= 2 {
+		t.Errorf("Advise() after ResetStats = %+v, want no waits and a smaller MaxCap", r)
+	}
+}
+
+func TestAdviseWindowClock(t *testing.T) {
+	sim := poolsim.New(time.Now())
+	p := pool.New(sim.Config(&pool.PoolConfig{MaxCap: 2}))
+	defer p.Release()
+
+	sim.Clock.Advance(time.Minute)
+	if w := p.Advise().Window; w != time.Minute {
+		t.Errorf("Window = %v, want 1m on the pool clock", w)
+	}
+	p.ResetStats()
+	sim.Clock.Advance(time.Second)
+	if w := p.Advise().Window; w != time.Second {
+		t.Errorf("Window after ResetStats = %v, want 1s", w)
+	}
+}
+
+func TestStaleConnsAndFullDiscards(t *testing.T) {
+	p, _ := newTestPool(&pool.PoolConfig{MaxCap: 2, MaxIdle: 1, IdleTimeout: 10 * time.Millisecond})
+	defer p.Release()
+
+	a, _ := p.Get()
+	b, _ := p.Get()
+	p.Put(a)
+	p.Put(b)
+	time.Sleep(20 * time.Millisecond)
+	v, _ := p.Get()
+	p.Put(v)
+
+	s := p.Stats()
+	if s.FullDiscards != 1 || s.StaleConns != 1 {
+		t.Errorf("FullDiscards, StaleConns = %d, %d, want 1, 1", s.FullDiscards, s.StaleConns)
+	}
+	if d := s.Delta(s); d.FullDiscards != 0 || d.StaleConns != 0 {
+		t.Errorf("Delta() = %d, %d, want 0, 0", d.FullDiscards, d.StaleConns)
+	}
+}
+
+func TestDialDurationStats(t *testing.T) {
+	p := pool.New(&pool.PoolConfig{
+		MaxCap: 2,
+		Factory: func() (interface{}, error) {
+			time.Sleep(15 * time.Millisecond)
+			return new(int), nil
+		},
+	})
+	defer p.Release()
+
+	a, _ := p.Get()
+	b, _ := p.Get()
+	p.Put(a)
+	p.Put(b)
+
+	s := p.Stats()
+	if s.DialCount != 2 || s.DialDuration < 30*time.Millisecond || s.MaxDialDuration < 15*time.Millisecond {
+		t.Errorf("DialCount, DialDuration, MaxDialDuration = %d, %v, %v", s.DialCount, s.DialDuration, s.MaxDialDuration)
+	}
+	var n uint64
+	for _, v := range s.DialDurations {
+		n += v
+	}
+	if n != 2 || s.DialDurations[0] != 0 {
+		t.Errorf("DialDurations = %v, want 2 calls of at least 10ms", s.DialDurations)
+	}
+
+	p.ResetStats()
+	if s := p.Stats(); s.DialCount != 0 || s.MaxDialDuration != 0 {
+		t.Errorf("after ResetStats DialCount, MaxDialDuration = %d, %v", s.DialCount, s.MaxDialDuration)
+	}
+}
+
+func TestStaleHits(t *testing.T) {
+	for _, exclude := range []bool{false, true} {
+		p, _ := newTestPool(&pool.PoolConfig{MaxCap: 2, IdleTimeout: 10 * time.Millisecond, ExcludeStaleHits: exclude})
+
+		a, _ := p.Get()
+		b, _ := p.Get()
+		p.Put(a)
+		time.Sleep(20 * time.Millisecond)
+		p.Put(b)
+		// a已过期被丢弃，取到b
+		if v, err := p.Get(); err != nil || v != b {
+			t.Fatalf("Get() = %v, %v, want the fresh conn", v, err)
+		}
+
+		wantHits := uint64(1)
+		if exclude {
+			wantHits = 0
+		}
+		if s := p.Stats(); s.StaleSkips != 1 || s.StaleHits != 1 || s.Hits != wantHits {
+			t.Errorf("ExcludeStaleHits=%v: StaleSkips, StaleHits, Hits = %d, %d, %d, want 1, 1, %d",
+				exclude, s.StaleSkips, s.StaleHits, s.Hits, wantHits)
+		}
+		p.Release()
+	}
+}
+
+func TestGetRateLimit(t *testing.T) {
+	sim := poolsim.New(time.Now())
+	p := pool.New(sim.Config(&pool.PoolConfig{
+		MaxCap:       4,
+		GetRateLimit: 10,
+		GetRateBurst: 2,
+	}))
+	defer p.Release()
+
+	for i := 0; i < 2; i++ {
+		c, err := p.Get()
+		if err != nil {
+			t.Fatalf("Get() within burst: %v", err)
+		}
+		p.Put(c)
+	}
+	if _, ok := p.TryGet(); ok {
+		t.Fatal("TryGet() succeeded with no tokens left")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := p.GetContext(ctx)
+	var ge *pool.GetError
+	if !errors.As(err, &ge) || ge.Kind != pool.KindRateLimited || !errors.Is(err, pool.ErrRateLimited) {
+		t.Fatalf("GetContext() with short deadline err = %v, want KindRateLimited", err)
+	}
+	if n := p.Stats().RateLimited; n != 2 {
+		t.Errorf("RateLimited = %d, want 2", n)
+	}
+
+	sim.Clock.Advance(100 * time.Millisecond)
+	if c, ok := p.TryGet(); !ok {
+		t.Fatal("TryGet() failed after a token was refilled")
+	} else {
+		p.Put(c)
+	}
+}
+
+func TestRotate(t *testing.T) {
+	sim := poolsim.New(time.Now())
+	p := pool.New(sim.Config(&pool.PoolConfig{MaxCap: 4}))
+	defer p.Release()
+
+	var conns []interface{}
+	for i := 0; i < 3; i++ {
+		c, err := p.Get()
+		if err != nil {
+			t.Fatal(err)
+		}
+		conns = append(conns, c)
+		sim.Clock.Sleep(time.Minute)
+	}
+	for _, c := range conns {
+		p.Put(c)
+	}
+
+	if n := p.Rotate(0); n != 0 {
+		t.Errorf("Rotate(0) = %d, want 0", n)
+	}
+	if n := p.Rotate(2); n != 2 {
+		t.Fatalf("Rotate(2) = %d, want 2", n)
+	}
+	if n := p.Stats().Closes[pool.CloseRotated]; n != 2 {
+		t.Errorf("rotated closes = %d, want 2", n)
+	}
+	if p.ConnID(conns[0]) != 0 || p.ConnID(conns[1]) != 0 || p.ConnID(conns[2]) == 0 {
+		t.Error("Rotate() did not retire the two oldest idle connections")
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for p.Len() < 3 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if p.Len() != 3 || sim.Dialed() != 5 {
+		t.Errorf("after rotate Len() = %d, dialed = %d, want 3 and 5", p.Len(), sim.Dialed())
+	}
+}
+
+func TestFlushOnErrors(t *testing.T) {
+	sim := poolsim.New(time.Now())
+	p := pool.New(sim.Config(&pool.PoolConfig{
+		MaxCap: 8,
+		FlushOnErrors: pool.FlushConfig{
+			Failures: 2,
+			Window:   time.Second,
+			Backoff:  time.Minute,
+		},
+	}))
+	defer p.Release()
+	events := p.Events()
+
+	// fail 取出4条连接，放回2条作为空闲连接，再以Close关闭另外2条
+	fail := func() {
+		t.Helper()
+		var conns []interface{}
+		for i := 0; i < 4; i++ {
+			c, err := p.Get()
+			if err != nil {
+				t.Fatal(err)
+			}
+			conns = append(conns, c)
+		}
+		p.Put(conns[0])
+		p.Put(conns[1])
+		p.Close(conns[2])
+		p.Close(conns[3])
+	}
+
+	fail()
+	select {
+	case ev := <-events:
+		for ev.Type != pool.EventFlushed {
+			ev = <-events
+		}
+		if ev.Size != 2 {
+			t.Errorf("EventFlushed Size = %d, want 2", ev.Size)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no EventFlushed after reaching the failure threshold")
+	}
+	if p.Len() != 0 || p.Stats().Closes[pool.CloseFlushed] != 2 {
+		t.Errorf("after flush Len() = %d, flushed closes = %d", p.Len(), p.Stats().Closes[pool.CloseFlushed])
+	}
+
+	// 抑制期内再次达到阈值不清空
+	fail()
+	if p.Len() != 2 {
+		t.Errorf("flushed again within backoff, Len() = %d", p.Len())
+	}
+
+	sim.Clock.Sleep(2 * time.Minute)
+	fail()
+	deadline := time.Now().Add(time.Second)
+	for p.Stats().Closes[pool.CloseFlushed] != 4 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if n := p.Stats().Closes[pool.CloseFlushed]; n != 4 {
+		t.Errorf("flushed closes after backoff = %d, want 4", n)
+	}
+}
+
+func TestGetWithOptions(t *testing.T) {
+	sim := poolsim.New(time.Now())
+	p := pool.New(sim.Config(&pool.PoolConfig{MaxCap: 2, Wait: true}))
+	defer p.Release()
+	ctx := context.Background()
+	fresh := pool.GetOptions{MaxIdleAge: 30 * time.Second}
+
+	c1, _ := p.Get()
+	c2, _ := p.Get()
+	p.Put(c1)
+	sim.Clock.Sleep(time.Minute)
+	p.Put(c2)
+
+	if c, err := p.GetWithOptions(ctx, fresh); err != nil || c != c2 {
+		t.Fatalf("GetWithOptions(MaxIdleAge) = %v, %v, want the recently used conn", c, err)
+	}
+	// c1已空闲过久且名额已满，不经校验淘汰c1后新建
+	c3, err := p.GetWithOptions(ctx, fresh)
+	if err != nil || c3 == c1 {
+		t.Fatalf("GetWithOptions(MaxIdleAge) with only a stale conn idle = %v, %v, want new conn", c3, err)
+	}
+	stats := p.Stats()
+	if sim.Dialed() != 3 || stats.Closes[pool.CloseEvicted] != 1 || stats.Closes[pool.CloseIdleTimeout] != 0 {
+		t.Errorf("dialed = %d, closes = %v, want 3 and one evicted", sim.Dialed(), stats.Closes)
+	}
+	// 名额已满且没有空闲连接，按Wait等待
+	wctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if _, err := p.GetWithOptions(wctx, fresh); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("GetWithOptions(MaxIdleAge) on a full pool err = %v, want to wait until the deadline", err)
+	}
+	p.Put(c2)
+	p.Put(c3)
+
+	sim.Clock.Sleep(time.Minute)
+	if c, err := p.GetWithOptions(ctx, pool.GetOptions{}); err != nil || sim.Dialed() != 3 {
+		t.Errorf("GetWithOptions() without MaxIdleAge = %v, %v, dialed = %d, want an old idle conn", c, err, sim.Dialed())
+	}
+}
+
+func TestBrokenLinger(t *testing.T) {
+	sim := poolsim.New(time.Now())
+	var dialed int
+	cfg := sim.Config(&pool.PoolConfig{
+		MaxCap:       4,
+		BrokenLinger: time.Second,
+		BrokenProbe: func(conn interface{}) error {
+			if conn.(*blipConn).id != 1 {
+				return errors.New("still down")
+			}
+			return nil
+		},
+	})
+	cfg.Factory = func() (interface{}, error) {
+		dialed++
+		return &blipConn{id: dialed}, nil
+	}
+	cfg.Close = nil
+	p := pool.New(cfg)
+	defer p.Release()
+
+	c1, _ := p.Get()
+	c2, _ := p.Get()
+	c1.(*blipConn).broken.Store(true)
+	c2.(*blipConn).broken.Store(true)
+	p.Put(c1)
+	p.Put(c2)
+	if stats := p.Stats(); p.Len() != 0 || stats.TotalConns != 2 {
+		t.Fatalf("lingering Len() = %d, TotalConns = %d, want 0 and 2", p.Len(), stats.TotalConns)
+	}
+
+	sim.Clock.Advance(time.Second)
+	stats := p.Stats()
+	if stats.Recovered != 1 || stats.Closes[pool.CloseBroken] != 1 || p.Len() != 1 {
+		t.Errorf("Recovered = %d, broken closes = %d, Len() = %d, want 1, 1, 1", stats.Recovered, stats.Closes[pool.CloseBroken], p.Len())
+	}
+	if c, ok := p.TryGet(); !ok || c != c1 || c.(*blipConn).Unusable() {
+		t.Errorf("TryGet() = %v, %v, want recovered conn", c, ok)
+	}
+}
+
+func TestKeyedPool(t *testing.T) {
+	errDown := errors.New("backend down")
+	p := pool.NewKeyedPool(func(key string) *pool.PoolConfig {
+		return &pool.PoolConfig{
+			MaxCap: 2,
+			Factory: func() (interface{}, error) {
+				if key == "down" {
+					return nil, errDown
+				}
+				return &testConn{}, nil
+			},
+		}
+	})
+	ctx := context.Background()
+
+	a1, _ := p.Get(ctx, "a")
+	a2, _ := p.Get(ctx, "a")
+	b, _ := p.Get(ctx, "b")
+	if _, err := p.Get(ctx, "down"); !errors.Is(err, errDown) {
+		t.Fatalf("Get(down) err = %v, want %v", err, errDown)
+	}
+	p.Put(a1)
+	p.Close(a2)
+	if err := p.Put(&testConn{}); err != pool.ErrUnknownConn {
+		t.Errorf("Put(foreign conn) err = %v, want ErrUnknownConn", err)
+	}
+
+	if keys := p.Keys(); !reflect.DeepEqual(keys, []string{"a", "b", "down"}) {
+		t.Errorf("Keys() = %v", keys)
+	}
+	stats := p.KeyStats()
+	if s := stats["a"]; s.Misses != 2 || s.IdleConns != 1 || s.BusyConns != 0 {
+		t.Errorf("a: Misses, IdleConns, BusyConns = %d, %d, %d, want 2, 1, 0", s.Misses, s.IdleConns, s.BusyConns)
+	}
+	if s := stats["b"]; s.BusyConns != 1 {
+		t.Errorf("b: BusyConns = %d, want 1", s.BusyConns)
+	}
+	if s := stats["down"]; s.DialErrors != 1 {
+		t.Errorf("down: DialErrors = %d, want 1", s.DialErrors)
+	}
+	if s := p.Stats(); s.Misses != 3 || s.DialErrors != 1 || s.TotalConns != 2 {
+		t.Errorf("aggregate Misses, DialErrors, TotalConns = %d, %d, %d, want 3, 1, 2", s.Misses, s.DialErrors, s.TotalConns)
+	}
+
+	p.Put(b)
+	p.Release()
+	if _, err := p.Get(ctx, "c"); !errors.Is(err, pool.ErrClosed) {
+		t.Errorf("Get() after Release err = %v, want ErrClosed", err)
+	}
+}
+
+func TestIOTimeout(t *testing.T) {
+	var servers []net.Conn
+	p := pool.New(&pool.PoolConfig{
+		MaxCap: 1,
+		Factory: func() (interface{}, error) {
+			client, server := net.Pipe()
+			servers = append(servers, server)
+			return client, nil
+		},
+		Close:     pool.CloseNetConn,
+		IOTimeout: 10 * time.Millisecond,
+	})
+	defer p.Release()
+	defer func() {
+		for _, s := range servers {
+			s.Close()
+		}
+	}()
+
+	cn, _ := p.Get()
+	conn, ok := cn.(*pool.DeadlineConn)
+	if !ok {
+		t.Fatalf("Get() returned %T, want *pool.DeadlineConn", cn)
+	}
+	if _, err := conn.Write([]byte("x")); err == nil {
+		t.Fatal("Write() to unread pipe did not time out")
+	}
+	if !conn.Unusable() {
+		t.Error("conn not marked unusable after timeout")
+	}
+	p.Put(conn)
+	if p.Len() != 0 {
+		t.Errorf("Len() = %d, want unusable conn discarded", p.Len())
+	}
+	if got := p.Stats().Closes[pool.CloseBroken]; got != 1 {
+		t.Errorf("Closes[CloseBroken] = %d, want 1", got)
+	}
+}
+
+func TestBalancedPoolStaticWeights(t *testing.T) {
+	p := pool.NewBalancedPool(pool.BalancedConfig{
+		Backends: []pool.Backend{
+			{Name: "a", Factory: backendFactory("a"), Weight: 3},
+			{Name: "b", Factory: backendFactory("b")},
+		},
+		Pool: pool.PoolConfig{MaxCap: 10},
+	})
+	defer p.Release()
+
+	counts := map[string]int{}
+	for i := 0; i < 8; i++ {
+		c, err := p.Get(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		counts[c.(*nameConn).backend]++
+	}
+	if counts["a"] != 6 || counts["b"] != 2 {
+		t.Errorf("counts = %v, want a:6 b:2", counts)
+	}
+	if stats := p.Keyed().KeyStats(); stats["a"].DialCount != 6 {
+		t.Errorf("a DialCount = %d, want 6", stats["a"].DialCount)
+	}
+}
+
+func TestStatsDialingConns(t *testing.T) {
+	dialing, unblock := make(chan struct{}), make(chan struct{})
+	p := pool.New(&pool.PoolConfig{
+		MaxCap: 2,
+		Factory: func() (interface{}, error) {
+			dialing <- struct{}{}
+			<-unblock
+			return new(int), nil
+		},
+	})
+	defer p.Release()
+
+	done := make(chan interface{})
+	go func() {
+		c, _ := p.Get()
+		done <- c
+	}()
+	<-dialing
+	// 正在新建的连接单独计数，不算作空闲连接
+	if s := p.Stats(); s.TotalConns != 1 || s.IdleConns != 0 || s.DialingConns != 1 {
+		t.Errorf("while dialing Total=%d Idle=%d Dialing=%d, want 1 0 1", s.TotalConns, s.IdleConns, s.DialingConns)
+	}
+	close(unblock)
+	p.Put(<-done)
+	if s := p.Stats(); s.TotalConns != 1 || s.IdleConns != 1 || s.DialingConns != 0 {
+		t.Errorf("after Put Total=%d Idle=%d Dialing=%d, want 1 1 0", s.TotalConns, s.IdleConns, s.DialingConns)
+	}
+}
@@ -0,0 +1,187 @@
+package pool
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StatsdConfig 将连接池统计信息推送到StatsD或Graphite的配置
+type StatsdConfig struct {
+	//StatsD或Graphite的地址，如 127.0.0.1:8125
+	Addr string
+	//网络类型，默认StatsD使用udp，Graphite使用tcp
+	Network string
+	//使用Graphite plaintext协议，默认使用StatsD协议
+	Graphite bool
+	//指标名前缀，如 myapp.redis_pool
+	Prefix string
+	//附加的标签，StatsD使用DogStatsD格式，Graphite使用 ;k=v 格式
+	//连接池的Name(标签名pool)及Labels自动附加，Tags中的同名标签优先
+	Tags map[string]string
+	//推送间隔，默认10秒
+	Interval time.Duration
+}
+
+// labeler 提供连接池名称及标签的连接池
+type labeler interface {
+	telemetryLabels() map[string]string
+}
+
+// StatsdExporter 定期将连接池统计信息推送到StatsD或Graphite
+type StatsdExporter struct {
+	p    Pooler
+	cfg  StatsdConfig
+	conn net.Conn
+	tags []string
+	//mu保护prev，使Flush与定期推送互斥，增量按推送顺序计算
+	mu   sync.Mutex
+	prev *Stats
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewStatsdExporter 连接到cfg.Addr并开始定期推送p的统计信息
+func NewStatsdExporter(p Pooler, cfg StatsdConfig) (*StatsdExporter, error) {
+	if cfg.Network == "" {
+		cfg.Network = "udp"
+		if cfg.Graphite {
+			cfg.Network = "tcp"
+		}
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = 10 * time.Second
+	}
+	conn, err := net.Dial(cfg.Network, cfg.Addr)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &StatsdExporter{
+		p:    p,
+		cfg:  cfg,
+		conn: conn,
+		prev: p.Stats(),
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	tags := make(map[string]string)
+	if l, ok := p.(labeler); ok {
+		for k, v := range l.telemetryLabels() {
+			tags[k] = v
+		}
+	}
+	for k, v := range cfg.Tags {
+		tags[k] = v
+	}
+	for k, v := range tags {
+		e.tags = append(e.tags, k+":"+v)
+	}
+	sort.Strings(e.tags)
+
+	go e.run()
+	return e, nil
+}
+
+func (e *StatsdExporter) run() {
+	defer close(e.done)
+	ticker := time.NewTicker(e.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			e.Flush()
+		case <-e.stop:
+			return
+		}
+	}
+}
+
+// Flush 立即推送一次统计信息，可与定期推送并发调用
+func (e *StatsdExporter) Flush() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	stats := e.p.Stats()
+	delta := stats.Delta(e.prev)
+	e.prev = stats
+
+	var buf bytes.Buffer
+	e.gauge(&buf, "total_conns", uint64(stats.TotalConns))
+	e.gauge(&buf, "idle_conns", uint64(stats.IdleConns))
+	e.gauge(&buf, "busy_conns", uint64(stats.BusyConns))
+	e.gauge(&buf, "dialing_conns", uint64(stats.DialingConns))
+	e.count(&buf, "hits", delta.Hits)
+	e.count(&buf, "misses", delta.Misses)
+	e.count(&buf, "stale_hits", delta.StaleHits)
+	e.count(&buf, "stale_skips", delta.StaleSkips)
+	e.count(&buf, "overflows", delta.Overflows)
+	e.count(&buf, "dial_error_cache_hits", delta.DialErrorCacheHits)
+	e.count(&buf, "shed_waiters", delta.ShedWaiters)
+	e.count(&buf, "rate_limited", delta.RateLimited)
+	e.count(&buf, "recovered", delta.Recovered)
+	e.count(&buf, "wait_count", delta.WaitCount)
+	e.count(&buf, "wait_duration_ms", uint64(delta.WaitDuration/time.Millisecond))
+	e.count(&buf, "dial_count", delta.DialCount)
+	e.count(&buf, "dial_errors", delta.DialErrors)
+	e.count(&buf, "dial_duration_ms", uint64(delta.DialDuration/time.Millisecond))
+	e.gauge(&buf, "max_dial_duration_ms", uint64(stats.MaxDialDuration/time.Millisecond))
+	for i, name := range dialBucketNames {
+		e.count(&buf, "dial_durations."+name, delta.DialDurations[i])
+	}
+	e.count(&buf, "stale_conns", delta.StaleConns)
+	e.count(&buf, "full_discards", delta.FullDiscards)
+	e.gauge(&buf, "idle_low", uint64(stats.IdleLow))
+	e.gauge(&buf, "idle_high", uint64(stats.IdleHigh))
+	for reason, n := range delta.Closes {
+		e.count(&buf, "closes."+metricName(CloseReason(reason).String()), n)
+	}
+	for i, name := range ageBucketNames {
+		e.gauge(&buf, "idle_ages."+name, uint64(stats.IdleAges[i]))
+		e.gauge(&buf, "conn_ages."+name, uint64(stats.ConnAges[i]))
+	}
+	_, err := e.conn.Write(buf.Bytes())
+	return err
+}
+
+// Close 停止推送并关闭连接
+func (e *StatsdExporter) Close() error {
+	close(e.stop)
+	<-e.done
+	return e.conn.Close()
+}
+
+func (e *StatsdExporter) gauge(buf *bytes.Buffer, name string, v uint64) {
+	e.write(buf, name, v, "g")
+}
+
+func (e *StatsdExporter) count(buf *bytes.Buffer, name string, v uint64) {
+	e.write(buf, name, v, "c")
+}
+
+func (e *StatsdExporter) write(buf *bytes.Buffer, name string, v uint64, kind string) {
+	if e.cfg.Prefix != "" {
+		name = e.cfg.Prefix + "." + name
+	}
+	if e.cfg.Graphite {
+		buf.WriteString(name)
+		for _, tag := range e.tags {
+			buf.WriteString(";" + strings.Replace(tag, ":", "=", 1))
+		}
+		fmt.Fprintf(buf, " %d %d\n", v, time.Now().Unix())
+		return
+	}
+	fmt.Fprintf(buf, "%s:%d|%s", name, v, kind)
+	if len(e.tags) > 0 {
+		buf.WriteString("|#" + strings.Join(e.tags, ","))
+	}
+	buf.WriteByte('\n')
+}
+
+// metricName 将空格替换为下划线，使其可用作指标名
+func metricName(s string) string {
+	return strings.Replace(s, " ", "_", -1)
+}
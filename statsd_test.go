@@ -0,0 +1,78 @@
+//go:build !poolnostats
+
+package pool_test
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hms58/pool"
+)
+
+func TestStatsdExporter(t *testing.T) {
+	l, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	p, _ := newTestPool(&pool.PoolConfig{MaxCap: 2, Name: "db"})
+	defer p.Release()
+	e, err := pool.NewStatsdExporter(p, pool.StatsdConfig{
+		Addr:     l.LocalAddr().String(),
+		Prefix:   "app.pool",
+		Tags:     map[string]string{"env": "test"},
+		Interval: time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+
+	cn, _ := p.Get()
+	p.Put(cn)
+	if err := e.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, 4096)
+	l.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := l.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(buf[:n])
+	for _, want := range []string{
+		"app.pool.total_conns:1|g|#env:test,pool:db\n",
+		"app.pool.misses:1|c|#env:test,pool:db\n",
+		"app.pool.closes.idle_timeout:0|c|#env:test,pool:db\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in %q", want, got)
+		}
+	}
+}
+
+func TestStatsdExporterConcurrentFlush(t *testing.T) {
+	l, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	p, _ := newTestPool(&pool.PoolConfig{MaxCap: 2})
+	defer p.Release()
+	e, err := pool.NewStatsdExporter(p, pool.StatsdConfig{Addr: l.LocalAddr().String(), Interval: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+
+	// 与定期推送并发调用Flush，由-race检查
+	for i := 0; i < 20; i++ {
+		e.Flush()
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,103 @@
+package pool
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// stashSlot 暂存一条最近放回的连接，填充避免伪共享
+type stashSlot struct {
+	conn atomic.Pointer[idleConn]
+	_    [56]byte
+}
+
+// stickyStash 按GOMAXPROCS分配的暂存槽位
+// hint按P缓存槽位指针，同一P上先后的Put与Get通常取到同一槽位；
+// 槽位只由slots持有，hint中的指针被GC清理不会丢失暂存的连接，多个P偶尔共用槽位也只经原子操作访问
+type stickyStash struct {
+	slots []stashSlot
+	next  uint32
+	hint  sync.Pool
+}
+
+// newStash 按GOMAXPROCS创建暂存槽位
+func newStash() *stickyStash {
+	s := &stickyStash{slots: make([]stashSlot, runtime.GOMAXPROCS(0))}
+	s.hint.New = func() interface{} {
+		return &s.slots[int(atomic.AddUint32(&s.next, 1)-1)%len(s.slots)]
+	}
+	return s
+}
+
+// slot 取当前P对应的槽位，用完后需通过release归还
+func (s *stickyStash) slot() *stashSlot {
+	return s.hint.Get().(*stashSlot)
+}
+
+// release 归还slot取得的槽位
+func (s *stickyStash) release(slot *stashSlot) {
+	s.hint.Put(slot)
+}
+
+// stashPut 将放回的连接暂存到当前P的槽位，槽位已占用或有等待者时返回false
+func (c *ChannelPool) stashPut(wrapConn *idleConn) bool {
+	if atomic.LoadInt32(&c.waiters) > 0 {
+		return false
+	}
+	slot := c.stash.slot()
+	ok := slot.conn.CompareAndSwap(nil, wrapConn)
+	c.stash.release(slot)
+	if !ok {
+		return false
+	}
+	atomic.AddInt32(&c.stashed, 1)
+
+	// 暂存后再检查一次，与登记等待者后清空暂存配合，保证等待者不会错过暂存的连接
+	if atomic.LoadInt32(&c.waiters) > 0 || c.State() != StateOpen {
+		c.flushStash()
+	}
+	return true
+}
+
+// stashGet 取出当前P槽位暂存的连接，没有时返回nil
+func (c *ChannelPool) stashGet() *idleConn {
+	slot := c.stash.slot()
+	wrapConn := slot.conn.Swap(nil)
+	c.stash.release(slot)
+	if wrapConn != nil {
+		atomic.AddInt32(&c.stashed, -1)
+	}
+	return wrapConn
+}
+
+// flushStash 将所有槽位暂存的连接移回空闲连接，超出MaxIdle的连接关闭，连接池已停止时全部关闭
+func (c *ChannelPool) flushStash() {
+	if c.stash == nil {
+		return
+	}
+	for i := range c.stash.slots {
+		wrapConn := c.stash.slots[i].conn.Swap(nil)
+		if wrapConn == nil {
+			continue
+		}
+		atomic.AddInt32(&c.stashed, -1)
+
+		conns := c.getConns()
+		switch {
+		case conns == nil:
+			c.discardStopped(wrapConn)
+		case c.idleLen(conns) >= c.loadMaxIdle() || !conns.push(wrapConn):
+			c.discard(wrapConn, ClosePoolFull)
+		default:
+			updateMax(&c.idleHigh, int32(conns.len()))
+			c.drainIfClosed()
+			c.notifyWaiter()
+		}
+	}
+}
+
+// idleLen 返回空闲连接数，包括暂存的连接，与MaxIdle比较
+func (c *ChannelPool) idleLen(conns idleQueue) int {
+	return conns.len() + int(atomic.LoadInt32(&c.stashed))
+}
@@ -0,0 +1,169 @@
+package pool
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"syscall"
+	"time"
+)
+
+// tcpOptions TCPFactory和TLSFactory的可选配置
+type tcpOptions struct {
+	dialer           net.Dialer
+	noDelay          bool
+	handshakeTimeout time.Duration
+	serverName       string
+	sessionCache     tls.ClientSessionCache
+	retries          int
+	retryInterval    time.Duration
+	attemptDelay     time.Duration
+}
+
+// TCPOption TCPFactory、TLSFactory和UnixFactory的可选配置项
+type TCPOption func(*tcpOptions)
+
+// WithDialTimeout 设置建立连接的超时时间
+func WithDialTimeout(d time.Duration) TCPOption {
+	return func(o *tcpOptions) { o.dialer.Timeout = d }
+}
+
+// WithKeepAlive 设置TCP keepalive探测间隔，为负数时关闭keepalive
+func WithKeepAlive(d time.Duration) TCPOption {
+	return func(o *tcpOptions) { o.dialer.KeepAlive = d }
+}
+
+// WithNoDelay 设置是否关闭Nagle算法，默认为true
+func WithNoDelay(noDelay bool) TCPOption {
+	return func(o *tcpOptions) { o.noDelay = noDelay }
+}
+
+// WithLocalAddr 绑定本地地址
+func WithLocalAddr(addr *net.TCPAddr) TCPOption {
+	return func(o *tcpOptions) { o.dialer.LocalAddr = addr }
+}
+
+// WithHandshakeTimeout 设置TLS握手的超时时间，仅对TLSFactory有效
+func WithHandshakeTimeout(d time.Duration) TCPOption {
+	return func(o *tcpOptions) { o.handshakeTimeout = d }
+}
+
+// WithServerName 覆盖TLS握手使用的SNI，仅对TLSFactory有效
+func WithServerName(name string) TCPOption {
+	return func(o *tcpOptions) { o.serverName = name }
+}
+
+// WithSessionCache 设置TLS会话缓存以复用session ticket，仅对TLSFactory有效
+func WithSessionCache(cache tls.ClientSessionCache) TCPOption {
+	return func(o *tcpOptions) { o.sessionCache = cache }
+}
+
+// WithDialRetry 设置socket文件不存在或拒绝连接时的重试次数和间隔，仅对UnixFactory有效
+func WithDialRetry(retries int, interval time.Duration) TCPOption {
+	return func(o *tcpOptions) {
+		o.retries = retries
+		o.retryInterval = interval
+	}
+}
+
+// newTCPOptions 应用可选配置
+func newTCPOptions(opts []TCPOption) *tcpOptions {
+	o := &tcpOptions{noDelay: true}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// dial 建立TCP连接并设置NoDelay
+func (o *tcpOptions) dial(addr string) (net.Conn, error) {
+	conn, err := o.dialer.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return o.setNoDelay(conn)
+}
+
+// setNoDelay 按配置设置NoDelay，失败时关闭连接
+func (o *tcpOptions) setNoDelay(conn net.Conn) (net.Conn, error) {
+	if tc, ok := conn.(*net.TCPConn); ok {
+		if err := tc.SetNoDelay(o.noDelay); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+// TCPFactory 返回连接addr的工厂方法，生成的连接类型为net.Conn
+func TCPFactory(addr string, opts ...TCPOption) Factory {
+	o := newTCPOptions(opts)
+	return func() (interface{}, error) {
+		return o.dial(addr)
+	}
+}
+
+// TLSFactory 返回连接addr并完成TLS握手的工厂方法，生成的连接类型为*tls.Conn
+func TLSFactory(addr string, tlsCfg *tls.Config, opts ...TCPOption) Factory {
+	o := newTCPOptions(opts)
+	if tlsCfg == nil {
+		tlsCfg = &tls.Config{}
+	} else {
+		tlsCfg = tlsCfg.Clone()
+	}
+	if o.serverName != "" {
+		tlsCfg.ServerName = o.serverName
+	}
+	if tlsCfg.ServerName == "" {
+		if host, _, err := net.SplitHostPort(addr); err == nil {
+			tlsCfg.ServerName = host
+		}
+	}
+	if o.sessionCache != nil {
+		tlsCfg.ClientSessionCache = o.sessionCache
+	}
+
+	return func() (interface{}, error) {
+		conn, err := o.dial(addr)
+		if err != nil {
+			return nil, err
+		}
+		tlsConn := tls.Client(conn, tlsCfg)
+		if o.handshakeTimeout > 0 {
+			conn.SetDeadline(time.Now().Add(o.handshakeTimeout))
+		}
+		if err := tlsConn.Handshake(); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		if o.handshakeTimeout > 0 {
+			conn.SetDeadline(time.Time{})
+		}
+		return tlsConn, nil
+	}
+}
+
+// UnixFactory 返回连接unix socket的工厂方法，生成的连接类型为net.Conn
+// sidecar重启期间socket文件短暂消失时，按WithDialRetry的配置重试
+func UnixFactory(path string, opts ...TCPOption) Factory {
+	o := newTCPOptions(opts)
+	return func() (interface{}, error) {
+		for i := 0; ; i++ {
+			conn, err := o.dialer.Dial("unix", path)
+			if err == nil || i >= o.retries || !retryableUnixErr(err) {
+				return conn, err
+			}
+			time.Sleep(o.retryInterval)
+		}
+	}
+}
+
+// retryableUnixErr socket文件不存在或尚未监听时可重试
+func retryableUnixErr(err error) bool {
+	return errors.Is(err, syscall.ENOENT) || errors.Is(err, syscall.ECONNREFUSED)
+}
+
+// CloseNetConn 关闭net.Conn，与TCPFactory、TLSFactory、UnixFactory配合使用
+func CloseNetConn(v interface{}) error {
+	return v.(net.Conn).Close()
+}
@@ -0,0 +1,117 @@
+package pool_test
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hms58/pool"
+)
+
+func TestTCPFactory(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	p := pool.New(&pool.PoolConfig{
+		MaxCap:  2,
+		Factory: pool.TCPFactory(ln.Addr().String(), pool.WithDialTimeout(time.Second), pool.WithKeepAlive(time.Minute)),
+		Close:   pool.CloseNetConn,
+	})
+	defer p.Release()
+
+	cn, err := p.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cn.(net.Conn); !ok {
+		t.Fatalf("Get() returned %T, want net.Conn", cn)
+	}
+	p.Put(cn)
+}
+
+func TestTLSFactory(t *testing.T) {
+	ts := httptest.NewTLSServer(http.NotFoundHandler())
+	defer ts.Close()
+
+	tlsCfg := ts.Client().Transport.(*http.Transport).TLSClientConfig
+	factory := pool.TLSFactory(ts.Listener.Addr().String(), tlsCfg,
+		pool.WithHandshakeTimeout(time.Second), pool.WithServerName("example.com"))
+	cn, err := factory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.CloseNetConn(cn)
+	if !cn.(*tls.Conn).ConnectionState().HandshakeComplete {
+		t.Error("TLS handshake not complete")
+	}
+}
+
+func TestUnixFactoryRetry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sidecar.sock")
+	factory := pool.UnixFactory(path, pool.WithDialRetry(50, 10*time.Millisecond))
+
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		ln, err := net.Listen("unix", path)
+		if err != nil {
+			return
+		}
+		conn, err := ln.Accept()
+		if err == nil {
+			conn.Close()
+		}
+		ln.Close()
+	}()
+
+	cn, err := factory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool.CloseNetConn(cn)
+
+	if _, err := pool.UnixFactory(path + ".missing")(); err == nil {
+		t.Error("UnixFactory() without retry dialed a missing socket")
+	}
+}
+
+func TestHappyEyeballsFactory(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+	factory := pool.HappyEyeballsFactory(net.JoinHostPort("localhost", port),
+		pool.WithDialTimeout(time.Second), pool.WithAttemptDelay(50*time.Millisecond))
+	cn, err := factory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool.CloseNetConn(cn)
+}
@@ -0,0 +1,190 @@
+package pool
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// TierPolicy TieredPool在热点与共享连接池之间移动连接的策略，零值表示只在热点连接池空闲已满时降级
+type TierPolicy struct {
+	//共享连接池的连接累计借出达到该次数后，放回时若热点连接池有空余名额则提升到热点连接池，0表示不提升
+	PromoteAfter uint64
+	//热点连接池的连接空闲超过该时间后降级到共享连接池，为更活跃的连接腾出名额，0表示不降级
+	DemoteIdle time.Duration
+}
+
+// TieredPool 两级连接池，Get优先从较小的热点连接池（如专用连接）取空闲连接，
+// 没有时回退到较大的共享连接池；热点连接池只通过InitialCap预建及提升获得连接，Get不为其新建连接
+// 连接需可作为map的key，以便区分所属的连接池
+type TieredPool struct {
+	hot    *ChannelPool
+	shared *ChannelPool
+	policy TierPolicy
+
+	promoted uint64
+	demoted  uint64
+}
+
+// NewTieredPool 使用热点、共享两份配置初始化两级连接池
+func NewTieredPool(hotConfig, sharedConfig *PoolConfig, policy TierPolicy) *TieredPool {
+	p := &TieredPool{
+		hot:    newPool(hotConfig, newChanQueue),
+		shared: newPool(sharedConfig, newChanQueue),
+		policy: policy,
+	}
+	if policy.DemoteIdle > 0 {
+		p.hot.every(policy.DemoteIdle, p.demoteIdle)
+	}
+	return p
+}
+
+// Get 优先取热点连接池的空闲连接，没有时从共享连接池获取
+func (p *TieredPool) Get(ctx context.Context) (interface{}, error) {
+	if conn, ok := p.hot.TryGet(); ok {
+		return conn, nil
+	}
+	return p.shared.GetContext(ctx)
+}
+
+// Put 将连接放回其所属的连接池，满足PromoteAfter的共享连接提升到热点连接池，
+// 热点连接池空闲已满时热点连接降级到共享连接池而不是关闭
+func (p *TieredPool) Put(conn interface{}) error {
+	if p.hot.owns(conn) {
+		moved, err := p.hot.handOver(conn, p.shared, func(ConnInfo) bool {
+			return p.hot.Len() >= p.hot.loadMaxIdle()
+		})
+		if moved {
+			atomic.AddUint64(&p.demoted, 1)
+		}
+		return err
+	}
+	if p.policy.PromoteAfter == 0 {
+		return p.shared.Put(conn)
+	}
+	moved, err := p.shared.handOver(conn, p.hot, func(info ConnInfo) bool {
+		return info.UseCount >= p.policy.PromoteAfter && p.hot.Len() < p.hot.loadMaxIdle()
+	})
+	if moved {
+		atomic.AddUint64(&p.promoted, 1)
+	}
+	return err
+}
+
+// Close 关闭连接并从其所属的连接池中移除
+func (p *TieredPool) Close(conn interface{}) error {
+	if p.hot.owns(conn) {
+		return p.hot.Close(conn)
+	}
+	return p.shared.Close(conn)
+}
+
+// demoteIdle 将空闲超过DemoteIdle的热点连接降级到共享连接池
+func (p *TieredPool) demoteIdle() {
+	now := p.hot.now()
+	n := p.hot.moveIdle(p.shared, func(info ConnInfo) bool {
+		return now.Sub(info.LastUsed) >= p.policy.DemoteIdle
+	})
+	atomic.AddUint64(&p.demoted, uint64(n))
+}
+
+// Hot 返回热点连接池
+func (p *TieredPool) Hot() *ChannelPool {
+	return p.hot
+}
+
+// Shared 返回共享连接池
+func (p *TieredPool) Shared() *ChannelPool {
+	return p.shared
+}
+
+// Moves 返回累计提升到热点连接池及降级到共享连接池的连接数
+func (p *TieredPool) Moves() (promoted, demoted uint64) {
+	return atomic.LoadUint64(&p.promoted), atomic.LoadUint64(&p.demoted)
+}
+
+// Stats 返回两个连接池合并后的统计信息
+func (p *TieredPool) Stats() *Stats {
+	stats := p.hot.Stats()
+	stats.merge(p.shared.Stats())
+	return stats
+}
+
+// Release 释放热点和共享两个连接池
+func (p *TieredPool) Release() {
+	p.hot.Release()
+	p.shared.Release()
+}
+
+// handOver 归还借出的conn，pred返回true且dst有空余名额时作为空闲连接移交给dst，
+// 否则按Put放回本连接池，返回是否已移交
+func (c *ChannelPool) handOver(conn interface{}, dst *ChannelPool, pred func(ConnInfo) bool) (bool, error) {
+	wrapConn, err := c.giveBack(conn)
+	if err != nil {
+		return false, err
+	}
+	if pred(wrapConn.info()) && dst.getConns() != nil {
+		if _, ok := dst.reserve(); ok {
+			return true, dst.adopt(c.detach(wrapConn))
+		}
+	}
+	return false, c.putIdle(wrapConn)
+}
+
+// moveIdle 将pred返回true的空闲连接移交给dst，dst名额已满时保留在本连接池，返回移交的连接数
+func (c *ChannelPool) moveIdle(dst *ChannelPool, pred func(ConnInfo) bool) int {
+	conns := c.getConns()
+	if conns == nil {
+		return 0
+	}
+
+	// 逐条取出判断，其余空闲连接仍可被借出
+	moved := 0
+	for n := conns.len(); n > 0; n-- {
+		wrapConn := conns.pop()
+		if wrapConn == nil {
+			break
+		}
+		if pred(wrapConn.lockedInfo()) && dst.getConns() != nil {
+			if _, ok := dst.reserve(); ok {
+				dst.adopt(c.detach(wrapConn))
+				moved++
+				continue
+			}
+		}
+		if c.idleLen(conns) < c.loadMaxIdle() && conns.push(wrapConn) {
+			continue
+		}
+		c.discard(wrapConn, ClosePoolFull)
+	}
+	c.drainIfClosed()
+	c.notifyWaiter()
+	return moved
+}
+
+// detach 停止追踪即将移交给其他连接池的连接并归还名额，不关闭连接，返回其元数据的副本
+func (c *ChannelPool) detach(wrapConn *idleConn) idleConn {
+	from := *wrapConn
+	from.children = nil
+	c.untrack(wrapConn)
+	c.unreserve()
+	return from
+}
+
+// adopt 将其他连接池移交的连接作为空闲连接放入，调用方已通过reserve占用名额
+func (c *ChannelPool) adopt(from idleConn) error {
+	c.mu.Lock()
+	gen := c.gen
+	c.mu.Unlock()
+
+	wrapConn := c.popBusy(from.conn, from.t)
+	wrapConn.created = from.created
+	wrapConn.origin = from.origin
+	wrapConn.labels = from.labels
+	wrapConn.jitter = from.jitter
+	wrapConn.uses = from.uses
+	wrapConn.gen = gen
+	wrapConn.id = atomic.AddUint64(&c.nextID, 1)
+	c.track(wrapConn)
+	return c.putIdle(wrapConn)
+}
@@ -0,0 +1,69 @@
+package pool_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hms58/pool"
+	"github.com/hms58/pool/poolsim"
+)
+
+func TestTieredPool(t *testing.T) {
+	hotCfg := &pool.PoolConfig{
+		InitialCap: 1,
+		MaxCap:     2,
+		Factory:    func() (interface{}, error) { return &testConn{id: 1}, nil },
+	}
+	sharedCfg := &pool.PoolConfig{
+		MaxCap:  4,
+		Factory: func() (interface{}, error) { return &testConn{id: 2}, nil },
+	}
+	p := pool.NewTieredPool(hotCfg, sharedCfg, pool.TierPolicy{PromoteAfter: 2})
+	defer p.Release()
+	ctx := context.Background()
+
+	hot, _ := p.Get(ctx)
+	shared, _ := p.Get(ctx)
+	if hot.(*testConn).id != 1 || shared.(*testConn).id != 2 {
+		t.Fatalf("Get() = %v, %v, want hot conn then shared conn", hot, shared)
+	}
+	p.Put(shared)
+	if p.Shared().Len() != 1 {
+		t.Fatalf("shared conn used once was promoted")
+	}
+
+	shared, _ = p.Get(ctx)
+	if err := p.Put(shared); err != nil {
+		t.Fatal(err)
+	}
+	if p.Hot().Len() != 1 || p.Shared().Len() != 0 || p.Shared().Stats().TotalConns != 0 {
+		t.Fatalf("hot Len=%d shared Len=%d, want promoted conn in hot pool", p.Hot().Len(), p.Shared().Len())
+	}
+	if promoted, _ := p.Moves(); promoted != 1 {
+		t.Errorf("promoted = %d, want 1", promoted)
+	}
+	if c, _ := p.Get(ctx); c != shared {
+		t.Errorf("Get() after promotion = %v, want promoted conn", c)
+	}
+	p.Put(hot)
+}
+
+func TestTieredPoolDemoteIdle(t *testing.T) {
+	sim := poolsim.New(time.Now())
+	hotCfg := sim.Config(&pool.PoolConfig{InitialCap: 2, MaxCap: 2})
+	sharedCfg := sim.Config(&pool.PoolConfig{MaxCap: 4})
+	p := pool.NewTieredPool(hotCfg, sharedCfg, pool.TierPolicy{DemoteIdle: time.Minute})
+	defer p.Release()
+
+	c, _ := p.Get(context.Background())
+	sim.Clock.Advance(30 * time.Second)
+	p.Put(c)
+	sim.Clock.Advance(30 * time.Second)
+	if p.Hot().Len() != 1 || p.Shared().Len() != 1 {
+		t.Fatalf("hot Len=%d shared Len=%d, want idle conn demoted", p.Hot().Len(), p.Shared().Len())
+	}
+	if _, demoted := p.Moves(); demoted != 1 || sim.Open() != 2 {
+		t.Errorf("demoted = %d open = %d, want 1 2", demoted, sim.Open())
+	}
+}
@@ -0,0 +1,98 @@
+package pool
+
+import (
+	"hash/maphash"
+	"sync"
+)
+
+// trackShards 追踪表的分片数
+const trackShards = 32
+
+// connTracker 连接池创建的所有连接，放回时据此识别重复放回及不属于本连接池的连接
+// 按连接的哈希分片加锁，并发的Get/Put只锁定各自连接所在的分片
+type connTracker struct {
+	seed   maphash.Seed
+	shards [trackShards]trackShard
+}
+
+// trackShard 追踪表的一个分片，mu同时保护其中连接的借出状态等字段
+type trackShard struct {
+	mu    sync.Mutex
+	conns map[interface{}]*idleConn
+	_     [48]byte
+}
+
+func newConnTracker() *connTracker {
+	t := &connTracker{seed: maphash.MakeSeed()}
+	for i := range t.shards {
+		t.shards[i].conns = make(map[interface{}]*idleConn)
+	}
+	return t
+}
+
+// shard 返回key所在的分片，key需可比较
+func (t *connTracker) shard(key interface{}) *trackShard {
+	return &t.shards[maphash.Comparable(t.seed, key)%trackShards]
+}
+
+// add 开始追踪连接
+func (t *connTracker) add(key interface{}, wrapConn *idleConn) {
+	s := t.shard(key)
+	s.mu.Lock()
+	s.conns[key] = wrapConn
+	wrapConn.shard = s
+	s.mu.Unlock()
+}
+
+// lookup 在分片的锁内对key对应的连接调用fn，连接未被追踪时wrapConn为nil
+func (t *connTracker) lookup(key interface{}, fn func(wrapConn *idleConn)) {
+	s := t.shard(key)
+	s.mu.Lock()
+	fn(s.conns[key])
+	s.mu.Unlock()
+}
+
+// each 依次锁定各分片，对其中的连接调用fn
+func (t *connTracker) each(fn func(key interface{}, wrapConn *idleConn)) {
+	for i := range t.shards {
+		s := &t.shards[i]
+		s.mu.Lock()
+		for key, wrapConn := range s.conns {
+			fn(key, wrapConn)
+		}
+		s.mu.Unlock()
+	}
+}
+
+// len 返回追踪的连接数
+func (t *connTracker) len() int {
+	n := 0
+	for i := range t.shards {
+		s := &t.shards[i]
+		s.mu.Lock()
+		n += len(s.conns)
+		s.mu.Unlock()
+	}
+	return n
+}
+
+// lock 锁定连接所在的分片，未追踪的连接只由持有者访问，无需加锁
+func (ic *idleConn) lock() {
+	if ic.shard != nil {
+		ic.shard.mu.Lock()
+	}
+}
+
+func (ic *idleConn) unlock() {
+	if ic.shard != nil {
+		ic.shard.mu.Unlock()
+	}
+}
+
+// lockedInfo 在分片的锁内读取连接的元数据快照
+func (ic *idleConn) lockedInfo() ConnInfo {
+	ic.lock()
+	info := ic.info()
+	ic.unlock()
+	return info
+}
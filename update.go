@@ -0,0 +1,99 @@
+package pool
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// PoolUpdate 运行时可修改的连接池配置，为nil的字段保持不变
+type PoolUpdate struct {
+	//链接最大空闲时间
+	IdleTimeout *time.Duration
+	//链接最大存活时间
+	MaxLifetime *time.Duration
+	//保留的最大空闲连接数，不能超过MaxCap，减小时立即关闭多余的空闲连接
+	MaxIdle *int
+	//Get阻塞等待的最长时间
+	WaitTimeout *time.Duration
+}
+
+// UpdateConfig 在运行时修改连接池配置，已有的连接保持不变
+func (c *ChannelPool) UpdateConfig(cfg PoolUpdate) {
+	c.mu.Lock()
+	if cfg.IdleTimeout != nil {
+		atomic.StoreInt64(&c.idleTimeout, int64(*cfg.IdleTimeout))
+	}
+	if cfg.MaxLifetime != nil {
+		atomic.StoreInt64(&c.maxLifetime, int64(*cfg.MaxLifetime))
+	}
+	if cfg.WaitTimeout != nil {
+		atomic.StoreInt64(&c.waitTimeout, int64(*cfg.WaitTimeout))
+	}
+	if cfg.MaxIdle != nil {
+		maxIdle := *cfg.MaxIdle
+		if maxIdle <= 0 || maxIdle > c.maxCap {
+			maxIdle = c.maxCap
+		}
+		atomic.StoreInt32(&c.maxIdle, int32(maxIdle))
+		c.emit(Event{Type: EventResized, Size: maxIdle})
+	}
+	c.mu.Unlock()
+
+	c.shrink()
+}
+
+// shrink 空闲连接超过MaxIdle时，按淘汰策略关闭多余的连接，未配置淘汰策略时关闭空闲最久的连接
+// 每次只从空闲队列头部取出至多evictSample条候选，其余空闲连接仍可被借出
+func (c *ChannelPool) shrink() {
+	conns := c.getConns()
+	if conns == nil {
+		return
+	}
+
+	for conns.len() > c.loadMaxIdle() {
+		if c.eviction == nil {
+			wrapConn := conns.pop()
+			if wrapConn == nil {
+				break
+			}
+			c.discard(wrapConn, ClosePoolFull)
+			continue
+		}
+
+		candidates := sampleIdle(conns, evictSample)
+		if len(candidates) == 0 {
+			break
+		}
+		infos := make([]ConnInfo, len(candidates))
+		for i, ic := range candidates {
+			infos[i] = ic.lockedInfo()
+		}
+		victim := 0
+		if v := c.eviction.Victim(infos); v >= 0 && v < len(candidates) {
+			victim = v
+		}
+		for i, wrapConn := range candidates {
+			if i != victim && conns.push(wrapConn) {
+				continue
+			}
+			c.discard(wrapConn, ClosePoolFull)
+		}
+	}
+	c.drainIfClosed()
+}
+
+func (c *ChannelPool) loadIdleTimeout() time.Duration {
+	return time.Duration(atomic.LoadInt64(&c.idleTimeout))
+}
+
+func (c *ChannelPool) loadMaxLifetime() time.Duration {
+	return time.Duration(atomic.LoadInt64(&c.maxLifetime))
+}
+
+func (c *ChannelPool) loadWaitTimeout() time.Duration {
+	return time.Duration(atomic.LoadInt64(&c.waitTimeout))
+}
+
+func (c *ChannelPool) loadMaxIdle() int {
+	return int(atomic.LoadInt32(&c.maxIdle))
+}
@@ -0,0 +1,94 @@
+package pool
+
+import (
+	"context"
+	"sync/atomic"
+
+	v1 "github.com/hms58/pool"
+)
+
+// adapter 将v1连接池包装为v2的Pooler
+type adapter struct {
+	p v1.Pooler
+}
+
+// Adapt 将v1连接池包装为v2的Pooler，两者共享同一组连接，便于逐步迁移
+func Adapt(p v1.Pooler) Pooler {
+	return &adapter{p: p}
+}
+
+// connIDer 能返回连接ID的v1连接池，未实现时连接ID为0
+type connIDer interface {
+	ConnID(conn interface{}) uint64
+}
+
+// releaseErrer 释放时返回关闭错误的v1连接池
+type releaseErrer interface {
+	ReleaseErr() error
+}
+
+func (a *adapter) Get(ctx context.Context) (Conn, error) {
+	var (
+		v   interface{}
+		err error
+	)
+	if g, ok := a.p.(v1.ContextGetter); ok {
+		v, err = g.GetContext(ctx)
+	} else {
+		v, err = a.p.Get()
+	}
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	c := &conn{p: a.p, v: v}
+	if ider, ok := a.p.(connIDer); ok {
+		c.id = ider.ConnID(v)
+	}
+	return c, nil
+}
+
+func (a *adapter) Len() int {
+	return a.p.Len()
+}
+
+func (a *adapter) Stats() *v1.Stats {
+	return a.p.Stats()
+}
+
+func (a *adapter) Close() error {
+	if r, ok := a.p.(releaseErrer); ok {
+		return r.ReleaseErr()
+	}
+	a.p.Release()
+	return nil
+}
+
+// conn 借出的连接，Release和Discard只能调用其中一个且只能调用一次
+type conn struct {
+	p    v1.Pooler
+	v    interface{}
+	id   uint64
+	done int32
+}
+
+func (c *conn) Value() interface{} {
+	return c.v
+}
+
+func (c *conn) ID() uint64 {
+	return c.id
+}
+
+func (c *conn) Release() error {
+	if !atomic.CompareAndSwapInt32(&c.done, 0, 1) {
+		return ErrReleased
+	}
+	return wrapErr(c.p.Put(c.v))
+}
+
+func (c *conn) Discard() error {
+	if !atomic.CompareAndSwapInt32(&c.done, 0, 1) {
+		return ErrReleased
+	}
+	return wrapErr(c.p.Close(c.v))
+}
@@ -0,0 +1,65 @@
+package pool
+
+import (
+	"time"
+
+	v1 "github.com/hms58/pool"
+)
+
+// Factory 生成连接的方法
+type Factory = v1.Factory
+
+// Option 连接池配置项
+type Option func(*v1.PoolConfig)
+
+// WithMaxCap 设置最大连接数
+func WithMaxCap(n int) Option {
+	return func(cfg *v1.PoolConfig) { cfg.MaxCap = n }
+}
+
+// WithMaxIdle 设置保留的最大空闲连接数
+func WithMaxIdle(n int) Option {
+	return func(cfg *v1.PoolConfig) { cfg.MaxIdle = n }
+}
+
+// WithWait 连接数达到上限时Get等待，timeout为0时一直等待直到ctx结束
+func WithWait(timeout time.Duration) Option {
+	return func(cfg *v1.PoolConfig) {
+		cfg.Wait = true
+		cfg.WaitTimeout = timeout
+	}
+}
+
+// WithClose 设置关闭连接的方法
+func WithClose(close func(interface{}) error) Option {
+	return func(cfg *v1.PoolConfig) { cfg.Close = close }
+}
+
+// WithPing 设置取出空闲连接时的校验方法
+func WithPing(ping func(interface{}) error) Option {
+	return func(cfg *v1.PoolConfig) { cfg.Ping = ping }
+}
+
+// WithIdleTimeout 设置连接最大空闲时间
+func WithIdleTimeout(d time.Duration) Option {
+	return func(cfg *v1.PoolConfig) { cfg.IdleTimeout = d }
+}
+
+// WithMaxLifetime 设置连接最大存活时间
+func WithMaxLifetime(d time.Duration) Option {
+	return func(cfg *v1.PoolConfig) { cfg.MaxLifetime = d }
+}
+
+// WithConfig 直接修改v1配置，用于尚未提供选项的配置项
+func WithConfig(fn func(*v1.PoolConfig)) Option {
+	return Option(fn)
+}
+
+// New 使用factory和配置项创建连接池
+func New(factory Factory, opts ...Option) Pooler {
+	cfg := &v1.PoolConfig{Factory: factory}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return Adapt(v1.NewChannelPool(cfg))
+}
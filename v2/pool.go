@@ -0,0 +1,114 @@
+// Package pool 是连接池的v2版本：Get接收context并返回Conn，
+// 连接通过Conn.Release放回、Conn.Discard关闭，错误带有类型，配置使用函数式选项
+// 底层复用v1实现，Adapt可包装已有的v1连接池以便逐步迁移
+package pool
+
+import (
+	"context"
+	"errors"
+
+	v1 "github.com/hms58/pool"
+)
+
+// Pooler 连接池
+type Pooler interface {
+	Get(ctx context.Context) (Conn, error)
+
+	Len() int
+
+	Stats() *v1.Stats
+
+	Close() error
+}
+
+// Conn 从连接池借出的连接
+type Conn interface {
+	// Value 返回底层连接
+	Value() interface{}
+
+	// ID 返回连接编号，与v1的ConnInfo.ID及日志中的conn_id一致，无法追踪的连接为0
+	ID() uint64
+
+	// Release 将连接放回连接池
+	Release() error
+
+	// Discard 关闭连接，不再放回连接池
+	Discard() error
+}
+
+// ErrorKind 错误类型
+type ErrorKind int
+
+const (
+	// KindUnknown 未归类的错误，如工厂方法返回的错误
+	KindUnknown ErrorKind = iota
+	// KindClosed 连接池已关闭
+	KindClosed
+	// KindDraining 连接池正在排空
+	KindDraining
+	// KindExhausted 连接数已达上限
+	KindExhausted
+	// KindTimeout 等待连接超时
+	KindTimeout
+	// KindReleased 连接已经放回或关闭
+	KindReleased
+)
+
+var kindNames = [...]string{
+	KindUnknown:   "unknown",
+	KindClosed:    "closed",
+	KindDraining:  "draining",
+	KindExhausted: "exhausted",
+	KindTimeout:   "timeout",
+	KindReleased:  "released",
+}
+
+func (k ErrorKind) String() string {
+	if k < 0 || int(k) >= len(kindNames) {
+		return "unknown"
+	}
+	return kindNames[k]
+}
+
+// Error 连接池返回的错误，Err为底层错误
+type Error struct {
+	Kind ErrorKind
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return "pool: " + e.Kind.String() + ": " + e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is 同类型的Error视为相等，可用errors.Is(err, ErrClosed)判断
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Kind == e.Kind && e.Kind != KindUnknown
+}
+
+var (
+	// ErrClosed 连接池已关闭
+	ErrClosed = &Error{Kind: KindClosed, Err: v1.ErrClosed}
+	// ErrDraining 连接池正在排空
+	ErrDraining = &Error{Kind: KindDraining, Err: v1.ErrDraining}
+	// ErrExhausted 连接数已达上限
+	ErrExhausted = &Error{Kind: KindExhausted, Err: v1.ErrPoolExhausted}
+	// ErrTimeout 等待连接超时
+	ErrTimeout = &Error{Kind: KindTimeout, Err: v1.ErrGetTimeout}
+	// ErrReleased 连接已经放回或关闭
+	ErrReleased = &Error{Kind: KindReleased, Err: v1.ErrDoublePut}
+)
+
+// wrapErr 将v1的错误转换为带类型的Error
+func wrapErr(err error) error {
+	for _, typed := range []*Error{ErrClosed, ErrDraining, ErrExhausted, ErrTimeout, ErrReleased} {
+		if errors.Is(err, typed.Err) {
+			return &Error{Kind: typed.Kind, Err: err}
+		}
+	}
+	return err
+}
@@ -0,0 +1,80 @@
+package pool_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	v1 "github.com/hms58/pool"
+	pool "github.com/hms58/pool/v2"
+)
+
+func TestConnReleaseDiscard(t *testing.T) {
+	closed := 0
+	p := pool.New(func() (interface{}, error) { return new(int), nil },
+		pool.WithMaxCap(1),
+		pool.WithClose(func(interface{}) error { closed++; return nil }),
+	)
+
+	cn, err := p.Get(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Get(context.Background()); err != nil {
+		t.Fatalf("Get() without Wait err = %v", err)
+	}
+	if err := cn.Release(); err != nil {
+		t.Fatal(err)
+	}
+	if err := cn.Discard(); !errors.Is(err, pool.ErrReleased) {
+		t.Errorf("Discard() after Release err = %v, want ErrReleased", err)
+	}
+	if p.Len() != 1 {
+		t.Errorf("Len() = %d, want 1", p.Len())
+	}
+
+	cn, _ = p.Get(context.Background())
+	cn.Discard()
+	if closed != 1 {
+		t.Errorf("closed %d conns, want 1", closed)
+	}
+
+	p.Close()
+	_, err = p.Get(context.Background())
+	if !errors.Is(err, pool.ErrClosed) || !errors.Is(err, v1.ErrClosed) {
+		t.Errorf("Get() after Close err = %v, want ErrClosed", err)
+	}
+}
+
+func TestAdaptTypedErrors(t *testing.T) {
+	p := pool.Adapt(v1.NewChannelPool(&v1.PoolConfig{
+		MaxCap:  1,
+		Wait:    true,
+		Factory: func() (interface{}, error) { return new(int), nil },
+	}))
+	defer p.Close()
+
+	p.Get(context.Background())
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := p.Get(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Get() with canceled ctx err = %v", err)
+	}
+
+	var perr *pool.Error
+	e := pool.New(func() (interface{}, error) { return nil, errors.New("x") }, pool.WithMaxCap(1))
+	if _, err := e.Get(context.Background()); errors.As(err, &perr) {
+		t.Errorf("factory error wrapped as %v, want passthrough", perr.Kind)
+	}
+}
+
+func TestConnID(t *testing.T) {
+	p := pool.New(func() (interface{}, error) { return new(int), nil }, pool.WithMaxCap(1))
+	defer p.Close()
+
+	cn, _ := p.Get(context.Background())
+	if cn.ID() != 1 {
+		t.Errorf("ID() = %d, want 1", cn.ID())
+	}
+	cn.Release()
+}
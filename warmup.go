@@ -0,0 +1,77 @@
+package pool
+
+import (
+	"log/slog"
+	"time"
+)
+
+// warmup 预先建立n条空闲连接，rate大于0时每秒最多建立rate条
+// 新建失败、名额已满或连接池释放时停止
+func (c *ChannelPool) warmup(n, rate int) {
+	var ticker *time.Ticker
+	if rate > 0 {
+		ticker = time.NewTicker(time.Second / time.Duration(rate))
+		defer ticker.Stop()
+	}
+
+	for i := 0; i < n; i++ {
+		if ticker != nil && i > 0 {
+			select {
+			case <-ticker.C:
+			case <-c.done:
+				return
+			}
+		}
+		if err := c.prefill(); err != nil {
+			c.logAttrs(slog.LevelWarn, "warmup stopped", slog.Int("conns", i), slog.Any("error", err))
+			return
+		}
+	}
+}
+
+// prefill 新建一条连接并直接放入空闲连接中，配置了Prepare时在后台准备完成后放入
+func (c *ChannelPool) prefill() error {
+	conns := c.getConns()
+	if conns == nil {
+		return c.stateErr()
+	}
+	if conns.len() >= c.loadMaxIdle() {
+		return ErrPoolExhausted
+	}
+	n, ok := c.reserve()
+	if !ok {
+		return ErrPoolExhausted
+	}
+	wrapConn, err := c.newConn(n)
+	if err != nil {
+		return err
+	}
+
+	c.track(wrapConn)
+	if c.prepare != nil {
+		go c.prepareIdle(conns, wrapConn)
+		return nil
+	}
+	return c.pushIdle(conns, wrapConn)
+}
+
+// prepareIdle 执行Prepare后将新建的连接放入空闲连接，失败时关闭该连接
+func (c *ChannelPool) prepareIdle(conns idleQueue, wrapConn *idleConn) {
+	if err := c.prepare(wrapConn.conn); err != nil {
+		c.logAttrs(slog.LevelWarn, "prepare failed", slog.Uint64("conn_id", wrapConn.id), slog.Any("error", err))
+		c.discard(wrapConn, CloseValidation)
+		return
+	}
+	c.pushIdle(conns, wrapConn)
+}
+
+// pushIdle 将新建的连接放入空闲连接并唤醒一个等待者
+func (c *ChannelPool) pushIdle(conns idleQueue, wrapConn *idleConn) error {
+	if !conns.push(wrapConn) {
+		return c.discard(wrapConn, ClosePoolFull)
+	}
+	updateMax(&c.idleHigh, int32(conns.len()))
+	c.drainIfClosed()
+	c.notifyWaiter()
+	return nil
+}
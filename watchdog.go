@@ -0,0 +1,86 @@
+package pool
+
+import (
+	"log/slog"
+	"sync/atomic"
+	"time"
+)
+
+// WatchdogConfig 看门狗配置，Interval大于0时启动。连接池持续不健康超过FailFor时，
+// 关闭所有空闲连接、清除新建连接错误缓存并重新预建连接，代替重启服务
+type WatchdogConfig struct {
+	//调用Healthy检查的间隔
+	Interval time.Duration
+	//持续不健康超过该时间后重建，默认为Interval的3倍
+	FailFor time.Duration
+	//两次重建之间的最小间隔，每次重建后翻倍直到MaxBackoff，恢复健康后重置，默认与FailFor相同
+	Backoff time.Duration
+	//重建间隔的上限，默认5分钟
+	MaxBackoff time.Duration
+	//重建后预建的连接数，默认为InitialCap，按WarmupRate建立
+	Rewarm int
+}
+
+// defaultWatchdogMaxBackoff 重建间隔的默认上限
+const defaultWatchdogMaxBackoff = 5 * time.Minute
+
+// watchdogLoop 定期检查连接池健康状态，持续不健康时重建，连接池Release后自动停止
+// 拨号失败率按看门狗相邻两次检查之间的拨号计算，不受调用方Healthy的影响
+func (c *ChannelPool) watchdogLoop(cfg WatchdogConfig, rate int) {
+	if cfg.FailFor <= 0 {
+		cfg.FailFor = 3 * cfg.Interval
+	}
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = cfg.FailFor
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = defaultWatchdogMaxBackoff
+	}
+
+	var (
+		failingSince, nextRebuild time.Time
+		window                    dialWindow
+	)
+	backoff := cfg.Backoff
+	c.every(cfg.Interval, func() {
+		if c.State() != StateOpen {
+			return
+		}
+		now := c.now()
+		ok, reasons := c.healthy(&window)
+		if ok {
+			failingSince = time.Time{}
+			backoff = cfg.Backoff
+			return
+		}
+		if failingSince.IsZero() {
+			failingSince = now
+		}
+		if now.Sub(failingSince) < cfg.FailFor || now.Before(nextRebuild) {
+			return
+		}
+		c.logAttrs(slog.LevelWarn, "watchdog rebuilding pool",
+			slog.Duration("unhealthy_for", now.Sub(failingSince)), slog.Any("reasons", reasons))
+		c.rebuild(cfg.Rewarm, rate)
+		nextRebuild = now.Add(backoff)
+		if backoff *= 2; backoff > cfg.MaxBackoff {
+			backoff = cfg.MaxBackoff
+		}
+	})
+}
+
+// rebuild 关闭所有空闲连接，清除新建连接错误缓存后在后台预建rewarm条连接
+func (c *ChannelPool) rebuild(rewarm, rate int) {
+	n := c.filterIdle(func(interface{}, ConnInfo) bool { return true }, CloseWatchdog)
+	c.mu.Lock()
+	c.dialErr = nil
+	c.mu.Unlock()
+	c.emit(Event{Type: EventWatchdogRebuild, Size: n})
+	// 与InitialCap的预建相同在后台进行，不阻塞看门狗的检查；上次重建的预建未结束时不再重复
+	if rewarm > 0 && atomic.CompareAndSwapInt32(&c.rewarming, 0, 1) {
+		go func() {
+			defer atomic.StoreInt32(&c.rewarming, 0)
+			c.warmup(rewarm, rate)
+		}()
+	}
+}
@@ -0,0 +1,171 @@
+package pool
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// WorkerPoolConfig goroutine池相关配置
+type WorkerPoolConfig struct {
+	//最大worker数，默认10
+	MaxWorkers int
+	//worker空闲超过该时间后在下次取用时退出
+	IdleTimeout time.Duration
+	//worker全忙时可排队的任务数，为0时直接返回ErrQueueFull
+	QueueLen int
+	//任务panic时回调，为空时记录日志
+	OnPanic func(interface{})
+	//状态变化时回调
+	OnStateChange func(from, to State)
+}
+
+// WorkerPool 复用goroutine执行任务，worker的容量和生命周期由连接池管理
+type WorkerPool struct {
+	workers *ChannelPool
+	queue   chan func()
+	onPanic func(interface{})
+}
+
+// worker 一个常驻goroutine，逐个执行tasks中的任务
+type worker struct {
+	tasks chan func()
+}
+
+// NewWorkerPool 初始化goroutine池
+func NewWorkerPool(cfg *WorkerPoolConfig) *WorkerPool {
+	if cfg.MaxWorkers <= 0 {
+		cfg.MaxWorkers = 10
+	}
+
+	p := &WorkerPool{
+		queue:   make(chan func(), cfg.QueueLen),
+		onPanic: cfg.OnPanic,
+	}
+	p.workers = newPool(&PoolConfig{
+		MaxCap: cfg.MaxWorkers,
+		//开启Wait使连接数上限生效，Submit本身不会阻塞等待
+		Wait: true,
+		Factory: func() (interface{}, error) {
+			// tasks带一个缓冲，worker放回自身后可能立即被kick取出并派发任务
+			w := &worker{tasks: make(chan func(), 1)}
+			go p.run(w)
+			return w, nil
+		},
+		Close: func(v interface{}) error {
+			close(v.(*worker).tasks)
+			return nil
+		},
+		IdleTimeout:   cfg.IdleTimeout,
+		OnStateChange: cfg.OnStateChange,
+	}, newChanQueue)
+	if p.onPanic == nil {
+		p.onPanic = func(r interface{}) {
+			p.workers.logAttrs(slog.LevelError, "worker task panicked", slog.Any("panic", r))
+		}
+	}
+	return p
+}
+
+// Submit 提交一个任务，有空闲worker或未达MaxWorkers时立即执行，
+// 否则进入队列，队列已满时返回ErrQueueFull
+func (p *WorkerPool) Submit(task func()) error {
+	conn, err := p.workers.getIdle()
+	if err == ErrNoIdleConn {
+		conn, err = p.workers.Dial()
+	}
+	switch err {
+	case nil:
+		conn.(*worker).tasks <- task
+		return nil
+	case ErrPoolExhausted:
+	default:
+		return err
+	}
+
+	select {
+	case p.queue <- task:
+	default:
+		return ErrQueueFull
+	}
+	// 入队前可能已有worker放回，需要唤醒它处理队列
+	p.kick()
+	return nil
+}
+
+// run worker的主循环，执行完任务及队列中的任务后放回连接池
+func (p *WorkerPool) run(w *worker) {
+	for task := range w.tasks {
+		p.exec(task)
+		for queued := true; queued; {
+			select {
+			case task := <-p.queue:
+				p.exec(task)
+			default:
+				queued = false
+			}
+		}
+		p.workers.Put(w)
+		p.kick()
+	}
+}
+
+// kick 队列中有任务时取出空闲worker处理
+func (p *WorkerPool) kick() {
+	for len(p.queue) > 0 {
+		conn, ok := p.workers.TryGet()
+		if !ok {
+			return
+		}
+		w := conn.(*worker)
+		select {
+		case task := <-p.queue:
+			w.tasks <- task
+		default:
+			p.workers.Put(w)
+			return
+		}
+	}
+}
+
+// exec 执行任务并恢复panic
+func (p *WorkerPool) exec(task func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			p.onPanic(r)
+		}
+	}()
+	task()
+}
+
+// Running 正在执行任务的worker数
+func (p *WorkerPool) Running() int {
+	return p.workers.BusyLen()
+}
+
+// Queued 排队等待执行的任务数
+func (p *WorkerPool) Queued() int {
+	return len(p.queue)
+}
+
+// State 返回goroutine池当前状态
+func (p *WorkerPool) State() State {
+	return p.workers.State()
+}
+
+// Stats 返回worker的统计信息，Hits为复用worker次数，Misses为新建worker次数
+func (p *WorkerPool) Stats() *Stats {
+	return p.workers.Stats()
+}
+
+// StartStatsReporter 定期上报统计信息，参见ChannelPool.StartStatsReporter
+func (p *WorkerPool) StartStatsReporter(interval time.Duration, fn func(*Stats)) {
+	p.workers.StartStatsReporter(interval, fn)
+}
+
+// Release 拒绝新任务，等待正在执行和排队的任务完成后释放所有worker
+func (p *WorkerPool) Release() {
+	p.workers.Drain()
+	p.workers.Wait(context.Background())
+	p.workers.Release()
+}
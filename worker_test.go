@@ -0,0 +1,69 @@
+package pool_test
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/hms58/pool"
+)
+
+func TestWorkerPool(t *testing.T) {
+	p := pool.NewWorkerPool(&pool.WorkerPoolConfig{MaxWorkers: 2, QueueLen: 100})
+
+	var done int32
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		err := p.Submit(func() {
+			defer wg.Done()
+			atomic.AddInt32(&done, 1)
+		})
+		if err != nil {
+			wg.Done()
+			t.Fatalf("Submit() err = %v", err)
+		}
+	}
+	wg.Wait()
+	if done != 50 {
+		t.Errorf("ran %d tasks, want 50", done)
+	}
+	if misses := p.Stats().Misses; misses > 2 {
+		t.Errorf("spawned %d workers, want <= 2", misses)
+	}
+
+	p.Release()
+	if err := p.Submit(func() {}); err != pool.ErrClosed {
+		t.Errorf("Submit() after Release err = %v, want ErrClosed", err)
+	}
+}
+
+func TestWorkerPoolQueueFull(t *testing.T) {
+	var panics int32
+	p := pool.NewWorkerPool(&pool.WorkerPoolConfig{
+		MaxWorkers: 1,
+		OnPanic:    func(interface{}) { atomic.AddInt32(&panics, 1) },
+	})
+
+	block := make(chan struct{})
+	p.Submit(func() { <-block })
+	if err := p.Submit(func() {}); err != pool.ErrQueueFull {
+		t.Errorf("Submit() with busy worker err = %v, want ErrQueueFull", err)
+	}
+	close(block)
+
+	p.Release()
+	if p.State() != pool.StateClosed {
+		t.Errorf("State() = %v after Release, want closed", p.State())
+	}
+
+	p = pool.NewWorkerPool(&pool.WorkerPoolConfig{
+		MaxWorkers: 1,
+		OnPanic:    func(interface{}) { atomic.AddInt32(&panics, 1) },
+	})
+	p.Submit(func() { panic("boom") })
+	p.Release()
+	if panics != 1 {
+		t.Errorf("OnPanic called %d times, want 1", panics)
+	}
+}